@@ -6,11 +6,18 @@
 package envconfig
 
 import (
+	"context"
+	"fmt"
+	"io/fs"
+	"math"
+	"net/url"
 	"os"
 	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
+	"text/template"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -29,11 +36,23 @@ type envTagOption struct {
 // ErrNotSet is the error that gets wrapped when a "required" env-var is not set.
 var ErrNotSet = errors.New("is not set")
 
-var tagDefaultRx = regexp.MustCompile(`^(.+),\s*(default=.*)$`)
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// A Defaulter is a struct that sets its own defaults.  If a pointer to a struct implements Defaulter, then
+// its Default method is called before any of its fields are parsed, and a field that is not set in the
+// environment (and that doesn't have a default of its own in the tag) keeps the value that Default gave
+// it, rather than being required.
+type Defaulter interface {
+	Default()
+}
+
+var defaulterType = reflect.TypeOf((*Defaulter)(nil)).Elem()
+
+var tagDefaultRx = regexp.MustCompile(`^(.+?),\s*((?:default|template)=.*)$`)
 
 func parseTagValue(str string, validOptions []envTagOption) (envTag, error) {
 	var parts []string
-	// Split string on comma, but leave everything after default= intact
+	// Split string on comma, but leave everything after default= (or template=) intact
 	if m := tagDefaultRx.FindStringSubmatch(str); m != nil {
 		parts = strings.Split(m[1], ",")
 		parts = append(parts, m[2])
@@ -91,84 +110,486 @@ func stringPointer(str string) *string {
 // set to os.LookupEnv.
 type LookupFunc func(key string) (string, bool)
 
+// FlagLookup returns a LookupFunc that looks up keys in flags, which is typically populated from
+// command-line flags.  It is mostly useful combined with ChainLookup, as a fallback for env-vars that
+// aren't set:
+//
+//	parser.ParseFromEnv(&cfg, envconfig.ChainLookup(os.LookupEnv, envconfig.FlagLookup(flags)))
+func FlagLookup(flags map[string]string) LookupFunc {
+	return func(key string) (string, bool) {
+		val, ok := flags[key]
+		return val, ok
+	}
+}
+
+// ChainLookup returns a LookupFunc that tries each of lookups in order, returning the first value that
+// is set; so earlier lookups take precedence over later ones.
+func ChainLookup(lookups ...LookupFunc) LookupFunc {
+	return func(key string) (string, bool) {
+		for _, lookup := range lookups {
+			if val, ok := lookup(key); ok {
+				return val, true
+			}
+		}
+		return "", false
+	}
+}
+
 // A FieldTypeHandler adds support for a struct member type.
 type FieldTypeHandler struct {
 	Parsers map[string]func(string) (interface{}, error)
 	Setter  func(reflect.Value, interface{})
+
+	// ContextParsers are like Parsers, but are also passed the context.Context given to
+	// ParseFromEnvContext; they are for parsers that perform I/O or have side effects (such as
+	// "resolvable-host", or opening a file).  So that generating a parser doesn't do that I/O, a default
+	// is not validated against a context parser when the parser is generated.
+	ContextParsers map[string]func(context.Context, string) (interface{}, error)
+
+	// Aliases maps deprecated parser names to the names of the parsers in Parsers or ContextParsers
+	// that replace them.  Using an alias works, but results in a warning.
+	Aliases map[string]string
+
+	// Optional indicates that the type can represent "not set" on its own (as the sql.Null* types
+	// do), so a member of this type is not required even if it has no default; if the env-var is
+	// not set, then the member is set to its zero value.
+	Optional bool
+}
+
+// parserNone is the name of the passthrough parser that is valid for every type, for const members that
+// only ever take a typed value from defaultFrom or defaultFunc.  A type handler may override it.
+const parserNone = "none"
+
+func parseNone(_ string) (interface{}, error) {
+	return nil, errors.Errorf("parser %q does not parse values", parserNone)
+}
+
+// parser returns the named parser from either Parsers or ContextParsers, falling back to parseNone for
+// parserNone.  It does not resolve Aliases.
+func (h FieldTypeHandler) parser(name string) (func(context.Context, string) (interface{}, error), bool) {
+	if fn, ok := h.Parsers[name]; ok {
+		return withoutContext(fn), true
+	}
+	if fn, ok := h.ContextParsers[name]; ok {
+		return fn, true
+	}
+	if name == parserNone {
+		return withoutContext(parseNone), true
+	}
+	return nil, false
+}
+
+func withoutContext(fn func(string) (interface{}, error)) func(context.Context, string) (interface{}, error) {
+	return func(_ context.Context, str string) (interface{}, error) {
+		return fn(str)
+	}
 }
 
 func (h FieldTypeHandler) parserNames() []string {
-	ret := make([]string, 0, len(h.Parsers))
+	ret := make([]string, 0, len(h.Parsers)+len(h.ContextParsers))
 	for name := range h.Parsers {
 		ret = append(ret, name)
 	}
+	for name := range h.ContextParsers {
+		ret = append(ret, name)
+	}
 	return ret
 }
 
-// expand uses os.Expand and the given lookupFunc to expand ${xxx} constructs
-// in the given value.
-func expand(value string, lookupFunc func(string) (string, bool)) string {
-	return os.Expand(value, func(key string) string {
+// MergeHandlers returns a new map of struct field type handlers that has the handlers from overrides
+// overlaid on to a copy of base; neither base nor overrides is modified.  If a type is in both maps, then the
+// parsers for that type are merged, with a parser from overrides winning over a parser with the same name
+// from base.  If the override for a type has a non-nil Setter, then its Setter and Optional replace those
+// from base; otherwise it only adds parsers.
+//
+//	handlers := envconfig.MergeHandlers(envconfig.DefaultFieldTypeHandlers(), myHandlers)
+func MergeHandlers(base, overrides map[reflect.Type]FieldTypeHandler) map[reflect.Type]FieldTypeHandler {
+	ret := make(map[reflect.Type]FieldTypeHandler, len(base)+len(overrides))
+	for _, handlers := range []map[reflect.Type]FieldTypeHandler{base, overrides} {
+		for typ, handler := range handlers {
+			merged, exists := ret[typ]
+			if !exists || handler.Setter != nil {
+				merged.Setter = handler.Setter
+				merged.Optional = handler.Optional
+			}
+			parsers := make(map[string]func(string) (interface{}, error), len(merged.Parsers)+len(handler.Parsers))
+			for name, parser := range merged.Parsers {
+				parsers[name] = parser
+			}
+			for name, parser := range handler.Parsers {
+				parsers[name] = parser
+			}
+			merged.Parsers = parsers
+			if len(merged.ContextParsers)+len(handler.ContextParsers) > 0 {
+				contextParsers := make(map[string]func(context.Context, string) (interface{}, error), len(merged.ContextParsers)+len(handler.ContextParsers))
+				for name, parser := range merged.ContextParsers {
+					contextParsers[name] = parser
+				}
+				for name, parser := range handler.ContextParsers {
+					contextParsers[name] = parser
+				}
+				merged.ContextParsers = contextParsers
+			}
+			if len(merged.Aliases)+len(handler.Aliases) > 0 {
+				aliases := make(map[string]string, len(merged.Aliases)+len(handler.Aliases))
+				for name, target := range merged.Aliases {
+					aliases[name] = target
+				}
+				for name, target := range handler.Aliases {
+					aliases[name] = target
+				}
+				merged.Aliases = aliases
+			}
+			ret[typ] = merged
+		}
+	}
+	return ret
+}
+
+// expand uses os.Expand and the given lookupFunc to expand ${xxx} and $xxx constructs in the given
+// value; "$$" is a literal "$", and an unset variable expands to "" (and is included in unset).  It
+// makes a single pass, so the expanded values are not themselves expanded.
+func expand(value string, lookupFunc func(string) (string, bool)) (expanded string, unset []string) {
+	seen := make(map[string]bool)
+	expanded = os.Expand(value, func(key string) string {
+		if key == "$" {
+			return "$"
+		}
 		if v, ok := lookupFunc(key); ok {
 			return v
 		}
+		if !seen[key] {
+			seen[key] = true
+			unset = append(unset, key)
+		}
 		return ""
 	})
+	return expanded, unset
 }
 
 // A StructParser inspects and parses the environment to set fields in a struct.
 type StructParser struct {
 	structType    reflect.Type
-	fieldHandlers []func(structValue reflect.Value, lookup LookupFunc) (warn, fatal []error)
+	fieldHandlers []func(structValue reflect.Value, state *parseState) (warn, fatal []error)
+	// fields describes the fields that fieldHandlers handle, for introspection.
+	fields []structField
+	// defaulter is whether the struct is a Defaulter.
+	defaulter bool
+	// warnings are non-fatal problems found by GenerateParser (such as use of a deprecated parser
+	// alias), which ParseFromEnv returns along with its own warnings.
+	warnings []error
+	// allOrNone are the groups of field names added by AllOrNone.
+	allOrNone [][]string
+	// recoverParserPanics is GenerateOptions.RecoverParserPanics; it is only set on the top-level parser.
+	recoverParserPanics bool
+}
+
+// A structField describes a field handled by a StructParser.
+type structField struct {
+	index int
+	name  string
+	// tag is the parsed "env" tag; it is the zero envTag for nested structs.
+	tag envTag
+	// required is whether the field has no default of any kind, so it must be set in the environment.
+	required bool
+	// handler is the field's entry in fieldHandlers, or nil for nested structs; for Lint.
+	handler func(structValue reflect.Value, state *parseState) (warn, fatal []error)
+	// nested is the parser for a nested struct, or nil if this is not a nested struct.
+	nested *StructParser
+	// nestedPtr is whether the nested struct is a pointer, which is only populated if any of its
+	// env-vars are set.
+	nestedPtr bool
+}
+
+// parseState is the state of a single ParseFromEnv call, shared with the parsers of nested structs.
+type parseState struct {
+	ctx                 context.Context
+	lookup              LookupFunc
+	recoverParserPanics bool
+	// provenance, if non-nil, is populated by the field handlers; see ParseWithProvenance.
+	provenance Provenance
+	// dryRun suppresses the GenerateOptions.OnSet callback, as the struct being populated is not the
+	// caller's; see Lint.
+	dryRun bool
 }
 
 // GenerateParser takes a struct (not a struct pointer) type with `"env:..."` tags on each of its fields, and returns a
 // parser for it.
+//
+// Fields that are structs or pointers to structs, and that don't have an "env" tag, are recursed in to;
+// unless their type has a type handler (such as time.Time or *url.URL), in which case they are ignored,
+// like any other field without a tag.  A pointer-to-struct field is left untouched (typically nil) if none of the nested struct's env-vars are set;
+// otherwise a new struct is allocated for it.
 func GenerateParser(structInfo reflect.Type, typeHandlers map[reflect.Type]FieldTypeHandler) (StructParser, error) {
+	return GenerateParserWithOptions(structInfo, GenerateOptions{TypeHandlers: typeHandlers})
+}
+
+// GenerateParserV2 is like GenerateParser, but also returns the non-fatal warnings that were found while
+// generating the parser (such as use of a deprecated parser alias).  Because they are returned here, they
+// are not also included in the warnings returned by the parser's ParseFromEnv.
+func GenerateParserV2(structInfo reflect.Type, typeHandlers map[reflect.Type]FieldTypeHandler) (StructParser, []error, error) {
+	return GenerateParserV2WithOptions(structInfo, GenerateOptions{TypeHandlers: typeHandlers})
+}
+
+// GenerateParserV2WithOptions is like GenerateParserWithOptions, but returns the generation warnings like
+// GenerateParserV2 does.
+func GenerateParserV2WithOptions(structInfo reflect.Type, opts GenerateOptions) (StructParser, []error, error) {
+	parser, err := GenerateParserWithOptions(structInfo, opts)
+	if err != nil {
+		return StructParser{}, nil, err
+	}
+	warnings := parser.warnings
+	parser.warnings = nil
+	return parser, warnings, nil
+}
+
+// GenerateOptions are the options for GenerateParserWithOptions.
+type GenerateOptions struct {
+	// TypeHandlers are the struct field type handlers to use; if nil, DefaultFieldTypeHandlers() is used.
+	TypeHandlers map[reflect.Type]FieldTypeHandler
+
+	// NameFunc, if set, is used to derive the env-var name of a field from its Go field name, if the field's
+	// tag has an empty name (and doesn't have const=true); for example ScreamingSnakeCase.
+	NameFunc func(fieldName string) string
+
+	// OnSet, if set, is called after each field is successfully set while parsing, with the field's name
+	// (dot-separated for fields of nested structs, like "Sub.Field") and its new value; for example to
+	// send change notifications.
+	OnSet func(fieldName string, value interface{})
+
+	// Choices are named maps of allowed values to their canonical values, for fields with a
+	// "choicesKey" tag option naming one of them; for example {"env": {"dev": "dev", "development":
+	// "dev", "prod": "prod", "production": "prod"}}.
+	Choices map[string]map[string]string
+
+	// Normalizers are named string normalization functions, for fields with a "normalize" tag option
+	// naming one of them; for example the Unicode normalization forms from
+	// github.com/datawire/envconfig/norm.
+	Normalizers map[string]func(string) string
+
+	// DefaultFS, if set, is the filesystem that "defaultFile" tag options are read from (such as an
+	// embed.FS); otherwise they are read from the OS filesystem, relative to the working directory.
+	DefaultFS fs.FS
+
+	// TagName is the struct tag key to read instead of "env", such as "config"; for when another library
+	// also uses the "env" key.  It applies to nested structs too.  If empty, "env" is used.
+	TagName string
+
+	// RecoverParserPanics, if set, causes a panic in a FieldTypeHandler's parser to be returned as a
+	// fatal error for that field (and parsing to continue with the remaining fields), rather than
+	// crashing the program.
+	RecoverParserPanics bool
+}
+
+// GenerateParserWithOptions is like GenerateParser, but takes a GenerateOptions for more control.
+func GenerateParserWithOptions(structInfo reflect.Type, opts GenerateOptions) (StructParser, error) {
 	if structInfo.Kind() != reflect.Struct {
 		return StructParser{}, errors.Errorf("structInfo does not describe a struct, it describes a %s", structInfo.Kind())
 	}
 
-	if typeHandlers == nil {
-		typeHandlers = DefaultFieldTypeHandlers()
+	if opts.TypeHandlers == nil {
+		opts.TypeHandlers = DefaultFieldTypeHandlers()
+	}
+	if opts.TagName == "" {
+		opts.TagName = "env"
+	}
+
+	ret, err := generateParser(structInfo, opts, make(map[reflect.Type]bool))
+	if err != nil {
+		return StructParser{}, err
+	}
+	ret.recoverParserPanics = opts.RecoverParserPanics
+	return ret, nil
+}
+
+func isStructPtr(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Ptr && typ.Elem().Kind() == reflect.Struct
+}
+
+// isIgnoredField returns whether generateParser skips a field: a field is ignored unless it has a tag or
+// is a struct (or pointer to a struct) to recurse in to.  A struct type that has a type handler (such as
+// time.Time or *url.URL) is a value, not something to recurse in to, so it is ignored if it has no tag.
+func isIgnoredField(fieldInfo reflect.StructField, opts GenerateOptions) bool {
+	if fieldInfo.Tag.Get(opts.TagName) != "" {
+		return false
+	}
+	if _, haveHandler := opts.TypeHandlers[fieldInfo.Type]; haveHandler {
+		return true
 	}
+	return fieldInfo.Type.Kind() != reflect.Struct && !isStructPtr(fieldInfo.Type)
+}
+
+// defaultFromCycle follows the chain of "defaultFrom=" references starting at the field named start, and
+// returns the cycle (as a list of field names that starts and ends with the same name) if the chain loops
+// back on itself, or nil if it doesn't.
+func defaultFromCycle(structInfo reflect.Type, tagName, start string) []string {
+	var path []string
+	for name := start; name != ""; name = tagOption(structInfo, tagName, name, "defaultFrom") {
+		for i, prev := range path {
+			if prev == name {
+				return append(path[i:], name)
+			}
+		}
+		path = append(path, name)
+	}
+	return nil
+}
+
+// tagOption returns the raw value of the option key in the tagName tag of the field named fieldName,
+// without validating the tag; or "" if there is no such field or option.
+func tagOption(structInfo reflect.Type, tagName, fieldName, key string) string {
+	fieldInfo, ok := structInfo.FieldByName(fieldName)
+	if !ok {
+		return ""
+	}
+	str := fieldInfo.Tag.Get(tagName)
+	parts := strings.Split(str, ",")
+	if m := tagDefaultRx.FindStringSubmatch(str); m != nil {
+		parts = append(strings.Split(m[1], ","), m[2])
+	}
+	for _, optionStr := range parts[1:] {
+		if keyval := strings.SplitN(strings.TrimSpace(optionStr), "=", 2); len(keyval) == 2 && keyval[0] == key {
+			return strings.TrimSpace(keyval[1])
+		}
+	}
+	return ""
+}
+
+// nestedOptions returns the options to use for generating the parser of the nested struct field
+// fieldName.
+func nestedOptions(opts GenerateOptions, fieldName string) GenerateOptions {
+	if onSet := opts.OnSet; onSet != nil {
+		opts.OnSet = func(subFieldName string, value interface{}) { onSet(fieldName+"."+subFieldName, value) }
+	}
+	return opts
+}
+
+// generateParser is the recursive implementation of GenerateParser.  visiting is the set of struct types
+// that are currently being generated, in order to avoid infinite recursion on self-referential
+// pointer-to-struct fields.
+func generateParser(structInfo reflect.Type, opts GenerateOptions, visiting map[reflect.Type]bool) (StructParser, error) {
+	visiting[structInfo] = true
+	defer delete(visiting, structInfo)
 
 	ret := StructParser{
 		structType:    structInfo,
-		fieldHandlers: make([]func(structValue reflect.Value, lookup LookupFunc) (warn, fatal []error), 0, structInfo.NumField()),
+		fieldHandlers: make([]func(structValue reflect.Value, state *parseState) (warn, fatal []error), 0, structInfo.NumField()),
+		defaulter:     reflect.PtrTo(structInfo).Implements(defaulterType),
+	}
+
+	// Collect the types of all of the fields first, so that "defaultFrom" may reference a field that is
+	// declared later.
+	fieldTypes := make(map[string]reflect.Type, structInfo.NumField())
+	for i := 0; i < structInfo.NumField(); i++ {
+		if fieldInfo := structInfo.Field(i); !isIgnoredField(fieldInfo, opts) {
+			fieldTypes[fieldInfo.Name] = fieldInfo.Type
+		}
 	}
 
-	seen := make(map[string]reflect.Type, structInfo.NumField())
 	for i := 0; i < structInfo.NumField(); i++ {
 		i := i // capture loop variable
 		var fieldInfo reflect.StructField = structInfo.Field(i)
 
-		if fieldInfo.Tag.Get("env") == "" && fieldInfo.Type.Kind() != reflect.Struct {
-			// A field is ignored unless it has an "env" tag or is a struct
+		if isIgnoredField(fieldInfo, opts) {
 			continue
 		}
 
-		typeHandler, typeHandlerOK := typeHandlers[fieldInfo.Type]
+		typeHandler, typeHandlerOK := opts.TypeHandlers[fieldInfo.Type]
 		if !typeHandlerOK {
-			if fieldInfo.Type.Kind() != reflect.Struct {
+			if fieldInfo.Type.Kind() != reflect.Struct && !isStructPtr(fieldInfo.Type) {
 				return StructParser{}, errors.Errorf("struct field %q: unsupported type %s", fieldInfo.Name, fieldInfo.Type)
 			}
-			if fieldInfo.Tag.Get("env") != "" {
+			if fieldInfo.Tag.Get(opts.TagName) != "" {
 				return StructParser{}, errors.Errorf("struct field %q: unsupported type %s; cannot have tag on nested struct", fieldInfo.Name, fieldInfo.Type)
 			}
+			if fieldInfo.Type.Kind() == reflect.Ptr {
+				if fieldInfo.PkgPath != "" || visiting[fieldInfo.Type.Elem()] {
+					// A pointer is ignored if it is unexported (we can't set it) or if it is
+					// self-referential (we'd recurse forever).
+					continue
+				}
+				subType := fieldInfo.Type.Elem()
+				subhandler, err := generateParser(subType, nestedOptions(opts, fieldInfo.Name), visiting)
+				if err != nil {
+					return StructParser{}, errors.Wrapf(err, "struct field %q", fieldInfo.Name)
+				}
+				if len(subhandler.envNames()) == 0 {
+					// Don't touch pointers to things that aren't configuration.
+					continue
+				}
+				for _, warning := range subhandler.warnings {
+					ret.warnings = append(ret.warnings, errors.Wrapf(warning, "struct field %q", fieldInfo.Name))
+				}
+				ret.fieldHandlers = append(ret.fieldHandlers, func(parentStructValue reflect.Value, state *parseState) (warn, fatal []error) {
+					if !subhandler.anySet(state.lookup) {
+						return nil, nil
+					}
+					subStructPtr := reflect.New(subType)
+					parentStructValue.Field(i).Set(subStructPtr)
+					return subhandler.parse(subStructPtr.Elem(), state)
+				})
+				ret.fields = append(ret.fields, structField{index: i, name: fieldInfo.Name, nested: &subhandler, nestedPtr: true})
+				continue
+			}
 			// recurse
-			subhandler, err := GenerateParser(fieldInfo.Type, typeHandlers)
+			subhandler, err := generateParser(fieldInfo.Type, nestedOptions(opts, fieldInfo.Name), visiting)
 			if err != nil {
 				return StructParser{}, errors.Wrapf(err, "struct field %q", fieldInfo.Name)
 			}
-			ret.fieldHandlers = append(ret.fieldHandlers, func(parentStructValue reflect.Value, lookup LookupFunc) (warn, fatal []error) {
-				return subhandler.ParseFromEnv(parentStructValue.Field(i).Addr().Interface(), lookup)
+			for _, warning := range subhandler.warnings {
+				ret.warnings = append(ret.warnings, errors.Wrapf(warning, "struct field %q", fieldInfo.Name))
+			}
+			ret.fieldHandlers = append(ret.fieldHandlers, func(parentStructValue reflect.Value, state *parseState) (warn, fatal []error) {
+				return subhandler.parse(parentStructValue.Field(i), state)
 			})
-			seen[fieldInfo.Name] = fieldInfo.Type
+			ret.fields = append(ret.fields, structField{index: i, name: fieldInfo.Name, nested: &subhandler})
 			continue
 		}
 		validTagOptions := []envTagOption{
 			//nolint:wrapcheck // The caller parser will wrap errors.
+			{
+				Name:      "absPath",
+				Default:   nil,
+				Validator: fieldTypeValidator(fieldInfo.Type, "string", isType(""), validateBool),
+			},
+			{
+				Name:    "clamp",
+				Default: nil,
+				Validator: func(val string) error {
+					kind := fieldInfo.Type.Kind()
+					if !isIntKind(kind) && !isUintKind(kind) {
+						return errors.Errorf("only valid on integer fields, not %s", fieldInfo.Type)
+					}
+					lo, hi, err := parseClamp(val)
+					if err != nil {
+						return err
+					}
+					bound := reflect.New(fieldInfo.Type).Elem()
+					overflows := bound.OverflowInt
+					if isUintKind(kind) {
+						overflows = func(n int64) bool { return n < 0 || bound.OverflowUint(uint64(n)) }
+					}
+					if (lo != math.MinInt64 && overflows(lo)) || (hi != math.MaxInt64 && overflows(hi)) {
+						return errors.Errorf("range %q does not fit in %s", val, fieldInfo.Type)
+					}
+					return nil
+				},
+			},
+			{
+				Name:    "choicesKey",
+				Default: nil,
+				Validator: fieldTypeValidator(fieldInfo.Type, "string", isType(""), func(val string) error {
+					if _, ok := opts.Choices[val]; !ok {
+						return errors.Errorf("GenerateOptions.Choices has no %q entry", val)
+					}
+					return nil
+				}),
+			},
+			{
+				Name:      "collapseSeparators",
+				Default:   nil,
+				Validator: fieldTypeValidator(fieldInfo.Type, "[]string", isType([]string{}), validateBool),
+			},
 			{
 				Name:    "const",
 				Default: stringPointer("false"),
@@ -184,14 +605,27 @@ func GenerateParser(structInfo reflect.Type, typeHandlers map[reflect.Type]Field
 					return nil
 				},
 			},
+			{
+				Name:    "defaultFile",
+				Default: nil,
+				Validator: func(val string) error {
+					if val == "" {
+						return errors.New("must name a file")
+					}
+					return nil
+				},
+			},
 			{
 				Name:    "defaultFrom",
 				Default: nil,
 				Validator: func(val string) error {
-					typ, typOK := seen[val]
+					typ, typOK := fieldTypes[val]
+					cycle := defaultFromCycle(structInfo, opts.TagName, fieldInfo.Name)
 					switch {
 					case !typOK:
-						return errors.Errorf("referenced field %q does not exist (yet?)", val)
+						return errors.Errorf("referenced field %q does not exist", val)
+					case cycle != nil:
+						return errors.Errorf("referenced field %q creates a cycle: %s", val, strings.Join(cycle, " -> "))
 					case typ != fieldInfo.Type:
 						return errors.Errorf("referenced field %q is of type %s, but we need type %s", val, typ, fieldInfo.Type)
 					default:
@@ -199,95 +633,596 @@ func GenerateParser(structInfo reflect.Type, typeHandlers map[reflect.Type]Field
 					}
 				},
 			},
+			{
+				Name:    "defaultFunc",
+				Default: nil,
+				Validator: func(val string) error {
+					method, methodOK := reflect.PtrTo(structInfo).MethodByName(val)
+					if !methodOK {
+						return errors.Errorf("referenced method %q does not exist (is it exported?)", val)
+					}
+					// method.Type includes the receiver as the first argument.
+					typ := method.Type
+					switch {
+					case typ.NumIn() == 1 && typ.NumOut() == 1 && typ.Out(0) == reflect.TypeOf(""):
+						return nil
+					case typ.NumIn() == 1 && typ.NumOut() == 2 && typ.Out(0) == fieldInfo.Type && typ.Out(1) == errorType:
+						return nil
+					default:
+						return errors.Errorf("referenced method %q has signature %s, but we need func() string or func() (%s, error)",
+							val, typ, fieldInfo.Type)
+					}
+				},
+			},
+			{
+				Name:      "deprecated",
+				Default:   nil,
+				Validator: validateBool,
+			},
+			{
+				Name:      "dropEmpty",
+				Default:   nil,
+				Validator: fieldTypeValidator(fieldInfo.Type, "[]string", isType([]string{}), validateBool),
+			},
+			{
+				Name:      "elemTrimPrefix",
+				Default:   nil,
+				Validator: fieldTypeValidator(fieldInfo.Type, "[]string", isType([]string{}), validateAny),
+			},
+			{
+				Name:      "elemTrimSuffix",
+				Default:   nil,
+				Validator: fieldTypeValidator(fieldInfo.Type, "[]string", isType([]string{}), validateAny),
+			},
+			{
+				Name:      "emptyKeepsDefault",
+				Default:   nil,
+				Validator: fieldTypeValidator(fieldInfo.Type, "slice", isKind(reflect.Slice), validateBool),
+			},
+			{
+				Name:      "expand",
+				Default:   nil,
+				Validator: validateBool,
+			},
+			{
+				Name:    "fileMode",
+				Default: nil,
+				Validator: fieldTypeValidator(fieldInfo.Type, "*os.File", isType((*os.File)(nil)), func(val string) error {
+					switch val {
+					case "read", "write", "append":
+						return nil
+					default:
+						return errors.Errorf("must be one of read, write, or append, not %q", val)
+					}
+				}),
+			},
+			{
+				Name:      "fromFile",
+				Default:   nil,
+				Validator: validateBool,
+			},
+			{
+				Name:      "indirect",
+				Default:   nil,
+				Validator: validateBool,
+			},
+			{
+				Name:    "maxBytes",
+				Default: nil,
+				Validator: fieldTypeValidator(fieldInfo.Type, "string, slice, or map", isListOrString, func(val string) error {
+					if n, err := strconv.Atoi(val); err != nil || n <= 0 {
+						return errors.Errorf("must be a positive integer, not %q", val)
+					}
+					return nil
+				}),
+			},
+			{
+				Name:      "maxDuration",
+				Default:   nil,
+				Validator: fieldTypeValidator(fieldInfo.Type, "time.Duration", isType(time.Duration(0)), validateDuration),
+			},
+			{
+				Name:      "minDuration",
+				Default:   nil,
+				Validator: fieldTypeValidator(fieldInfo.Type, "time.Duration", isType(time.Duration(0)), validateDuration),
+			},
+			{
+				Name:      "mergeNumbered",
+				Default:   nil,
+				Validator: fieldTypeValidator(fieldInfo.Type, "[]string", isType([]string{}), validateBool),
+			},
+			{
+				Name:      "negate",
+				Default:   nil,
+				Validator: fieldTypeValidator(fieldInfo.Type, "bool", isType(false), validateBool),
+			},
+			{
+				Name:      "notBlank",
+				Default:   nil,
+				Validator: validateBool,
+			},
+			{
+				Name:    "normalize",
+				Default: nil,
+				Validator: fieldTypeValidator(fieldInfo.Type, "string", isType(""), func(val string) error {
+					if _, ok := opts.Normalizers[val]; !ok {
+						return errors.Errorf("GenerateOptions.Normalizers has no %q entry", val)
+					}
+					return nil
+				}),
+			},
+			{
+				Name:    "nullValue",
+				Default: nil,
+				Validator: func(val string) error {
+					if val == "" {
+						return errors.New("must list at least one value")
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "outputCase",
+				Default:   nil,
+				Validator: fieldTypeValidator(fieldInfo.Type, "string", isType(""), validateCase),
+			},
 			{
 				Name:    "parser",
 				Default: nil,
 				Validator: func(name string) error {
-					if _, ok := typeHandler.Parsers[name]; !ok {
+					if target, isAlias := typeHandler.Aliases[name]; isAlias {
+						name = target
+					}
+					if _, ok := typeHandler.parser(name); !ok {
 						return errors.Errorf("value %q is not one of %v", name, typeHandler.parserNames())
 					}
 					return nil
 				},
 			},
+			{
+				Name:      "trimFileNewline",
+				Default:   nil,
+				Validator: validateBool,
+			},
+			{
+				Name:      "powerOfTwo",
+				Default:   nil,
+				Validator: fieldTypeValidator(fieldInfo.Type, "integer", func(typ reflect.Type) bool { return isIntKind(typ.Kind()) || isUintKind(typ.Kind()) }, validateBool),
+			},
+			{
+				Name:      "reCaseInsensitive",
+				Default:   nil,
+				Validator: fieldTypeValidator(fieldInfo.Type, "*regexp.Regexp", isType((*regexp.Regexp)(nil)), validateBool),
+			},
+			{
+				Name:      "redact",
+				Default:   nil,
+				Validator: validateBool,
+			},
+			{
+				Name:    "removeIn",
+				Default: nil,
+				Validator: func(val string) error {
+					if val == "" {
+						return errors.New("must name a version")
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "resolveHost",
+				Default:   nil,
+				Validator: fieldTypeValidator(fieldInfo.Type, "*url.URL", isType((*url.URL)(nil)), validateBool),
+			},
+			{
+				Name:    "schemeAllow",
+				Default: nil,
+				Validator: fieldTypeValidator(fieldInfo.Type, "*url.URL or []*url.URL", isURLType, func(val string) error {
+					if val == "" {
+						return errors.New("must list at least one scheme")
+					}
+					return nil
+				}),
+			},
+			{
+				Name:    "sha256",
+				Default: nil,
+				Validator: func(val string) error {
+					if val == "" {
+						return errors.New("must name an environment variable")
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "sort",
+				Default:   nil,
+				Validator: fieldTypeValidator(fieldInfo.Type, "string or numeric slice", isSortableSlice, validateSortOrder),
+			},
+			{
+				Name:      "strictlyIncreasing",
+				Default:   nil,
+				Validator: fieldTypeValidator(fieldInfo.Type, "numeric slice", isNumericSlice, validateBool),
+			},
+			{
+				Name:    "template",
+				Default: nil,
+				Validator: func(val string) error {
+					_, err := template.New(fieldInfo.Name).Parse(val)
+					return err
+				},
+			},
+			{
+				Name:    "uniqueItems",
+				Default: nil,
+				Validator: fieldTypeValidator(fieldInfo.Type, "slice", func(typ reflect.Type) bool {
+					return typ.Kind() == reflect.Slice && typ.Elem().Comparable()
+				}, validateBool),
+			},
 		}
 
-		tag, err := parseTagValue(fieldInfo.Tag.Get("env"), validTagOptions)
+		tag, err := parseTagValue(fieldInfo.Tag.Get(opts.TagName), validTagOptions)
 		if err != nil {
 			return StructParser{}, errors.Wrapf(err, "struct field %q", fieldInfo.Name)
 		}
+		// resolve "parser" aliases
+		if target, isAlias := typeHandler.Aliases[tag.Options["parser"]]; isAlias {
+			ret.warnings = append(ret.warnings, errors.Errorf("struct field %q: parser %q is deprecated; use %q instead",
+				fieldInfo.Name, tag.Options["parser"], target))
+			tag.Options["parser"] = target
+		}
 		// validate .Name vs "const"
 		tagOptionConst, _ := strconv.ParseBool(tag.Options["const"])
+		if tag.Name == "" && !tagOptionConst && opts.NameFunc != nil {
+			tag.Name = opts.NameFunc(fieldInfo.Name)
+		}
 		if (tag.Name == "") != tagOptionConst {
 			return StructParser{}, errors.Errorf("struct field %q: does not have an environment variable name (and const=false)", fieldInfo.Name)
 		}
 
+		// validate "fromFile" vs .Name, and "trimFileNewline" vs "fromFile"
+		if _, haveFromFile := tag.fileName(); haveFromFile && tag.Name == "" {
+			return StructParser{}, errors.Errorf("struct field %q: fromFile requires an environment variable name", fieldInfo.Name)
+		}
+		if _, haveFromFile := tag.fileName(); !haveFromFile && tag.Options["trimFileNewline"] != "" {
+			return StructParser{}, errors.Errorf("struct field %q: trimFileNewline requires fromFile=true", fieldInfo.Name)
+		}
+
+		// validate "indirect" vs "mergeNumbered"
+		indirect, _ := strconv.ParseBool(tag.Options["indirect"])
+		if mergeNumbered, _ := strconv.ParseBool(tag.Options["mergeNumbered"]); indirect && mergeNumbered {
+			return StructParser{}, errors.Errorf("struct field %q: has both indirect and mergeNumbered", fieldInfo.Name)
+		}
+
+		// validate "sha256" vs "mergeNumbered"
+		if mergeNumbered, _ := strconv.ParseBool(tag.Options["mergeNumbered"]); mergeNumbered && tag.Options["sha256"] != "" {
+			return StructParser{}, errors.Errorf("struct field %q: has both sha256 and mergeNumbered", fieldInfo.Name)
+		}
+
+		// validate "removeIn" vs "deprecated"
+		if deprecated, _ := strconv.ParseBool(tag.Options["deprecated"]); !deprecated && tag.Options["removeIn"] != "" {
+			return StructParser{}, errors.Errorf("struct field %q: removeIn requires deprecated=true", fieldInfo.Name)
+		}
+
 		// validate "parser" (existence)
 		if _, parserNameOK := tag.Options["parser"]; !parserNameOK {
 			return StructParser{}, errors.Errorf("struct field %q: type %s requires a \"parser\" setting (valid parsers are %v)", fieldInfo.Name, fieldInfo.Type, typeHandler.parserNames())
 		}
 
+		// validate "minDuration" vs "maxDuration"
+		if minStr, maxStr := tag.Options["minDuration"], tag.Options["maxDuration"]; minStr != "" && maxStr != "" {
+			if minDur, maxDur := mustParseDuration(minStr), mustParseDuration(maxStr); minDur > maxDur {
+				return StructParser{}, errors.Errorf("struct field %q: minDuration %s is greater than maxDuration %s", fieldInfo.Name, minDur, maxDur)
+			}
+		}
+
 		dflt, haveDef := tag.Options["default"]
-		_, haveDefFrom := tag.Options["defaultFrom"]
-		// validate "default" vs "defaultFrom"
-		if haveDef && haveDefFrom {
-			return StructParser{}, errors.Errorf("struct field %q: has both default and defaultFrom", fieldInfo.Name)
+		// validate "default" vs "defaultFile" vs "defaultFrom" vs "defaultFunc" vs "template"
+		var defaultOptions []string
+		for _, name := range []string{"default", "defaultFile", "defaultFrom", "defaultFunc", "template"} {
+			if _, ok := tag.Options[name]; ok {
+				defaultOptions = append(defaultOptions, name)
+			}
+		}
+		if len(defaultOptions) > 1 {
+			return StructParser{}, errors.Errorf("struct field %q: has more than one of %s", fieldInfo.Name, strings.Join(defaultOptions, " and "))
+		}
+		// read "defaultFile" in to "default"
+		defFile, haveDefFile := tag.Options["defaultFile"]
+		if haveDefFile {
+			content, err := readDefaultFile(opts.DefaultFS, defFile)
+			if err != nil {
+				return StructParser{}, errors.Wrapf(err, "struct field %q: defaultFile", fieldInfo.Name)
+			}
+			dflt, haveDef = string(content), true
+			tag.Options["default"] = dflt
+		}
+		// validate "emptyKeepsDefault" vs the default
+		if emptyKeepsDefault, _ := strconv.ParseBool(tag.Options["emptyKeepsDefault"]); emptyKeepsDefault && len(defaultOptions) == 0 {
+			return StructParser{}, errors.Errorf("struct field %q: emptyKeepsDefault requires a default", fieldInfo.Name)
 		}
-		// validate "default" vs "parser"
-		if haveDef {
+		// validate "parser=none" vs the default
+		if _, overridden := typeHandler.Parsers[parserNone]; tag.Options["parser"] == parserNone && !overridden && typeHandler.ContextParsers[parserNone] == nil {
+			_, haveDefFrom := tag.Options["defaultFrom"]
+			defFunc, haveDefFunc := tag.Options["defaultFunc"]
+			if haveDefFunc {
+				method, _ := reflect.PtrTo(structInfo).MethodByName(defFunc)
+				haveDefFunc = method.Type.NumOut() == 2
+			}
+			if !tagOptionConst || !(haveDefFrom || haveDefFunc) {
+				return StructParser{}, errors.Errorf("struct field %q: parser=%s requires const=true and either defaultFrom or a defaultFunc that returns (%s, error)",
+					fieldInfo.Name, parserNone, fieldInfo.Type)
+			}
+		}
+		parserFn, _ := typeHandler.parser(tag.Options["parser"])
+		parserFn = wrapParser(parserFn, tag, opts)
+		if key, ok := tag.Options["choicesKey"]; ok {
+			parserFn = choicesParser(parserFn, opts.Choices[key])
+		}
+		// validate "default" vs "parser" (skipping parsers that do I/O, which is only done at parse time)
+		_, isContextParser := typeHandler.ContextParsers[tag.Options["parser"]]
+		resolveHost, _ := strconv.ParseBool(tag.Options["resolveHost"])
+		if haveDef && !isContextParser && !resolveHost {
 			// Check that the expanded value is unchanged before validating, because a default that contains
-			// expanded variables cannot be validated.
-			if expand(dflt, func(string) (string, bool) { return "X", true }) == dflt {
-				parserFn := typeHandler.Parsers[tag.Options["parser"]]
-				if _, err := parserFn(dflt); err != nil {
+			// expanded variables cannot be validated.  The contents of a defaultFile are not expanded.
+			expanded, _ := expand(dflt, func(string) (string, bool) { return "X", true })
+			if haveDefFile || !tag.expandVars() || expanded == dflt {
+				if _, err := parserFn(context.Background(), dflt); err != nil {
 					return StructParser{}, errors.Wrapf(err, "struct field %q: invalid default", fieldInfo.Name)
 				}
 			}
 		}
 
-		ret.fieldHandlers = append(ret.fieldHandlers, generateFieldHandler(i, tag, typeHandler))
-		seen[fieldInfo.Name] = fieldInfo.Type
+		handler := generateFieldHandler(i, tag, parserFn, typeHandler, ret.defaulter, opts.OnSet)
+		ret.fieldHandlers = append(ret.fieldHandlers, handler)
+		ret.fields = append(ret.fields, structField{
+			index:    i,
+			name:     fieldInfo.Name,
+			tag:      tag,
+			required: tag.Name != "" && len(defaultOptions) == 0 && !typeHandler.Optional && !ret.defaulter,
+			handler:  handler,
+		})
 	}
+	ret.fieldHandlers = orderByDefaultFrom(ret.fieldHandlers, ret.fields)
 
 	return ret, nil
 }
 
-func generateFieldHandler(i int, tag envTag, typeHandler FieldTypeHandler) func(structValue reflect.Value, lookup LookupFunc) (warn, fatal []error) {
-	return func(structValue reflect.Value, lookup LookupFunc) (warn, fatal []error) {
+// orderByDefaultFrom reorders handlers (which correspond one-to-one with fields) so that each field that
+// has a "defaultFrom" option is handled after the field that it references, but is otherwise in the same
+// order.  It assumes that there are no cycles, as generateParser has already rejected them.
+func orderByDefaultFrom(handlers []func(structValue reflect.Value, state *parseState) (warn, fatal []error), fields []structField) []func(structValue reflect.Value, state *parseState) (warn, fatal []error) {
+	index := make(map[string]int, len(fields))
+	for i, field := range fields {
+		index[field.name] = i
+	}
+	ret := make([]func(structValue reflect.Value, state *parseState) (warn, fatal []error), 0, len(handlers))
+	done := make([]bool, len(handlers))
+	var visit func(i int)
+	visit = func(i int) {
+		if done[i] {
+			return
+		}
+		done[i] = true
+		if dep, haveDep := index[fields[i].tag.Options["defaultFrom"]]; haveDep {
+			visit(dep)
+		}
+		ret = append(ret, handlers[i])
+	}
+	for i := range handlers {
+		visit(i)
+	}
+	return ret
+}
+
+// A parserPanicError is returned from a parser that panicked, if GenerateOptions.RecoverParserPanics is set.
+type parserPanicError struct {
+	parser string
+	value  interface{}
+}
+
+func (e *parserPanicError) Error() string {
+	return fmt.Sprintf("parser %q panicked: %v", e.parser, e.value)
+}
+
+// generateFieldHandler returns the handler for field i.  If keepUnset, then the struct is a Defaulter, and if
+// the field has no default in its tag, then it is left as-is if it isn't set.
+func generateFieldHandler(i int, tag envTag, parserFn func(context.Context, string) (interface{}, error), typeHandler FieldTypeHandler, keepUnset bool, onSet func(string, interface{})) func(structValue reflect.Value, state *parseState) (warn, fatal []error) {
+	var tmpl *template.Template
+	if tmplStr, haveTmpl := tag.Options["template"]; haveTmpl {
+		// Already validated by generateParser.
+		tmpl = template.Must(template.New(tag.Name).Parse(tmplStr))
+	}
+	return func(structValue reflect.Value, state *parseState) (warn, fatal []error) {
+		lookup := state.lookup
 		parser := tag.Options["parser"]
+		emptyKeepsDefault, _ := strconv.ParseBool(tag.Options["emptyKeepsDefault"])
+		indirect, _ := strconv.ParseBool(tag.Options["indirect"])
+		deprecated, _ := strconv.ParseBool(tag.Options["deprecated"])
+		// expandValue expands the value that came from name, unless expand=false; warning about
+		// references to unset env-vars, which are easy to miss because they expand to "".
+		expandValue := func(name, value string) string {
+			if !tag.expandVars() {
+				return value
+			}
+			expanded, unset := expand(value, lookup)
+			for _, key := range unset {
+				warn = append(warn, errors.Errorf("%s refers to env-var %q, which is not set (expanding it to \"\")", name, key))
+			}
+			return expanded
+		}
+		parse := func(str string) (val interface{}, err error) {
+			if state.recoverParserPanics {
+				defer func() {
+					if r := recover(); r != nil {
+						val, err = nil, &parserPanicError{parser: parser, value: r}
+					}
+				}()
+			}
+			return parserFn(state.ctx, str)
+		}
 
 		var val interface{}
 		var err error
 		found := false
+		source := SourceEnv
+		// raw is the name and raw string value of each env-var that the value came from, for provenance.
+		var raw [][2]string
+		// pinned is the value as it was set, before expansion, for the "sha256" option.
+		var pinned string
 		if tag.Name != "" {
 			var ev string
-			if ev, found = lookup(tag.Name); found {
-				val, err = typeHandler.Parsers[parser](ev)
+			if mergeNumbered, _ := strconv.ParseBool(tag.Options["mergeNumbered"]); mergeNumbered {
+				vars, gapWarn := lookupNumbered(state.ctx, lookup, tag.Name)
+				if gapWarn != nil {
+					warn = append(warn, gapWarn)
+				}
+				if len(vars) > 0 {
+					found = true
+					for j := range vars {
+						vars[j][1] = expandValue(vars[j][0], vars[j][1])
+					}
+					raw = vars
+					val, err = parseNumbered(parse, vars)
+				}
+			} else if ev, found = lookup(tag.Name); found && ((ev == "" && emptyKeepsDefault) || tag.isNullValue(ev)) {
+				// Treat it as if it weren't set.
+				found = false
+			} else if found && indirect {
+				target := ev
+				if ev, found = lookup(target); !found {
+					return nil, []error{errors.Errorf("invalid %s (aborting): %s=%s refers to env-var %q, which is not set",
+						structValue.Type().Field(i).Name, tag.Name, target, target)}
+				}
+				pinned = ev
+				ev = expandValue(target, ev)
+				raw = [][2]string{{tag.Name, target}, {target, ev}}
+				val, err = parse(ev)
+			} else if found {
+				pinned = ev
+				ev = expandValue(tag.Name, ev)
+				raw = [][2]string{{tag.Name, ev}}
+				val, err = parse(ev)
+			} else if fileName, fromFile := tag.fileName(); fromFile {
+				var path string
+				if path, found = lookup(fileName); found {
+					source = SourceFile
+					if ev, err = readValueFile(path, tag.Options["trimFileNewline"]); err == nil {
+						pinned = ev
+						raw = [][2]string{{tag.Name, ev}}
+						val, err = parse(ev)
+					} else {
+						err = errors.Wrapf(err, "%s", fileName)
+					}
+				}
 			}
 		}
 		field := structValue.Type().Field(i)
+		if digestVar, haveDigest := tag.Options["sha256"]; haveDigest && len(raw) > 0 {
+			// Check the value as it was set, before expansion; for indirect that's the target env-var's.
+			if err := checkSHA256(lookup, digestVar, pinned); err != nil {
+				return nil, []error{errors.Wrapf(err, "invalid %s (aborting)", field.Name)}
+			}
+			// Record the digest env-var too, so that it counts as consumed (see ParseFromEnvCapturingRest).
+			digest, _ := lookup(digestVar)
+			raw = append(raw, [2]string{digestVar, digest})
+		}
+		if deprecated && len(raw) > 0 {
+			if removeIn := tag.Options["removeIn"]; removeIn != "" {
+				warn = append(warn, errors.Errorf("%s is deprecated, will be removed in %s", tag.Name, removeIn))
+			} else {
+				warn = append(warn, errors.Errorf("%s is deprecated", tag.Name))
+			}
+		}
 		defStr, haveDef := tag.Options["default"]
 		defFromStr, haveDefFrom := tag.Options["defaultFrom"]
+		defFuncStr, haveDefFunc := tag.Options["defaultFunc"]
+		var panicErr *parserPanicError
 		switch {
+		case errors.As(err, &panicErr):
+			// Never fall back to defaults when a parser is outright broken
+			return nil, []error{errors.Wrapf(err, "invalid %s (aborting)", field.Name)}
 		case found && err == nil:
 			// Never use defaults when the value was found and successfully parsed
 		case haveDef:
+			source = SourceDefault
+			defFile, haveDefFile := tag.Options["defaultFile"]
 			if err != nil {
-				warn = append(warn, errors.Wrapf(err, "invalid %s (falling back to default %q)", field.Name, defStr))
+				if haveDefFile {
+					warn = append(warn, errors.Wrapf(err, "invalid %s (falling back to defaultFile %q)", field.Name, defFile))
+				} else {
+					warn = append(warn, errors.Wrapf(err, "invalid %s (falling back to default %q)", field.Name, defStr))
+				}
+			}
+			expanded := defStr
+			if !haveDefFile {
+				expanded = expandValue("the default for "+tag.Name, defStr)
 			}
-			if val, err = typeHandler.Parsers[parser](expand(defStr, lookup)); err != nil {
+			raw = [][2]string{{tag.Name, expanded}}
+			if val, err = parse(expanded); err != nil {
 				return nil, []error{errors.Wrapf(err, "struct field %q: invalid default", field.Name)}
 			}
 		case haveDefFrom:
+			source = SourceDefaultFrom
 			if err != nil {
 				warn = append(warn, errors.Wrapf(err, "invalid %s (falling back to defaultFrom %q)", field.Name, defFromStr))
 			}
+			raw = [][2]string{{tag.Name, ""}}
 			val = structValue.FieldByName(defFromStr).Interface()
+		case haveDefFunc:
+			source = SourceDefaultFunc
+			if err != nil {
+				warn = append(warn, errors.Wrapf(err, "invalid %s (falling back to defaultFunc %q)", field.Name, defFuncStr))
+			}
+			out := structValue.Addr().MethodByName(defFuncStr).Call(nil)
+			raw = [][2]string{{tag.Name, ""}}
+			if len(out) == 1 {
+				raw = [][2]string{{tag.Name, out[0].String()}}
+				if val, err = parse(out[0].String()); err != nil {
+					return nil, []error{errors.Wrapf(err, "struct field %q: invalid defaultFunc", field.Name)}
+				}
+			} else {
+				if errVal := out[1].Interface(); errVal != nil {
+					return nil, []error{errors.Wrapf(errVal.(error), "struct field %q: defaultFunc", field.Name)}
+				}
+				val = out[0].Interface()
+			}
+		case tmpl != nil:
+			source = SourceTemplate
+			if err != nil {
+				warn = append(warn, errors.Wrapf(err, "invalid %s (falling back to template)", field.Name))
+			}
+			var buf strings.Builder
+			if err := tmpl.Execute(&buf, structValue.Addr().Interface()); err != nil {
+				return nil, []error{errors.Wrapf(err, "struct field %q: template", field.Name)}
+			}
+			raw = [][2]string{{tag.Name, buf.String()}}
+			if val, err = parse(buf.String()); err != nil {
+				return nil, []error{errors.Wrapf(err, "struct field %q: invalid template result %q", field.Name, buf.String())}
+			}
+		case keepUnset:
+			if err != nil {
+				warn = append(warn, errors.Wrapf(err, "invalid %s (falling back to Default())", field.Name))
+			}
+			if state.provenance != nil && tag.Name != "" {
+				state.provenance[tag.Name] = ProvenanceEntry{Source: SourceDefaultMethod}
+			}
+			return warn, nil
+		case typeHandler.Optional && err == nil:
+			// Leave val nil, so that the field gets set to its zero value
+			source = SourceUnset
+			raw = [][2]string{{tag.Name, ""}}
 		default:
-			return nil, []error{errors.Wrapf(ErrNotSet, "invalid %s (aborting)", field.Name)}
+			// With nothing to fall back to, report why the value was invalid; or, if there was no value,
+			// that it is not set.
+			if err == nil {
+				err = ErrNotSet
+			}
+			return nil, []error{errors.Wrapf(err, "invalid %s (aborting)", field.Name)}
 		}
 		fieldType := field.Type
 		if rt := reflect.TypeOf(val); rt != nil {
-			if rt != fieldType {
+			if rt != fieldType && !(fieldType.Kind() == reflect.Interface && rt.Implements(fieldType)) {
 				// This indicates a bug in a parser in envconfig_types.go.  Explicitly (eagerly) check for it
 				// here, instead of waiting for an implicit (lazy) check when something references it with
 				// `defaultFrom`.  The detection being so far from the source would make things hard to debug.
@@ -295,18 +1230,130 @@ func generateFieldHandler(i int, tag envTag, typeHandler FieldTypeHandler) func(
 					fieldType,
 					parser))
 			}
+			if clampStr, haveClamp := tag.Options["clamp"]; haveClamp {
+				var clampWarn error
+				if val, clampWarn = clamp(val, clampStr); clampWarn != nil {
+					warn = append(warn, errors.Wrapf(clampWarn, "invalid %s", field.Name))
+				}
+			}
 			typeHandler.Setter(structValue.Field(i), val)
 		} else {
 			// Assign a zero value to the field (a pointer's zero value is a pointer of the given type that points to nil).
 			structValue.Field(i).Set(reflect.New(fieldType).Elem())
 		}
+		if onSet != nil && !state.dryRun {
+			onSet(field.Name, structValue.Field(i).Interface())
+		}
+		if state.provenance != nil && tag.Name != "" {
+			for _, kv := range raw {
+				state.provenance[kv[0]] = ProvenanceEntry{Raw: kv[1], Source: source}
+			}
+		}
 		return warn, nil
 	}
 }
 
+// envNames returns the names of all env-vars that this parser looks at, including those looked at by
+// nested structs.
+func (p StructParser) envNames() []string {
+	var ret []string
+	for _, field := range p.fields {
+		switch {
+		case field.nested != nil:
+			ret = append(ret, field.nested.envNames()...)
+		case field.tag.Name != "":
+			ret = append(ret, field.tag.Name)
+			ret = append(ret, field.tag.altNames()...)
+		}
+	}
+	return ret
+}
+
+// anySet returns whether any of the env-vars looked at by this parser are set.
+func (p StructParser) anySet(lookup LookupFunc) bool {
+	for _, name := range p.envNames() {
+		if _, set := lookup(name); set {
+			return true
+		}
+	}
+	return false
+}
+
 // ParseFromEnv populates structPtr from values returned by the given LookupFunc function, returning warnings and
-// fatal errors. It panics if structPtr is of the wrong type for this parser.
+// fatal errors. It panics if structPtr is of the wrong type for this parser.  The warnings include any that were
+// found when the parser was generated (such as use of a deprecated parser name), unless the parser came from
+// GenerateParserV2.
 func (p StructParser) ParseFromEnv(structPtr interface{}, lookup LookupFunc) (warn, fatal []error) {
+	return p.ParseFromEnvContext(context.Background(), structPtr, lookup)
+}
+
+// ParseFromEnvContext is like ParseFromEnv, but passes ctx to any ContextParsers (such as
+// "resolvable-host").
+func (p StructParser) ParseFromEnvContext(ctx context.Context, structPtr interface{}, lookup LookupFunc) (warn, fatal []error) {
+	return p.parseTop(ctx, structPtr, lookup, nil)
+}
+
+// ParseWithProvenance is like ParseFromEnv, but also returns the Provenance of the values, which can be compared
+// with the Provenance from a previous parse to see what changed between reloads.
+func (p StructParser) ParseWithProvenance(structPtr interface{}, lookup LookupFunc) (prov Provenance, warn, fatal []error) {
+	prov = make(Provenance)
+	warn, fatal = p.parseTop(context.Background(), structPtr, lookup, prov)
+	return prov, warn, fatal
+}
+
+// ParseFromEnvCapturingRest is like ParseFromEnv, but also returns the "rest" of the env-vars that share
+// the struct's prefix but that aren't looked at by any field; for passing through to a downstream library.
+// allKeys returns the names of all env-vars that are set (such as the keys of os.Environ()); it is also
+// used to find the numbered env-vars of "mergeNumbered" fields, as with WithEnvKeys.
+//
+// The struct's prefix is the longest common prefix of all of the env-var names that the parser looks at,
+// up to and including its last "_"; for example "APP_" for a struct that looks at APP_HOST and APP_PORT.
+// If there is no such prefix, then rest is empty.
+func (p StructParser) ParseFromEnvCapturingRest(structPtr interface{}, lookup LookupFunc, allKeys func() []string) (rest map[string]string, warn, fatal []error) {
+	prov := make(Provenance)
+	warn, fatal = p.parseTop(WithEnvKeys(context.Background(), allKeys), structPtr, lookup, prov)
+
+	known := make(map[string]bool)
+	for _, name := range p.envNames() {
+		known[name] = true
+	}
+	for name := range prov {
+		known[name] = true
+	}
+	rest = make(map[string]string)
+	prefix := p.envPrefix()
+	if prefix == "" {
+		return rest, warn, fatal
+	}
+	for _, key := range allKeys() {
+		if !strings.HasPrefix(key, prefix) || known[key] {
+			continue
+		}
+		if val, ok := lookup(key); ok {
+			rest[key] = val
+		}
+	}
+	return rest, warn, fatal
+}
+
+// envPrefix returns the longest common prefix of envNames, up to and including its last "_"; or "" if
+// there is no such prefix.
+func (p StructParser) envPrefix() string {
+	names := p.envNames()
+	if len(names) == 0 {
+		return ""
+	}
+	prefix := names[0]
+	for _, name := range names[1:] {
+		for !strings.HasPrefix(name, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix[:strings.LastIndex(prefix, "_")+1]
+}
+
+// parseTop is the common implementation of the top-level ParseFromEnv* functions.
+func (p StructParser) parseTop(ctx context.Context, structPtr interface{}, lookup LookupFunc, prov Provenance) (warn, fatal []error) {
 	structPtrValue := reflect.ValueOf(structPtr)
 	if structPtrValue.Kind() != reflect.Ptr {
 		panic(errors.New("structPtr is not a pointer"))
@@ -316,11 +1363,189 @@ func (p StructParser) ParseFromEnv(structPtr interface{}, lookup LookupFunc) (wa
 		panic(errors.Errorf("wrong type (%s) for parser (%s)", structValue.Elem().Type(), p.structType))
 	}
 
+	if prov == nil && len(p.allOrNone) > 0 {
+		// checkAllOrNone needs to know where the values came from.
+		prov = make(Provenance)
+	}
+	warn, fatal = p.parse(structValue, &parseState{
+		ctx:                 ctx,
+		lookup:              lookup,
+		recoverParserPanics: p.recoverParserPanics,
+		provenance:          prov,
+	})
+	fatal = append(fatal, p.checkAllOrNone(structValue, prov)...)
+	return append(append([]error(nil), p.warnings...), warn...), fatal
+}
+
+// AllOrNone returns a copy of the parser that also returns a fatal error from parsing if some, but not
+// all, of the named fields were set from the environment (from their env-var, or from the file named by
+// their NAME_FILE env-var with fromFile=true); for settings that only make sense together, such as a TLS
+// certificate and its key.  A field whose value came from a default is not set from the environment.
+// Fields of nested structs are named like "Sub.Field", as in FieldSnapshot.FieldName.  It panics if a
+// name is not one of the parser's fields.
+func (p StructParser) AllOrNone(fields ...string) StructParser {
+	known := make(map[string]bool)
+	for _, name := range p.fieldNames("") {
+		known[name] = true
+	}
+	for _, name := range fields {
+		if !known[name] {
+			panic(errors.Errorf("AllOrNone: %s has no field %q", p.structType, name))
+		}
+	}
+	// Use a full slice expression so that copies of p don't share appends.
+	p.allOrNone = append(p.allOrNone[:len(p.allOrNone):len(p.allOrNone)], append([]string(nil), fields...))
+	return p
+}
+
+// fieldNames returns the names of the (non-struct) fields that the parser handles, including those of
+// nested structs, with prefix prepended.
+func (p StructParser) fieldNames(prefix string) []string {
+	var ret []string
+	for _, field := range p.fields {
+		if field.nested != nil {
+			ret = append(ret, field.nested.fieldNames(prefix+field.name+".")...)
+		} else {
+			ret = append(ret, prefix+field.name)
+		}
+	}
+	return ret
+}
+
+// checkAllOrNone returns an error for each AllOrNone group that was only partly set from the
+// environment by the parse that just populated structValue with the Provenance prov.
+func (p StructParser) checkAllOrNone(structValue reflect.Value, prov Provenance) []error {
+	if len(p.allOrNone) == 0 {
+		return nil
+	}
+	fromEnv := make(map[string]bool)
+	for _, field := range p.snapshot(structValue, "", prov) {
+		fromEnv[field.FieldName] = field.Source == SourceEnv || field.Source == SourceFile
+	}
+	var errs []error
+	for _, group := range p.allOrNone {
+		if err := allOrNoneError(group, fromEnv); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// allOrNoneError returns an error if some, but not all, of the fields in the AllOrNone group are
+// fromEnv.
+func allOrNoneError(group []string, fromEnv map[string]bool) error {
+	var set, unset []string
+	for _, name := range group {
+		if fromEnv[name] {
+			set = append(set, name)
+		} else {
+			unset = append(unset, name)
+		}
+	}
+	if len(set) > 0 && len(unset) > 0 {
+		return errors.Errorf("fields %s must be set all together or not at all (set: %s; not set: %s)",
+			strings.Join(group, ", "), strings.Join(set, ", "), strings.Join(unset, ", "))
+	}
+	return nil
+}
+
+func (p StructParser) parse(structValue reflect.Value, state *parseState) (warn, fatal []error) {
+	if p.defaulter {
+		structValue.Addr().Interface().(Defaulter).Default()
+	}
 	for _, fieldHandler := range p.fieldHandlers {
-		_warn, _fatal := fieldHandler(structValue, lookup)
+		_warn, _fatal := fieldHandler(structValue, state)
 		warn = append(warn, _warn...)
 		fatal = append(fatal, _fatal...)
 	}
 
 	return warn, fatal
 }
+
+// ParseFromEnvThen is like ParseFromEnv, but then also calls validate with structPtr to perform any checks that
+// involve the struct as a whole (such as checking the relationship between several fields), and appends the errors
+// that it returns to fatal.  validate is not called if parsing the individual fields already resulted in fatal
+// errors, as the struct is likely to be only partially populated.
+func (p StructParser) ParseFromEnvThen(structPtr interface{}, lookup LookupFunc, validate func(interface{}) []error) (warn, fatal []error) {
+	warn, fatal = p.ParseFromEnv(structPtr, lookup)
+	if len(fatal) == 0 {
+		fatal = validate(structPtr)
+	}
+	return warn, fatal
+}
+
+// CheckRequired returns the names of the required env-vars (those with no default of any kind) that are
+// not set, without attempting to parse anything.  The env-vars of a nested pointer-to-struct are only
+// required if at least one of that struct's env-vars is set.
+func (p StructParser) CheckRequired(lookup LookupFunc) []string {
+	var missing []string
+	for _, field := range p.fields {
+		switch {
+		case field.nested != nil:
+			if field.nestedPtr && !field.nested.anySet(lookup) {
+				continue
+			}
+			missing = append(missing, field.nested.CheckRequired(lookup)...)
+		case field.required:
+			set := false
+			for _, name := range append([]string{field.tag.Name}, field.tag.altNames()...) {
+				var val string
+				if val, set = lookup(name); set && !(name == field.tag.Name && field.tag.isNullValue(val)) {
+					break
+				}
+				set = false
+			}
+			if !set {
+				missing = append(missing, field.tag.Name)
+			}
+		}
+	}
+	return missing
+}
+
+// ParseOrError is like ParseFromEnv, but discards warnings and collapses any fatal errors in to a single
+// error.  It returns nil if there were no fatal errors, the error itself if there was exactly one, and a
+// MultiError if there were several.
+func (p StructParser) ParseOrError(structPtr interface{}, lookup LookupFunc) error {
+	_, fatal := p.ParseFromEnv(structPtr, lookup)
+	return joinErrors(fatal)
+}
+
+// ParseOrErrorStrict is like ParseOrError, but treats warnings as fatal errors.
+func (p StructParser) ParseOrErrorStrict(structPtr interface{}, lookup LookupFunc) error {
+	warn, fatal := p.ParseFromEnv(structPtr, lookup)
+	return joinErrors(append(fatal, warn...))
+}
+
+// MultiError is an error that aggregates several errors.
+type MultiError []error
+
+func (e MultiError) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%d errors: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// Is reports whether any of the aggregated errors matches target, so that errors.Is works on a
+// MultiError.
+func (e MultiError) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinErrors(errs []error) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	default:
+		return MultiError(errs)
+	}
+}
@@ -0,0 +1,109 @@
+//go:build go1.19
+// +build go1.19
+
+package envconfig_test
+
+import (
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/datawire/envconfig"
+)
+
+func init() {
+	versionedSmokeTests["*atomic.Int64"] = map[string]smokeTestcase{
+		"strconv.ParseInt": {
+			Object: &struct {
+				Value *atomic.Int64 `env:"VALUE,parser=strconv.ParseInt"`
+			}{},
+			EnvVar: "123",
+			Value: func(obj interface{}) interface{} {
+				return &struct{ Value int64 }{Value: reflect.ValueOf(obj).Elem().Field(0).Interface().(*atomic.Int64).Load()}
+			},
+			Expected: `&{123}`,
+		},
+	}
+	versionedSmokeTests["*atomic.Bool"] = map[string]smokeTestcase{
+		"strconv.ParseBool": {
+			Object: &struct {
+				Value *atomic.Bool `env:"VALUE,parser=strconv.ParseBool"`
+			}{},
+			EnvVar: "true",
+			Value: func(obj interface{}) interface{} {
+				return &struct{ Value bool }{Value: reflect.ValueOf(obj).Elem().Field(0).Interface().(*atomic.Bool).Load()}
+			},
+			Expected: `&{true}`,
+		},
+	}
+}
+
+func TestAtomicTypes(t *testing.T) {
+	var config struct {
+		Workers *atomic.Int64 `env:"WORKERS,parser=strconv.ParseInt,default=4"`
+		Verbose *atomic.Bool  `env:"VERBOSE,parser=strconv.ParseBool,default=false"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("allocates", func(t *testing.T) {
+		config.Workers, config.Verbose = nil, nil
+		warn, fatal := parser.ParseFromEnv(&config, testEnv{}.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		if assert.NotNil(t, config.Workers) && assert.NotNil(t, config.Verbose) {
+			assert.Equal(t, int64(4), config.Workers.Load())
+			assert.False(t, config.Verbose.Load())
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		config.Workers, config.Verbose = nil, nil
+		warn, fatal := parser.ParseFromEnv(&config, testEnv{"WORKERS": "lots", "VERBOSE": "very"}.lookup)
+		assert.Equal(t, len(warn), 2, "There should be 2 warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		assert.Equal(t, int64(4), config.Workers.Load())
+		assert.False(t, config.Verbose.Load())
+	})
+
+	t.Run("reparse-is-seen-by-concurrent-reader", func(t *testing.T) {
+		config.Workers, config.Verbose = nil, nil
+		if _, fatal := parser.ParseFromEnv(&config, testEnv{}.lookup); len(fatal) > 0 {
+			t.Fatal(fatal)
+		}
+		// The reader holds on to the pointers, the way that a long-running worker would.
+		workers, verbose := config.Workers, config.Verbose
+
+		// The reader polls (without any other synchronization) until it sees the reparsed values.
+		seen := make(chan struct{})
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			for !(workers.Load() == 16 && verbose.Load()) {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+			}
+			close(seen)
+		}()
+
+		warn, fatal := parser.ParseFromEnv(&config, testEnv{"WORKERS": "16", "VERBOSE": "true"}.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		assert.Same(t, workers, config.Workers, "a reparse should not replace the pointer")
+		assert.Same(t, verbose, config.Verbose, "a reparse should not replace the pointer")
+
+		select {
+		case <-seen:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the concurrent reader did not see the reparsed values")
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package envconfig
+
+import (
+	"context"
+	"reflect"
+)
+
+// A LintResult describes what parsing an environment would do with a single field, as returned by
+// StructParser.Lint.
+type LintResult struct {
+	// EnvName is the name of the field's env-var.
+	EnvName string
+	// FieldName is the name of the Go field, as in FieldSnapshot.FieldName.
+	FieldName string
+	// Present is whether the field's env-var (or, with fromFile=true, the env-var naming its file) is
+	// set.
+	Present bool
+	// Parses is whether the value from the environment would be used; that is, it is present, it
+	// parses, and it satisfies the field's tag options (rather than falling back to a default).
+	Parses bool
+	// EqualsDefault is whether the value from the environment would be used, but is equal to the value
+	// that the field would have if it weren't set; so setting it is redundant.
+	EqualsDefault bool
+	// Errors are the problems that parsing would report for the field, whether fatal or not (such as an
+	// invalid value that falls back to the default, or a deprecated env-var); and the error for any
+	// AllOrNone group that the field is in that would only be partly set.
+	Errors []error
+}
+
+// Lint reports what parsing the environment given by lookup would do with each field that has an
+// env-var, in the order of the fields in the struct, without populating a struct of the caller's; for
+// a "config lint" command.  Like Snapshot, the fields of a nested pointer-to-struct are not included if
+// none of its env-vars are set.  GenerateOptions.OnSet is not called.  Like ParseFromEnvContext, ctx is
+// passed to any ContextParsers (such as "resolvable-host"); but parsers with side effects only check the
+// value, so the "os.OpenFile" parser doesn't open (or create, or truncate) the file.
+func (p StructParser) Lint(ctx context.Context, lookup LookupFunc) []LintResult {
+	state := &parseState{
+		ctx:                 context.WithValue(ctx, dryRunContextKey{}, true),
+		lookup:              lookup,
+		recoverParserPanics: true,
+		provenance:          make(Provenance),
+		dryRun:              true,
+	}
+	// Parse everything first, so that the fields referenced by defaultFrom or a template are set.
+	structValue := reflect.New(p.structType).Elem()
+	p.parse(structValue, state)
+	results := p.lint(structValue, "", state)
+
+	fromEnv := make(map[string]bool, len(results))
+	for _, result := range results {
+		fromEnv[result.FieldName] = result.Parses
+	}
+	for _, group := range p.allOrNone {
+		err := allOrNoneError(group, fromEnv)
+		if err == nil {
+			continue
+		}
+		for i := range results {
+			for _, name := range group {
+				if results[i].FieldName == name {
+					results[i].Errors = append(results[i].Errors, err)
+				}
+			}
+		}
+	}
+	return results
+}
+
+func (p StructParser) lint(structValue reflect.Value, prefix string, state *parseState) []LintResult {
+	var ret []LintResult
+	for _, field := range p.fields {
+		switch {
+		case field.nested != nil:
+			fieldValue := structValue.Field(field.index)
+			if field.nestedPtr {
+				if fieldValue.IsNil() {
+					continue
+				}
+				fieldValue = fieldValue.Elem()
+			}
+			ret = append(ret, field.nested.lint(fieldValue, prefix+field.name+".", state)...)
+		case field.tag.Name != "":
+			ret = append(ret, p.lintField(structValue, field, prefix, state))
+		}
+	}
+	return ret
+}
+
+// lintField re-runs the handler for field on the already-parsed structValue, to find out what happens
+// to that field in particular.
+func (p StructParser) lintField(structValue reflect.Value, field structField, prefix string, state *parseState) LintResult {
+	result := LintResult{
+		EnvName:   field.tag.Name,
+		FieldName: prefix + field.name,
+	}
+	names := append([]string{field.tag.Name}, field.tag.altNames()...)
+	for _, name := range names {
+		if val, set := state.lookup(name); set && !(name == field.tag.Name && field.tag.isNullValue(val)) {
+			result.Present = true
+		}
+	}
+
+	warn, fatal := field.handler(structValue, state)
+	result.Errors = append(warn, fatal...)
+	source := p.fieldSource(field, state.provenance)
+	result.Parses = len(fatal) == 0 && (source == SourceEnv || source == SourceFile)
+	if !result.Parses {
+		return result
+	}
+
+	// Parse the field again with its env-vars hidden, to get its default.  Start from a copy with the
+	// field zeroed, as some Setters store in to the existing value rather than replacing it.
+	hidden := make(map[string]bool, len(names))
+	for _, name := range names {
+		hidden[name] = true
+	}
+	defState := *state
+	defState.provenance = make(Provenance)
+	defState.lookup = func(key string) (string, bool) {
+		if hidden[key] {
+			return "", false
+		}
+		return state.lookup(key)
+	}
+	defValue := reflect.New(structValue.Type()).Elem()
+	defValue.Set(structValue)
+	defValue.Field(field.index).Set(reflect.Zero(structValue.Field(field.index).Type()))
+	if p.defaulter {
+		fresh := reflect.New(structValue.Type())
+		fresh.Interface().(Defaulter).Default()
+		defValue.Field(field.index).Set(fresh.Elem().Field(field.index))
+	}
+	if _, fatal := field.handler(defValue, &defState); len(fatal) == 0 {
+		if field.tag.Options["parser"] == "os.OpenFile" {
+			// The files weren't opened (see parseOpenFile), so compare their paths.
+			result.EqualsDefault = state.provenance[field.tag.Name].Raw == defState.provenance[field.tag.Name].Raw
+		} else {
+			result.EqualsDefault = reflect.DeepEqual(structValue.Field(field.index).Interface(), defValue.Field(field.index).Interface())
+		}
+	}
+	return result
+}
@@ -0,0 +1,688 @@
+package envconfig
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// wrapParser wraps a field's parser to apply any tag options that transform or validate the parsed
+// value.  Because the wrapped parser is used for defaults as well as for env-var values, a value rejected
+// by an option falls back to the default just like a value rejected by the parser itself would.
+//
+// The options apply in the order they are checked below, not the order they appear in the tag; in
+// particular, the elemTrim… options and then sort are applied to a list before uniqueItems and
+// strictlyIncreasing check it.
+func wrapParser(parserFn func(context.Context, string) (interface{}, error), tag envTag, opts GenerateOptions) func(context.Context, string) (interface{}, error) {
+	if maxStr, ok := tag.Options["maxBytes"]; ok {
+		// Checked before parsing, so that an oversized value doesn't get as far as allocating a huge
+		// list; and the value isn't included in the error, because it is huge.
+		maxBytes, _ := strconv.Atoi(maxStr)
+		inner := parserFn
+		parserFn = func(ctx context.Context, str string) (interface{}, error) {
+			if len(str) > maxBytes {
+				return nil, errors.Errorf("is %d bytes, which is more than the maximum of %d", len(str), maxBytes)
+			}
+			return inner(ctx, str)
+		}
+	}
+	if mode, ok := tag.Options["fileMode"]; ok {
+		inner := parserFn
+		parserFn = func(ctx context.Context, str string) (interface{}, error) {
+			return inner(context.WithValue(ctx, fileModeContextKey{}, mode), str)
+		}
+	}
+	if notBlank, _ := strconv.ParseBool(tag.Options["notBlank"]); notBlank {
+		inner := parserFn
+		parserFn = func(ctx context.Context, str string) (interface{}, error) {
+			if strings.TrimSpace(str) == "" {
+				return nil, errors.Errorf("is blank: %q", str)
+			}
+			return inner(ctx, str)
+		}
+	}
+	if dropEmpty, _ := strconv.ParseBool(tag.Options["dropEmpty"]); dropEmpty {
+		parserFn = filterParser(parserFn, func(val interface{}) (interface{}, error) {
+			in := val.([]string)
+			out := make([]string, 0, len(in))
+			for _, s := range in {
+				if s != "" {
+					out = append(out, s)
+				}
+			}
+			return out, nil
+		})
+	}
+	if collapse, _ := strconv.ParseBool(tag.Options["collapseSeparators"]); collapse {
+		parserFn = filterParser(parserFn, func(val interface{}) (interface{}, error) {
+			// An empty element in the middle of the list is from a run of consecutive separators; unlike
+			// dropEmpty, an empty element from a leading or trailing separator is kept.
+			in := val.([]string)
+			out := make([]string, 0, len(in))
+			for i, s := range in {
+				if s != "" || i == 0 || i == len(in)-1 {
+					out = append(out, s)
+				}
+			}
+			return out, nil
+		})
+	}
+	if negate, _ := strconv.ParseBool(tag.Options["negate"]); negate {
+		parserFn = filterParser(parserFn, func(val interface{}) (interface{}, error) {
+			return !val.(bool), nil
+		})
+	}
+	if name, ok := tag.Options["normalize"]; ok {
+		normalize := opts.Normalizers[name]
+		parserFn = filterParser(parserFn, func(val interface{}) (interface{}, error) {
+			if !utf8.ValidString(val.(string)) {
+				return nil, errors.Errorf("invalid UTF-8: %q", val)
+			}
+			return normalize(val.(string)), nil
+		})
+	}
+	switch tag.Options["outputCase"] {
+	case "lower":
+		parserFn = filterParser(parserFn, func(val interface{}) (interface{}, error) {
+			return strings.ToLower(val.(string)), nil
+		})
+	case "upper":
+		parserFn = filterParser(parserFn, func(val interface{}) (interface{}, error) {
+			return strings.ToUpper(val.(string)), nil
+		})
+	}
+	if absPath, _ := strconv.ParseBool(tag.Options["absPath"]); absPath {
+		parserFn = filterParser(parserFn, func(val interface{}) (interface{}, error) {
+			if val.(string) == "" {
+				return val, nil
+			}
+			return filepath.Abs(val.(string))
+		})
+	}
+	if powerOfTwo, _ := strconv.ParseBool(tag.Options["powerOfTwo"]); powerOfTwo {
+		parserFn = filterParser(parserFn, func(val interface{}) (interface{}, error) {
+			if rv := reflect.ValueOf(val); isUintKind(rv.Kind()) {
+				if n := rv.Uint(); n == 0 || n&(n-1) != 0 {
+					return nil, errors.Errorf("%d is not a power of two", n)
+				}
+			} else if n := rv.Int(); n <= 0 || n&(n-1) != 0 {
+				return nil, errors.Errorf("%d is not a power of two", n)
+			}
+			return val, nil
+		})
+	}
+	if caseInsensitive, _ := strconv.ParseBool(tag.Options["reCaseInsensitive"]); caseInsensitive {
+		parserFn = filterParser(parserFn, func(val interface{}) (interface{}, error) {
+			return regexp.Compile("(?i)" + val.(*regexp.Regexp).String())
+		})
+	}
+	if prefix, ok := tag.Options["elemTrimPrefix"]; ok {
+		parserFn = filterParser(parserFn, func(val interface{}) (interface{}, error) {
+			in := val.([]string)
+			out := make([]string, 0, len(in))
+			for _, s := range in {
+				out = append(out, strings.TrimPrefix(s, prefix))
+			}
+			return out, nil
+		})
+	}
+	if suffix, ok := tag.Options["elemTrimSuffix"]; ok {
+		parserFn = filterParser(parserFn, func(val interface{}) (interface{}, error) {
+			in := val.([]string)
+			out := make([]string, 0, len(in))
+			for _, s := range in {
+				out = append(out, strings.TrimSuffix(s, suffix))
+			}
+			return out, nil
+		})
+	}
+	if order, ok := tag.Options["sort"]; ok {
+		parserFn = filterParser(parserFn, func(val interface{}) (interface{}, error) {
+			sortSlice(reflect.ValueOf(val), order == "desc")
+			return val, nil
+		})
+	}
+	if unique, _ := strconv.ParseBool(tag.Options["uniqueItems"]); unique {
+		parserFn = filterParser(parserFn, func(val interface{}) (interface{}, error) {
+			slice := reflect.ValueOf(val)
+			seen := make(map[interface{}]struct{}, slice.Len())
+			for i := 0; i < slice.Len(); i++ {
+				item := slice.Index(i).Interface()
+				if _, dup := seen[item]; dup {
+					return nil, errors.Errorf("duplicate item %q", fmt.Sprint(item))
+				}
+				seen[item] = struct{}{}
+			}
+			return val, nil
+		})
+	}
+	if increasing, _ := strconv.ParseBool(tag.Options["strictlyIncreasing"]); increasing {
+		parserFn = filterParser(parserFn, func(val interface{}) (interface{}, error) {
+			slice := reflect.ValueOf(val)
+			less := elemLess(slice.Type())
+			for i := 1; i < slice.Len(); i++ {
+				if !less(slice.Index(i-1), slice.Index(i)) {
+					return nil, errors.Errorf("not strictly increasing: item %d (%v) is not greater than item %d (%v)",
+						i, slice.Index(i), i-1, slice.Index(i-1))
+				}
+			}
+			return val, nil
+		})
+	}
+	if minStr, ok := tag.Options["minDuration"]; ok {
+		minDur := mustParseDuration(minStr)
+		parserFn = filterParser(parserFn, func(val interface{}) (interface{}, error) {
+			if val.(time.Duration) < minDur {
+				return nil, errors.Errorf("%s is less than the minimum of %s", val, minDur)
+			}
+			return val, nil
+		})
+	}
+	if maxStr, ok := tag.Options["maxDuration"]; ok {
+		maxDur := mustParseDuration(maxStr)
+		parserFn = filterParser(parserFn, func(val interface{}) (interface{}, error) {
+			if val.(time.Duration) > maxDur {
+				return nil, errors.Errorf("%s is greater than the maximum of %s", val, maxDur)
+			}
+			return val, nil
+		})
+	}
+	if schemes, ok := tag.Options["schemeAllow"]; ok {
+		allowed := strings.Split(schemes, "|")
+		parserFn = filterParser(parserFn, func(val interface{}) (interface{}, error) {
+			if u, isURL := val.(*url.URL); isURL {
+				return val, checkScheme(u, allowed)
+			}
+			for i, u := range val.([]*url.URL) {
+				if err := checkScheme(u, allowed); err != nil {
+					return nil, errors.Wrapf(err, "item %d (%q)", i, u)
+				}
+			}
+			return val, nil
+		})
+	}
+	if resolve, _ := strconv.ParseBool(tag.Options["resolveHost"]); resolve {
+		inner := parserFn
+		parserFn = func(ctx context.Context, str string) (interface{}, error) {
+			val, err := inner(ctx, str)
+			if err != nil || val == nil {
+				return val, err
+			}
+			host := val.(*url.URL).Hostname()
+			if host == "" {
+				return nil, errors.Errorf("URL %q has no host to resolve", str)
+			}
+			if _, err := lookupHost(ctx, host); err != nil {
+				return nil, err
+			}
+			return val, nil
+		}
+	}
+	return parserFn
+}
+
+// choicesParser returns a parser that maps the string result of parserFn to its canonical value in
+// choices, and rejects values that aren't in choices.
+func choicesParser(parserFn func(context.Context, string) (interface{}, error), choices map[string]string) func(context.Context, string) (interface{}, error) {
+	return filterParser(parserFn, func(val interface{}) (interface{}, error) {
+		canonical, ok := choices[val.(string)]
+		if !ok {
+			allowed := make([]string, 0, len(choices))
+			for choice := range choices {
+				allowed = append(allowed, choice)
+			}
+			sort.Strings(allowed)
+			return nil, errors.Errorf("%q is not one of %q", val, allowed)
+		}
+		return canonical, nil
+	})
+}
+
+// filterParser returns a parser that passes the result of parserFn through filter.
+func filterParser(parserFn func(context.Context, string) (interface{}, error), filter func(interface{}) (interface{}, error)) func(context.Context, string) (interface{}, error) {
+	return func(ctx context.Context, str string) (interface{}, error) {
+		val, err := parserFn(ctx, str)
+		if err != nil || val == nil {
+			return val, err
+		}
+		return filter(val)
+	}
+}
+
+// fieldTypeValidator returns an option validator that rejects the option on fields whose type isn't
+// accepted by typeOK (typeDesc describes the accepted types), and otherwise validates the option's value
+// with validator.
+func fieldTypeValidator(fieldType reflect.Type, typeDesc string, typeOK func(reflect.Type) bool, validator func(string) error) func(string) error {
+	return func(val string) error {
+		if !typeOK(fieldType) {
+			return errors.Errorf("only valid on %s fields, not %s", typeDesc, fieldType)
+		}
+		return validator(val)
+	}
+}
+
+// isType returns a fieldTypeValidator typeOK function that accepts only the type of v.
+func isType(v interface{}) func(reflect.Type) bool {
+	return func(typ reflect.Type) bool { return typ == reflect.TypeOf(v) }
+}
+
+// isKind returns a fieldTypeValidator typeOK function that accepts any type of the given kind.
+func isKind(kind reflect.Kind) func(reflect.Type) bool {
+	return func(typ reflect.Type) bool { return typ.Kind() == kind }
+}
+
+//nolint:wrapcheck // The caller parser will wrap errors.
+func validateBool(val string) error {
+	_, err := strconv.ParseBool(val)
+	return err
+}
+
+//nolint:wrapcheck // The caller parser will wrap errors.
+func validateDuration(val string) error {
+	_, err := time.ParseDuration(val)
+	return err
+}
+
+func validateAny(string) error {
+	return nil
+}
+
+// mustParseDuration is time.ParseDuration for values that have already been validated.
+func mustParseDuration(str string) time.Duration {
+	dur, err := time.ParseDuration(str)
+	if err != nil {
+		// This should have been caught by GenerateParser.
+		panic(err)
+	}
+	return dur
+}
+
+func isIntKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	default:
+		return false
+	}
+}
+
+// isNumericSlice returns whether typ is a slice of numbers.
+func isNumericSlice(typ reflect.Type) bool {
+	return isSortableSlice(typ) && typ.Elem().Kind() != reflect.String
+}
+
+// isListOrString returns whether typ is a string, slice, or map, which may be size-limited with a
+// "maxBytes=" option.
+func isListOrString(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		return true
+	default:
+		return false
+	}
+}
+
+// isURLType returns whether typ is *url.URL or []*url.URL, which may be restricted with a "schemeAllow="
+// option.
+func isURLType(typ reflect.Type) bool {
+	return typ == reflect.TypeOf((*url.URL)(nil)) || typ == reflect.TypeOf([]*url.URL{})
+}
+
+// checkScheme returns an error if u's scheme is not one of allowed.
+func checkScheme(u *url.URL, allowed []string) error {
+	for _, scheme := range allowed {
+		if strings.EqualFold(u.Scheme, scheme) {
+			return nil
+		}
+	}
+	return errors.Errorf("scheme %q is not one of %q", u.Scheme, allowed)
+}
+
+// isSortableSlice returns whether typ is a slice of strings or of numbers, which may be sorted with a
+// "sort=" option.
+func isSortableSlice(typ reflect.Type) bool {
+	if typ.Kind() != reflect.Slice {
+		return false
+	}
+	switch kind := typ.Elem().Kind(); {
+	case kind == reflect.String, isIntKind(kind), isUintKind(kind), kind == reflect.Float32, kind == reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+func isUintKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	default:
+		return false
+	}
+}
+
+func validateSortOrder(val string) error {
+	switch val {
+	case "asc", "desc":
+		return nil
+	default:
+		return errors.Errorf("must be %q or %q, not %q", "asc", "desc", val)
+	}
+}
+
+func validateCase(val string) error {
+	switch val {
+	case "lower", "upper":
+		return nil
+	default:
+		return errors.Errorf("must be %q or %q, not %q", "lower", "upper", val)
+	}
+}
+
+// sortSlice sorts a slice that isSortableSlice accepts in place; strings are sorted lexicographically,
+// and numbers numerically.  The sort is stable.
+func sortSlice(slice reflect.Value, desc bool) {
+	less := elemLess(slice.Type())
+	sort.SliceStable(slice.Interface(), func(i, j int) bool {
+		if desc {
+			return less(slice.Index(j), slice.Index(i))
+		}
+		return less(slice.Index(i), slice.Index(j))
+	})
+}
+
+// elemLess returns a function that compares two elements of a slice type that isSortableSlice accepts.
+func elemLess(sliceType reflect.Type) func(a, b reflect.Value) bool {
+	switch kind := sliceType.Elem().Kind(); {
+	case kind == reflect.String:
+		return func(a, b reflect.Value) bool { return a.String() < b.String() }
+	case isIntKind(kind):
+		return func(a, b reflect.Value) bool { return a.Int() < b.Int() }
+	case isUintKind(kind):
+		return func(a, b reflect.Value) bool { return a.Uint() < b.Uint() }
+	default:
+		return func(a, b reflect.Value) bool { return a.Float() < b.Float() }
+	}
+}
+
+// clampKeywords are the symbolic values that may be used as an endpoint of a "clamp=" option, in
+// addition to plain integers.
+var clampKeywords = map[string]func() int64{
+	"NumCPU":         func() int64 { return int64(runtime.NumCPU()) },
+	"runtime.NumCPU": func() int64 { return int64(runtime.NumCPU()) },
+}
+
+// parseClamp parses the value of a "clamp=MIN:MAX" option.  Either endpoint may be left empty to leave
+// that side of the range unbounded.
+func parseClamp(str string) (lo, hi int64, err error) {
+	parts := strings.Split(str, ":")
+	if len(parts) != 2 {
+		return 0, 0, errors.Errorf("not a MIN:MAX range: %q", str)
+	}
+	bounds := [2]int64{math.MinInt64, math.MaxInt64}
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		switch {
+		case part == "":
+			// leave unbounded
+		case clampKeywords[part] != nil:
+			bounds[i] = clampKeywords[part]()
+		default:
+			n, err := strconv.ParseInt(part, 10, 64)
+			if err != nil {
+				return 0, 0, errors.Errorf("invalid endpoint %q: must be an integer or one of %v", part, clampKeywordNames())
+			}
+			bounds[i] = n
+		}
+	}
+	if bounds[0] > bounds[1] {
+		return 0, 0, errors.Errorf("invalid range %q: minimum %d is greater than maximum %d", str, bounds[0], bounds[1])
+	}
+	return bounds[0], bounds[1], nil
+}
+
+func clampKeywordNames() []string {
+	ret := make([]string, 0, len(clampKeywords))
+	for name := range clampKeywords {
+		ret = append(ret, name)
+	}
+	return ret
+}
+
+// clamp bounds the integer (signed or unsigned) val in to the range described by the "clamp=" option
+// clampStr, returning a warning if it had to be adjusted.
+func clamp(val interface{}, clampStr string) (interface{}, error) {
+	lo, hi, err := parseClamp(clampStr)
+	if err != nil {
+		// This should have been caught by GenerateParser.
+		panic(err)
+	}
+	rv := reflect.ValueOf(val)
+	clamped := reflect.New(rv.Type()).Elem()
+	if isUintKind(rv.Kind()) {
+		// GenerateParser has checked that any bounds are non-negative; an unbounded hi may be less than
+		// the largest unsigned value.
+		n := rv.Uint()
+		switch {
+		case lo > 0 && n < uint64(lo):
+			clamped.SetUint(uint64(lo))
+		case hi != math.MaxInt64 && n > uint64(hi):
+			clamped.SetUint(uint64(hi))
+		default:
+			return val, nil
+		}
+		return clamped.Interface(), errors.Errorf("value %d is out of range [%s] (clamping to %d)", n, clampStr, clamped.Uint())
+	}
+	n := rv.Int()
+	switch {
+	case n < lo:
+		clamped.SetInt(lo)
+	case n > hi:
+		clamped.SetInt(hi)
+	default:
+		return val, nil
+	}
+	return clamped.Interface(), errors.Errorf("value %d is out of range [%s] (clamping to %d)", n, clampStr, clamped.Int())
+}
+
+// fileName returns the name of the env-var that holds the path of a file to read the value from, if the
+// tag has fromFile=true.
+func (tag envTag) fileName() (string, bool) {
+	if fromFile, _ := strconv.ParseBool(tag.Options["fromFile"]); !fromFile {
+		return "", false
+	}
+	return tag.Name + "_FILE", true
+}
+
+// expandVars returns whether ${VAR} references in the tag's values should be expanded; that is, unless
+// the "expand" option is false.
+func (tag envTag) expandVars() bool {
+	expandStr, ok := tag.Options["expand"]
+	if !ok {
+		return true
+	}
+	expandVars, _ := strconv.ParseBool(expandStr)
+	return expandVars
+}
+
+// readDefaultFile reads the file named by a "defaultFile" option from fsys, or from the OS filesystem if
+// fsys is nil.
+//
+//nolint:wrapcheck // The caller will wrap errors.
+func readDefaultFile(fsys fs.FS, name string) ([]byte, error) {
+	if fsys == nil {
+		return os.ReadFile(name)
+	}
+	return fs.ReadFile(fsys, name)
+}
+
+// isNullValue returns whether str is one of the "|"-separated values of the "nullValue" option, which
+// are treated as if the env-var were not set.
+func (tag envTag) isNullValue(str string) bool {
+	nullValues, ok := tag.Options["nullValue"]
+	if !ok {
+		return false
+	}
+	for _, nullValue := range strings.Split(nullValues, "|") {
+		if str == nullValue {
+			return true
+		}
+	}
+	return false
+}
+
+// altNames returns the names of env-vars other than tag.Name that can set the field; those from the
+// "fromFile" and "mergeNumbered" options.  For "mergeNumbered", only the first numbered name is included.
+func (tag envTag) altNames() []string {
+	var ret []string
+	if fileName, fromFile := tag.fileName(); fromFile {
+		ret = append(ret, fileName)
+	}
+	if mergeNumbered, _ := strconv.ParseBool(tag.Options["mergeNumbered"]); mergeNumbered {
+		ret = append(ret, tag.Name+"_1")
+	}
+	return ret
+}
+
+// checkSHA256 checks that the SHA-256 digest of value matches the hex digest in the env-var digestVar, for
+// the "sha256" tag option.
+func checkSHA256(lookup LookupFunc, digestVar, value string) error {
+	expectedStr, ok := lookup(digestVar)
+	if !ok {
+		return errors.Errorf("%s, which has the expected sha256 digest, is not set", digestVar)
+	}
+	expected, err := hex.DecodeString(strings.TrimSpace(expectedStr))
+	if err != nil || len(expected) != sha256.Size {
+		return errors.Errorf("%s=%q is not a hex-encoded sha256 digest", digestVar, expectedStr)
+	}
+	actual := sha256.Sum256([]byte(value))
+	if subtle.ConstantTimeCompare(actual[:], expected) != 1 {
+		return errors.Errorf("sha256 digest does not match %s", digestVar)
+	}
+	return nil
+}
+
+// readValueFile reads a value from the file at path.  Unless trimNewlineStr is false, exactly one trailing
+// newline ("\n" or "\r\n") is removed, as most editors add one.
+//
+//nolint:wrapcheck // The caller will wrap errors.
+func readValueFile(path, trimNewlineStr string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	str := string(content)
+	if trimNewline, _ := strconv.ParseBool(trimNewlineStr); trimNewline || trimNewlineStr == "" {
+		if strings.HasSuffix(str, "\r\n") {
+			str = strings.TrimSuffix(str, "\r\n")
+		} else {
+			str = strings.TrimSuffix(str, "\n")
+		}
+	}
+	return str, nil
+}
+
+type envKeysContextKey struct{}
+
+// WithEnvKeys returns a copy of ctx that lets the "mergeNumbered" tag option use keys to list all of the
+// env-vars (typically by splitting the entries of os.Environ() on "="), when ctx is passed to
+// ParseFromEnvContext.  Without it, "mergeNumbered" stops at the first gap in the numbering, with a
+// warning if the env-var just past the gap is set.
+func WithEnvKeys(ctx context.Context, keys func() []string) context.Context {
+	return context.WithValue(ctx, envKeysContextKey{}, keys)
+}
+
+// lookupNumbered returns the names and values of the env-vars name, name_1, name_2, ... that are set, in
+// numeric order.  Without WithEnvKeys it stops at the first gap, and returns a warning if the env-var just
+// past the gap is set, as that (and maybe others after it) is being ignored.
+func lookupNumbered(ctx context.Context, lookup LookupFunc, name string) (vars [][2]string, warn error) {
+	var ret [][2]string
+	if val, ok := lookup(name); ok {
+		ret = append(ret, [2]string{name, val})
+	}
+	keys, haveKeys := ctx.Value(envKeysContextKey{}).(func() []string)
+	if !haveKeys {
+		for n := 1; ; n++ {
+			key := name + "_" + strconv.Itoa(n)
+			val, ok := lookup(key)
+			if !ok {
+				next := name + "_" + strconv.Itoa(n+1)
+				if _, hidden := lookup(next); hidden {
+					warn = errors.Errorf("%s is set, but is ignored because %s is not set (use WithEnvKeys to allow gaps in the numbering)",
+						next, key)
+				}
+				return ret, warn
+			}
+			ret = append(ret, [2]string{key, val})
+		}
+	}
+	var nums []int
+	for _, key := range keys() {
+		suffix := strings.TrimPrefix(key, name+"_")
+		if suffix == key || suffix == "" || strings.Trim(suffix, "0123456789") != "" {
+			continue
+		}
+		if n, err := strconv.Atoi(suffix); err == nil && strconv.Itoa(n) == suffix {
+			nums = append(nums, n)
+		}
+	}
+	sort.Ints(nums)
+	for _, n := range nums {
+		key := name + "_" + strconv.Itoa(n)
+		if val, ok := lookup(key); ok {
+			ret = append(ret, [2]string{key, val})
+		}
+	}
+	return ret, nil
+}
+
+// parseNumbered parses each of the values returned by lookupNumbered, and concatenates the results.
+func parseNumbered(parse func(string) (interface{}, error), vars [][2]string) (interface{}, error) {
+	ret := []string{}
+	for _, kv := range vars {
+		val, err := parse(kv[1])
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s", kv[0])
+		}
+		if val != nil {
+			ret = append(ret, val.([]string)...)
+		}
+	}
+	return ret, nil
+}
+
+// ScreamingSnakeCase converts a Go CamelCase field name to SCREAMING_SNAKE_CASE; for example "MaxConns" to
+// "MAX_CONNS", and "HTTPPort" to "HTTP_PORT".  It is intended for use as GenerateOptions.NameFunc.
+func ScreamingSnakeCase(name string) string {
+	runes := []rune(name)
+	var ret strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+				ret.WriteByte('_')
+			}
+		}
+		ret.WriteRune(unicode.ToUpper(r))
+	}
+	return ret.String()
+}
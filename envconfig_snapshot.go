@@ -0,0 +1,181 @@
+package envconfig
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// A FieldSource says where the value of a field came from.
+type FieldSource string
+
+const (
+	// SourceUnset is for a field that has not been parsed, or that was not set and has no default (and
+	// so was set to its zero value).
+	SourceUnset FieldSource = "unset"
+	// SourceEnv is for a field whose value came from its env-var.
+	SourceEnv FieldSource = "env"
+	// SourceFile is for a field whose value came from the file named by its NAME_FILE env-var.
+	SourceFile FieldSource = "file"
+	// SourceDefault is for a field whose value came from the "default" tag option.
+	SourceDefault FieldSource = "default"
+	// SourceDefaultFrom is for a field whose value came from the "defaultFrom" tag option.
+	SourceDefaultFrom FieldSource = "defaultFrom"
+	// SourceDefaultFunc is for a field whose value came from the "defaultFunc" tag option.
+	SourceDefaultFunc FieldSource = "defaultFunc"
+	// SourceTemplate is for a field whose value came from the "template" tag option.
+	SourceTemplate FieldSource = "template"
+	// SourceDefaultMethod is for a field whose value was left as set by the struct's Default method (see
+	// Defaulter).
+	SourceDefaultMethod FieldSource = "Default()"
+)
+
+// redacted is the Value of a FieldSnapshot for a non-zero field with redact=true.
+const redacted = "<redacted>"
+
+// A FieldSnapshot describes the current value of a single field, as returned by StructParser.Snapshot.
+type FieldSnapshot struct {
+	// EnvName is the name of the field's env-var; it is empty for const fields.
+	EnvName string
+	// FieldName is the name of the Go field, with the names of any nested structs that it is in
+	// prepended, separated by ".".
+	FieldName string
+	// Type is the Go type of the field.
+	Type string
+	// Source is where the field's value came from, according to the Provenance passed to
+	// SnapshotWithProvenance.
+	Source FieldSource
+	// Value is the field's value, formatted with fmt.Sprint; or "<redacted>" if the field has the
+	// redact=true tag option and is not the zero value.
+	Value string
+}
+
+// Snapshot describes the current value of every field of structPtr, in the order of the fields in the
+// struct.  It does not re-parse anything, and so it doesn't know where the values came from: every Source
+// is SourceUnset, except for const fields.  Use SnapshotWithProvenance to include the sources.  The fields
+// of a nil pointer-to-struct are not included.  It panics if structPtr is of the wrong type for this
+// parser.
+func (p StructParser) Snapshot(structPtr interface{}) []FieldSnapshot {
+	return p.SnapshotWithProvenance(structPtr, nil)
+}
+
+// SnapshotWithProvenance is like Snapshot, but also says where each value came from.  prov is the
+// Provenance returned by the ParseWithProvenance call that populated structPtr; a field that isn't in it
+// (such as one whose parse failed) has SourceUnset.
+//
+//	prov, warn, fatal := parser.ParseWithProvenance(&cfg, os.LookupEnv)
+//	...
+//	snapshot := parser.SnapshotWithProvenance(&cfg, prov)
+func (p StructParser) SnapshotWithProvenance(structPtr interface{}, prov Provenance) []FieldSnapshot {
+	structPtrValue := reflect.ValueOf(structPtr)
+	if structPtrValue.Kind() != reflect.Ptr {
+		panic(errors.New("structPtr is not a pointer"))
+	}
+	structValue := structPtrValue.Elem()
+	if structValue.Type() != p.structType {
+		panic(errors.Errorf("wrong type (%s) for parser (%s)", structValue.Type(), p.structType))
+	}
+	return p.snapshot(structValue, "", prov)
+}
+
+func (p StructParser) snapshot(structValue reflect.Value, prefix string, prov Provenance) []FieldSnapshot {
+	var ret []FieldSnapshot
+	for _, field := range p.fields {
+		fieldValue := structValue.Field(field.index)
+		switch {
+		case field.nested != nil:
+			if field.nestedPtr {
+				if fieldValue.IsNil() {
+					continue
+				}
+				fieldValue = fieldValue.Elem()
+			}
+			ret = append(ret, field.nested.snapshot(fieldValue, prefix+field.name+".", prov)...)
+		default:
+			value := fmt.Sprint(fieldValue.Interface())
+			if redact, _ := strconv.ParseBool(field.tag.Options["redact"]); redact && !fieldValue.IsZero() {
+				value = redacted
+			}
+			ret = append(ret, FieldSnapshot{
+				EnvName:   field.tag.Name,
+				FieldName: prefix + field.name,
+				Type:      fieldValue.Type().String(),
+				Source:    p.fieldSource(field, prov),
+				Value:     value,
+			})
+		}
+	}
+	return ret
+}
+
+// Provenance records, for each env-var name, the raw string that a parse used for it and where that string
+// came from; as returned by StructParser.ParseWithProvenance.  Const fields are not included.  A field whose
+// value was not set from a string (a defaultFrom, or a defaultFunc that returns a typed value) has an empty
+// Raw.  Values are recorded verbatim, so beware of logging a Provenance for fields with redact=true.
+type Provenance map[string]ProvenanceEntry
+
+// A ProvenanceEntry is the raw string used for one env-var, and its source.
+type ProvenanceEntry struct {
+	Raw    string
+	Source FieldSource
+}
+
+// Diff returns the sorted names of the env-vars whose entries differ between prev and p, including those
+// that are in only one of them.
+func (p Provenance) Diff(prev Provenance) []string {
+	var changed []string
+	for name, entry := range p {
+		if prevEntry, ok := prev[name]; !ok || prevEntry != entry {
+			changed = append(changed, name)
+		}
+	}
+	for name := range prev {
+		if _, ok := p[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// fieldSource returns where the value of field came from, according to prov.  Const fields aren't in a
+// Provenance, but they always get their value from the same place.
+func (p StructParser) fieldSource(field structField, prov Provenance) FieldSource {
+	if field.tag.Name == "" {
+		for _, opt := range []struct {
+			name   string
+			source FieldSource
+		}{
+			{"default", SourceDefault},
+			{"defaultFrom", SourceDefaultFrom},
+			{"defaultFunc", SourceDefaultFunc},
+			{"template", SourceTemplate},
+		} {
+			if _, ok := field.tag.Options[opt.name]; ok {
+				return opt.source
+			}
+		}
+		if p.defaulter {
+			return SourceDefaultMethod
+		}
+		return SourceUnset
+	}
+	if entry, ok := prov[field.tag.Name]; ok {
+		return entry.Source
+	}
+	if mergeNumbered, _ := strconv.ParseBool(field.tag.Options["mergeNumbered"]); mergeNumbered {
+		// NAME itself may not be set, only NAME_1 and so on.
+		for name, entry := range prov {
+			if suffix := strings.TrimPrefix(name, field.tag.Name+"_"); suffix != name {
+				if _, err := strconv.Atoi(suffix); err == nil {
+					return entry.Source
+				}
+			}
+		}
+	}
+	return SourceUnset
+}
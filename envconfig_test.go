@@ -1,13 +1,34 @@
 package envconfig_test
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"net"
 	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
+	"regexp"
+	"runtime"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"testing/fstest"
+	"text/template"
 	"time"
 
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -21,6 +42,16 @@ func (e testEnv) lookup(key string) (string, bool) {
 	return v, ok
 }
 
+// assertFellBack asserts that the only problem from a parse was a single invalid value that fell back to
+// its default, and that the warning for it contains expectErr.
+func assertFellBack(t *testing.T, warn, fatal []error, expectErr string) {
+	t.Helper()
+	assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+	if assert.Equal(t, len(warn), 1, "There should be a warning from falling back to the default") {
+		assert.Contains(t, warn[0].Error(), expectErr)
+	}
+}
+
 // Note: DO NOT use t.Parallel(); because these tests all make use of
 // the global environment (os.Getenv/os.Setenv), they are not safe to
 // run in parallel.
@@ -66,6 +97,329 @@ func TestAbsoluteURL(t *testing.T) {
 	}
 }
 
+func TestAnyURI(t *testing.T) {
+	var config struct {
+		URI *url.URL `env:"CONFIG_URI,parser=any-URI"`
+		URL *url.URL `env:"CONFIG_URI,parser=absolute-URL,default=https://example.com/"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := []struct {
+		Input          string
+		ExpectError    bool
+		ExpectURLError bool
+	}{
+		{Input: "https://api.example.com/"},
+		{Input: "urn:ietf:rfc:2648", ExpectURLError: true},
+		{Input: "localhost:8080", ExpectURLError: true}, // an absolute URN with scheme "localhost"
+		{Input: "/home/user/repo.git", ExpectError: true, ExpectURLError: true},
+	}
+	for i, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			config.URI, config.URL = nil, nil
+			env := testEnv{"CONFIG_URI": tc.Input}
+
+			warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+
+			if tc.ExpectError {
+				assert.Equal(t, len(fatal), 1, "There should be 1 fatal error")
+				assert.Nil(t, config.URI, "config.URI should be nil because there should be an error")
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				if assert.NotNil(t, config.URI, "config.URI should not be nil") {
+					assert.Equal(t, tc.Input, config.URI.String(), "config.URI should stringify to the input")
+				}
+			}
+			if tc.ExpectURLError {
+				assert.Equal(t, len(warn), 1, "There should be a warning from absolute-URL")
+				assert.Equal(t, "https://example.com/", config.URL.String(), "absolute-URL should fall back to the default")
+			} else {
+				assert.Equal(t, len(warn), 0, "There should be no warnings")
+			}
+		})
+	}
+}
+
+// TestInvalidFallsBack checks that, for each parser, an invalid value falls back to the default with a
+// warning that says what was wrong with it; the tests for the individual parsers check the fatal error
+// for a field without a default.
+func TestInvalidFallsBack(t *testing.T) {
+	testcases := map[string]struct {
+		Object     interface{}
+		EnvVar     string
+		ExpectWarn string
+		Expected   string
+	}{
+		"relative-URL": {
+			Object: &struct {
+				Value *url.URL `env:"VALUE,parser=relative-URL,default=/default"`
+			}{},
+			EnvVar:     "http://example.com/",
+			ExpectWarn: `not a relative URL`,
+			Expected:   `&{/default}`,
+		},
+		"comma-split-kv-duration": {
+			Object: &struct {
+				Value map[string]time.Duration `env:"VALUE,parser=comma-split-kv,default=default=5s"`
+			}{},
+			EnvVar:     "slow=bogus",
+			ExpectWarn: `key "slow"`,
+			Expected:   `&{map[default:5s]}`,
+		},
+		"comma-split-kv-string": {
+			Object: &struct {
+				Value map[string]string `env:"VALUE,parser=comma-split-kv,default=team=none"`
+			}{},
+			EnvVar:     "team",
+			ExpectWarn: `not a key=value pair`,
+			Expected:   `&{map[team:none]}`,
+		},
+		"comma-split-kv-bool": {
+			Object: &struct {
+				Value map[string]bool `env:"VALUE,parser=comma-split-kv,default=fallback"`
+			}{},
+			EnvVar:     "a=maybe",
+			ExpectWarn: `key "a"`,
+			Expected:   `&{map[fallback:true]}`,
+		},
+		"json-number": {
+			Object: &struct {
+				Value json.Number `env:"VALUE,parser=json-number,default=0"`
+			}{},
+			EnvVar:     "1e",
+			ExpectWarn: `invalid JSON number`,
+			Expected:   `&{0}`,
+		},
+		"time.RFC3339": {
+			Object: &struct {
+				Value time.Time `env:"VALUE,parser=time.RFC3339,default=1970-01-01T00:00:00Z"`
+			}{},
+			EnvVar:     "2024-01-02 03:04:05",
+			ExpectWarn: `cannot parse`,
+			Expected:   `&{1970-01-01 00:00:00 +0000 UTC}`,
+		},
+		"currency-amount": {
+			Object: &struct {
+				Value envconfig.Money `env:"VALUE,parser=currency-amount,default=USD 0"`
+			}{},
+			EnvVar:     "12.50",
+			ExpectWarn: `not a "CURRENCY AMOUNT" string: "12.50"`,
+			Expected:   `&{USD 0.00}`,
+		},
+		"comma-split-weighted": {
+			Object: &struct {
+				Value []envconfig.WeightedEntry `env:"VALUE,parser=comma-split-weighted,default=fallback"`
+			}{},
+			EnvVar:     "a:x",
+			ExpectWarn: `entry "a:x": invalid weight "x"`,
+			Expected:   `&{[{fallback 1}]}`,
+		},
+		"comma-split-ip-kv": {
+			Object: &struct {
+				Value map[string]string `env:"VALUE,parser=comma-split-ip-kv,default=127.0.0.1=localhost"`
+			}{},
+			EnvVar:     "not-an-ip=x",
+			ExpectWarn: `invalid IP address "not-an-ip"`,
+			Expected:   `&{map[127.0.0.1:localhost]}`,
+		},
+		"dotenv": {
+			Object: &struct {
+				Value map[string]string `env:"VALUE,parser=dotenv,default=FALLBACK=1"`
+			}{},
+			EnvVar:     "NOT VALID",
+			ExpectWarn: `line 1: not a KEY=VALUE pair: "NOT VALID"`,
+			Expected:   `&{map[FALLBACK:1]}`,
+		},
+		"comma-split-int": {
+			Object: &struct {
+				Value []int `env:"VALUE,parser=comma-split-int,default=1,2,4"`
+			}{},
+			EnvVar:     "1,x,4",
+			ExpectWarn: `item 1 ("x")`,
+			Expected:   `&{[1 2 4]}`,
+		},
+		"strconv.ParseInt": {
+			Object: &struct {
+				Value int8 `env:"VALUE,parser=strconv.ParseInt,default=1"`
+			}{},
+			EnvVar:     "200",
+			ExpectWarn: `out of range for an 8-bit integer`,
+			Expected:   `&{1}`,
+		},
+		"strconv.ParseUint": {
+			Object: &struct {
+				Value uint `env:"VALUE,parser=strconv.ParseUint,default=0"`
+			}{},
+			EnvVar:     "-5",
+			ExpectWarn: `is negative`,
+			Expected:   `&{0}`,
+		},
+		"integer-seconds": {
+			Object: &struct {
+				Value time.Duration `env:"VALUE,parser=integer-seconds,default=0"`
+			}{},
+			EnvVar:     "1.5",
+			ExpectWarn: `parsing "1.5": invalid syntax`,
+			Expected:   `&{0s}`,
+		},
+		"duration-range": {
+			Object: &struct {
+				Value envconfig.DurationRange `env:"VALUE,parser=duration-range,default=1s..1s"`
+			}{},
+			EnvVar:     "2s..1s",
+			ExpectWarn: `minimum 2s is greater than maximum 1s`,
+			Expected:   `&{{1s 1s}}`,
+		},
+		"comma-split-ports": {
+			Object: &struct {
+				Value []uint16 `env:"VALUE,parser=comma-split-ports,default=80"`
+			}{},
+			EnvVar:     "80,70000",
+			ExpectWarn: `invalid port "70000"`,
+			Expected:   `&{[80]}`,
+		},
+		"mask-or-prefix-length": {
+			Object: &struct {
+				Value net.IPMask `env:"VALUE,parser=mask-or-prefix-length,default=/32"`
+			}{},
+			EnvVar:     "/33",
+			ExpectWarn: `invalid prefix length "/33"`,
+			Expected:   `&{ffffffff}`,
+		},
+		"comma-split-CIDR": {
+			Object: &struct {
+				Value []*net.IPNet `env:"VALUE,parser=comma-split-CIDR,default=127.0.0.0/8"`
+			}{},
+			EnvVar:     "10.0.0.0/33",
+			ExpectWarn: `invalid CIDR address: 10.0.0.0/33`,
+			Expected:   `&{[127.0.0.0/8]}`,
+		},
+		"shell-split": {
+			Object: &struct {
+				Value []string `env:"VALUE,parser=shell-split,default=fallback"`
+			}{},
+			EnvVar:     "'unterminated",
+			ExpectWarn: `unterminated single quote`,
+			Expected:   `&{[fallback]}`,
+		},
+		"valid-utf8": {
+			Object: &struct {
+				Value string `env:"VALUE,parser=valid-utf8,default=anon"`
+			}{},
+			EnvVar:     "caf\xe9",
+			ExpectWarn: `invalid UTF-8`,
+			Expected:   `&{anon}`,
+		},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			parser, err := envconfig.GenerateParser(reflect.TypeOf(tc.Object).Elem(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			warn, fatal := parser.ParseFromEnv(tc.Object, testEnv{"VALUE": tc.EnvVar}.lookup)
+			assertFellBack(t, warn, fatal, tc.ExpectWarn)
+			assert.Equal(t, tc.Expected, fmt.Sprintf("%v", tc.Object))
+		})
+	}
+}
+
+func TestRelativeURL(t *testing.T) {
+	var config struct {
+		U *url.URL `env:"CONFIG_URL,parser=relative-URL"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := []struct {
+		Input       string
+		ExpectError string
+	}{
+		{Input: "/path"},
+		{Input: "/path?x=1#frag"},
+		{Input: "path/to/thing"},
+		{Input: "https://x/", ExpectError: "not a relative URL"},
+		{Input: "//x/path", ExpectError: "not a relative URL"},
+		{Input: "", ExpectError: "is not set"},
+	}
+	for i, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			config.U = nil
+			env := testEnv{"CONFIG_URL": tc.Input}
+
+			warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError != "" {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.Contains(t, fatal[0].Error(), tc.ExpectError)
+				}
+				assert.Nil(t, config.U, "config.U should be nil because there should be an error")
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				if assert.NotNil(t, config.U, "config.U should not be nil") {
+					assert.Equal(t, tc.Input, config.U.String(), "config.U should stringify to the input")
+				}
+			}
+		})
+	}
+}
+
+func TestBaseURL(t *testing.T) {
+	var config struct {
+		U *url.URL `env:"CONFIG_URL,parser=base-URL"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		Expected    string
+		ExpectError string
+	}{
+		"with-slash":    {Input: "https://x/api/", Expected: "https://x/api/"},
+		"without-slash": {Input: "https://x/api", Expected: "https://x/api/"},
+		"empty-path":    {Input: "https://x", Expected: "https://x/"},
+		"query":         {Input: "https://x/api?k=v", Expected: "https://x/api/?k=v"},
+		"escaped":       {Input: "https://x/a%2Fb", Expected: "https://x/a%2Fb/"},
+		"relative":      {Input: "/api", ExpectError: "not an absolute URL"},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.U = nil
+			env := testEnv{"CONFIG_URL": tc.Input}
+
+			warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError != "" {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.Contains(t, fatal[0].Error(), tc.ExpectError)
+				}
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				if assert.NotNil(t, config.U, "config.U should not be nil") {
+					assert.Equal(t, tc.Expected, config.U.String())
+					ref, _ := url.Parse("v1")
+					assert.Equal(t, strings.TrimSuffix(tc.Expected, "?k=v")+"v1", config.U.ResolveReference(ref).String(),
+						"resolving a relative reference should append to the path")
+				}
+			}
+		})
+	}
+}
+
 func TestIgnoredField(t *testing.T) {
 	var config struct {
 		Value   string `env:"VALUE,parser=nonempty-string"`
@@ -80,6 +434,58 @@ func TestIgnoredField(t *testing.T) {
 	assert.Equal(t, len(warn), 0, "There should be no warnings")
 	assert.Equal(t, len(fatal), 0, "There should be no errors")
 	assert.Equal(t, config.Value, "value")
+
+	t.Run("untagged-url", func(t *testing.T) {
+		// *url.URL is a pointer to a struct, but it has a type handler, so it is a value to be parsed
+		// (which needs a tag), not a struct to recurse in to.
+		var config struct {
+			Value string `env:"VALUE,parser=nonempty-string"`
+			U     *url.URL
+		}
+		parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no errors")
+		assert.Equal(t, "value", config.Value)
+		assert.Nil(t, config.U)
+	})
+
+	t.Run("untagged-time", func(t *testing.T) {
+		var config struct {
+			Value string `env:"VALUE,parser=nonempty-string"`
+			When  time.Time
+		}
+		parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no errors")
+		assert.Equal(t, "value", config.Value)
+		assert.True(t, config.When.IsZero())
+	})
+
+	t.Run("untagged-sql-null", func(t *testing.T) {
+		var config struct {
+			Value string `env:"VALUE,parser=nonempty-string"`
+			Name  sql.NullString
+			Count sql.NullInt64
+		}
+		parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no errors")
+		assert.Equal(t, "value", config.Value)
+		assert.Equal(t, sql.NullString{}, config.Name)
+		assert.Equal(t, sql.NullInt64{}, config.Count)
+	})
 }
 
 func TestExpandedEnv(t *testing.T) {
@@ -98,7 +504,98 @@ func TestExpandedEnv(t *testing.T) {
 	assert.Equal(t, len(warn), 0, "There should be no warnings")
 	assert.Equal(t, len(fatal), 0, "There should be no errors")
 	require.NotNil(t, config.Value)
-	assert.Equal(t, config.Value, "http://${VALUE}/path")
+	assert.Equal(t, config.Value, "http://example.com/path")
+
+	testcases := map[string]struct {
+		Env          testEnv
+		Expected     string
+		ExpectedWarn string
+	}{
+		"multiple": {
+			Env:      testEnv{"SCHEME": "https", "HOST": "example.com", "PORT": "8443", "EXPANDED_VALUE": "${SCHEME}://$HOST:${PORT}/"},
+			Expected: "https://example.com:8443/",
+		},
+		"undefined": {
+			Env:          testEnv{"EXPANDED_VALUE": "http://${UNDEFINED}/path/$UNDEFINED"},
+			Expected:     "http:///path/",
+			ExpectedWarn: `EXPANDED_VALUE refers to env-var "UNDEFINED", which is not set (expanding it to "")`,
+		},
+		"escaped": {
+			Env:      testEnv{"EXPANDED_VALUE": "price: $$5, path: $${HOME}"},
+			Expected: "price: $5, path: ${HOME}",
+		},
+		"single-pass": {
+			Env:      testEnv{"A": "${B}", "B": "b", "EXPANDED_VALUE": "[${A}]"},
+			Expected: "[${B}]",
+		},
+		"self-reference": {
+			Env:      testEnv{"EXPANDED_VALUE": "x${EXPANDED_VALUE}"},
+			Expected: "xx${EXPANDED_VALUE}",
+		},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Value = ""
+			warn, fatal := parser.ParseFromEnv(&config, tc.Env.lookup)
+			if tc.ExpectedWarn != "" {
+				if assert.Equal(t, len(warn), 1, "There should be 1 warning") {
+					assert.EqualError(t, warn[0], tc.ExpectedWarn)
+				}
+			} else {
+				assert.Equal(t, len(warn), 0, "There should be no warnings")
+			}
+			assert.Equal(t, len(fatal), 0, "There should be no errors")
+			assert.Equal(t, tc.Expected, config.Value)
+		})
+	}
+
+	t.Run("indirect", func(t *testing.T) {
+		var config struct {
+			Value string `env:"VALUE_FROM,parser=nonempty-string,indirect=true"`
+		}
+		parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		env := testEnv{"VALUE_FROM": "TARGET", "TARGET": "${USER}@${HOST}", "USER": "admin", "HOST": "db"}
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no errors")
+		assert.Equal(t, "admin@db", config.Value)
+
+		delete(env, "HOST")
+		warn, fatal = parser.ParseFromEnv(&config, env.lookup)
+		if assert.Equal(t, len(warn), 1, "There should be 1 warning") {
+			assert.EqualError(t, warn[0], `TARGET refers to env-var "HOST", which is not set (expanding it to "")`)
+		}
+		assert.Equal(t, len(fatal), 0, "There should be no errors")
+		assert.Equal(t, "admin@", config.Value)
+	})
+
+	t.Run("expand=false", func(t *testing.T) {
+		var config struct {
+			Value    string `env:"VALUE    ,parser=nonempty-string ,expand=false                  "`
+			Password string `env:"PASSWORD ,parser=nonempty-string ,expand=false ,default=pa$$w0rd "`
+		}
+		parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		env := testEnv{"VALUE": "${HOST} costs $$5", "HOST": "example.com"}
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no errors")
+		assert.Equal(t, "${HOST} costs $$5", config.Value)
+		assert.Equal(t, "pa$$w0rd", config.Password)
+
+		_, err = envconfig.GenerateParser(reflect.StructOf([]reflect.StructField{{
+			Name: "Value",
+			Type: reflect.TypeOf(""),
+			Tag:  `env:"VALUE,parser=nonempty-string,expand=maybe"`,
+		}}), nil)
+		assert.Error(t, err, "expand=maybe should be rejected")
+	})
 }
 
 func TestExpandedDefault(t *testing.T) {
@@ -115,154 +612,5350 @@ func TestExpandedDefault(t *testing.T) {
 	assert.Equal(t, len(fatal), 0, "There should be no errors")
 	require.NotNil(t, config.Value)
 	assert.Equal(t, config.Value.String(), "http://example.com/path")
+
+	warn, fatal = parser.ParseFromEnv(&config, testEnv{}.lookup)
+	if assert.Equal(t, len(warn), 1, "There should be 1 warning") {
+		assert.EqualError(t, warn[0], `the default for EXPANDED_VALUE refers to env-var "VALUE", which is not set (expanding it to "")`)
+	}
+	assert.Equal(t, len(fatal), 0, "There should be no errors")
 }
 
-func TestRecursive(t *testing.T) {
-	var config struct {
-		ParentThing string `env:"PARENT_THING,parser=nonempty-string"`
-		Child       struct {
-			Thing1 string `env:"CHILD_THING1,parser=nonempty-string"`
-			Thing2 string `env:"CHILD_THING2,parser=nonempty-string"`
-		}
+func TestTagName(t *testing.T) {
+	type Sub struct {
+		Level string `config:"LOG_LEVEL ,parser=nonempty-string ,default=info"`
 	}
-	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	type Config struct {
+		// The "env" tags belong to some other library, and must be ignored.
+		Host    string `config:"HOST ,parser=nonempty-string" env:"OTHER_HOST"`
+		Port    int    `config:"PORT ,parser=strconv.ParseInt ,default=80"`
+		Backup  int    `config:"BACKUP_PORT ,parser=strconv.ParseInt ,defaultFrom=Port"`
+		Ignored string `env:"IGNORED,parser=nonempty-string"`
+		Log     Sub
+		Trace   *Sub
+	}
+	parser, err := envconfig.GenerateParserWithOptions(reflect.TypeOf(Config{}), envconfig.GenerateOptions{
+		TagName: "config",
+	})
 	if err != nil {
 		t.Fatal(err)
 	}
-	env := testEnv{
-		"PARENT_THING": "foo",
-		"CHILD_THING1": "bar",
-		"CHILD_THING2": "baz",
+
+	var cfg Config
+	env := testEnv{"HOST": "example.com", "OTHER_HOST": "wrong.example.com", "IGNORED": "x", "LOG_LEVEL": "debug"}
+	warn, fatal := parser.ParseFromEnv(&cfg, env.lookup)
+	assert.Equal(t, len(warn), 0, "There should be no warnings")
+	assert.Equal(t, len(fatal), 0, "There should be no errors")
+	assert.Equal(t, Config{
+		Host:   "example.com",
+		Port:   80,
+		Backup: 80,
+		Log:    Sub{Level: "debug"},
+		Trace:  &Sub{Level: "debug"},
+	}, cfg)
+
+	t.Run("default-tag-name", func(t *testing.T) {
+		// GenerateParser still reads "env", so it sees Host as OTHER_HOST, with no parser.
+		_, err := envconfig.GenerateParser(reflect.TypeOf(Config{}), nil)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), `struct field "Host": type string requires a "parser" setting`)
+		}
+
+		type EnvConfig struct {
+			Ignored string `env:"IGNORED,parser=nonempty-string" config:"HOST,parser=nonempty-string"`
+		}
+		var envCfg EnvConfig
+		for _, opts := range []envconfig.GenerateOptions{{}, {TagName: "env"}} {
+			parser, err := envconfig.GenerateParserWithOptions(reflect.TypeOf(EnvConfig{}), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			warn, fatal := parser.ParseFromEnv(&envCfg, env.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			assert.Equal(t, len(fatal), 0, "There should be no errors")
+			assert.Equal(t, EnvConfig{Ignored: "x"}, envCfg)
+		}
+	})
+}
+
+func TestNameFunc(t *testing.T) {
+	type config struct {
+		MaxConns int           `env:",parser=strconv.ParseInt"`
+		HTTPPort int           `env:",parser=strconv.ParseInt ,default=80"`
+		Explicit string        `env:"OTHER_NAME,parser=nonempty-string"`
+		Const    time.Duration `env:",const=true,parser=time.ParseDuration,default=1s"`
 	}
-	warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+	parser, err := envconfig.GenerateParserWithOptions(reflect.TypeOf(config{}), envconfig.GenerateOptions{
+		NameFunc: envconfig.ScreamingSnakeCase,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg config
+	env := testEnv{"MAX_CONNS": "10", "HTTP_PORT": "8080", "OTHER_NAME": "x", "EXPLICIT": "y", "CONST": "2s"}
+	warn, fatal := parser.ParseFromEnv(&cfg, env.lookup)
 	assert.Equal(t, len(warn), 0, "There should be no warnings")
 	assert.Equal(t, len(fatal), 0, "There should be no errors")
-	assert.Equal(t, config.ParentThing, "foo")
-	assert.Equal(t, config.Child.Thing1, "bar")
-	assert.Equal(t, config.Child.Thing2, "baz")
+	assert.Equal(t, config{MaxConns: 10, HTTPPort: 8080, Explicit: "x", Const: time.Second}, cfg)
+
+	t.Run("without-NameFunc", func(t *testing.T) {
+		_, err := envconfig.GenerateParser(reflect.TypeOf(config{}), nil)
+		assert.EqualError(t, err, `struct field "MaxConns": does not have an environment variable name (and const=false)`)
+	})
+	t.Run("ScreamingSnakeCase", func(t *testing.T) {
+		for in, out := range map[string]string{
+			"MaxConns":   "MAX_CONNS",
+			"HTTPPort":   "HTTP_PORT",
+			"URL":        "URL",
+			"APIKey2":    "API_KEY2",
+			"Port8080":   "PORT8080",
+			"lowerStart": "LOWER_START",
+		} {
+			assert.Equal(t, out, envconfig.ScreamingSnakeCase(in), in)
+		}
+	})
 }
 
-func TestSmokeTestAllParsers(t *testing.T) {
-	type testcase struct {
-		Object   interface{}
-		EnvVar   string
-		Format   string
-		Expected string
-		Errors   int
-		Warnings int
+func TestChoices(t *testing.T) {
+	type sub struct {
+		Region string `env:"REGION,parser=nonempty-string,choicesKey=region"`
 	}
-	// This isn't going in to any depth on any of the types; just
-	// checking that the parser and setter don't panic.
-	tests := map[string]map[string]testcase{
-		"string": {
-			"nonempty-string": {
-				Object: &struct {
-					Value string `env:"VALUE,parser=nonempty-string"`
-				}{},
-				EnvVar:   "str",
-				Expected: `&{str}`,
-			},
-			"nonempty-string-unset": {
-				// Error, required value with unset environment variable,
-				Object: &struct {
-					Value string `env:"UNSET_VALUE,parser=nonempty-string"`
-				}{},
-				Errors:   1,
-				Expected: `&{}`,
-			},
-			"nonempty-string-default-set": {
-				// Parser errors on empty string and falls back to default
-				Object: &struct {
-					Value string `env:"VALUE,parser=nonempty-string,default=str"`
-				}{},
-				EnvVar:   "",
-				Expected: `&{str}`,
-				Warnings: 1,
-			},
-			"nonempty-string-default-unset": {
-				// UNSET_VALUE is not present so parser called with default
-				Object: &struct {
-					Value string `env:"UNSET_VALUE,parser=nonempty-string,default=str"`
-				}{},
-				Expected: `&{str}`,
-			},
-			"possibly-empty-string": {
-				Object: &struct {
-					Value string `env:"VALUE,parser=possibly-empty-string"`
-				}{},
-				EnvVar:   "",
-				Expected: `&{}`,
-			},
-			"possibly-empty-string-unset": {
-				Object: &struct {
-					Value string `env:"UNSET_VALUE,parser=possibly-empty-string"`
-				}{},
-				Expected: `&{}`,
-				Errors:   1,
+	type config struct {
+		Env string `env:"ENV ,parser=nonempty-string ,choicesKey=env ,default=production "`
+		Sub sub
+	}
+	opts := envconfig.GenerateOptions{
+		Choices: map[string]map[string]string{
+			"env": {
+				"dev":         "dev",
+				"development": "dev",
+				"prod":        "prod",
+				"production":  "prod",
 			},
-			"possibly-empty-string-default-set": {
-				Object: &struct {
-					Value string `env:"VALUE,parser=possibly-empty-string,default=str"`
-				}{},
-				EnvVar:   "",
-				Expected: `&{}`,
+			"region": {
+				"us-east-1": "us-east-1",
+				"use1":      "us-east-1",
 			},
-			"possibly-empty-string-default-unset": {
-				Object: &struct {
-					// Use UNSET_VALUE to reference a non-existent env variable.
-					Value string `env:"UNSET_VALUE,parser=possibly-empty-string,default=str"`
-				}{},
-				Expected: `&{str}`,
+		},
+	}
+	parser, err := envconfig.GenerateParserWithOptions(reflect.TypeOf(config{}), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Env           testEnv
+		Expected      config
+		ExpectedWarn  string
+		ExpectedFatal string
+	}{
+		"canonical": {
+			Env:      testEnv{"ENV": "dev", "REGION": "us-east-1"},
+			Expected: config{Env: "dev", Sub: sub{Region: "us-east-1"}},
+		},
+		"alias": {
+			Env:      testEnv{"ENV": "development", "REGION": "use1"},
+			Expected: config{Env: "dev", Sub: sub{Region: "us-east-1"}},
+		},
+		"default-is-canonicalized": {
+			Env:      testEnv{"REGION": "use1"},
+			Expected: config{Env: "prod", Sub: sub{Region: "us-east-1"}},
+		},
+		"unknown-with-default": {
+			Env:          testEnv{"ENV": "staging", "REGION": "use1"},
+			Expected:     config{Env: "prod", Sub: sub{Region: "us-east-1"}},
+			ExpectedWarn: `"staging" is not one of ["dev" "development" "prod" "production"]`,
+		},
+		"unknown": {
+			Env:           testEnv{"ENV": "dev", "REGION": "mars-1"},
+			Expected:      config{Env: "dev"},
+			ExpectedFatal: `"mars-1" is not one of ["us-east-1" "use1"]`,
+		},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			var cfg config
+			warn, fatal := parser.ParseFromEnv(&cfg, tc.Env.lookup)
+			if tc.ExpectedWarn != "" {
+				if assert.Equal(t, len(warn), 1, "There should be 1 warning") {
+					assert.Contains(t, warn[0].Error(), tc.ExpectedWarn)
+				}
+			} else {
+				assert.Equal(t, len(warn), 0, "There should be no warnings")
+			}
+			if tc.ExpectedFatal != "" {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.Contains(t, fatal[0].Error(), tc.ExpectedFatal)
+				}
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+			}
+			assert.Equal(t, tc.Expected, cfg)
+		})
+	}
+
+	t.Run("invalid-option", func(t *testing.T) {
+		for _, field := range []reflect.StructField{
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=nonempty-string,choicesKey=nonexistent"`},
+			{Type: reflect.TypeOf(0), Tag: `env:"VALUE,parser=strconv.ParseInt,choicesKey=env"`},
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=nonempty-string,choicesKey=env,default=staging"`},
+		} {
+			field.Name = "Value"
+			_, err := envconfig.GenerateParserWithOptions(reflect.StructOf([]reflect.StructField{field}), opts)
+			assert.Errorf(t, err, "tag %s should be rejected", field.Tag)
+		}
+		_, err := envconfig.GenerateParser(reflect.TypeOf(config{}), nil)
+		assert.Error(t, err, "choicesKey without GenerateOptions.Choices should be rejected")
+	})
+}
+
+func TestNormalize(t *testing.T) {
+	var config struct {
+		Name string `env:"NAME ,parser=nonempty-string ,normalize=squash                       ,default=anonymous "`
+		Env  string `env:"ENV  ,parser=nonempty-string ,normalize=lower    ,choicesKey=env  ,default=prod      "`
+	}
+	opts := envconfig.GenerateOptions{
+		Normalizers: map[string]func(string) string{
+			"squash": func(str string) string { return strings.Join(strings.Fields(str), " ") },
+			"lower":  strings.ToLower,
+		},
+		Choices: map[string]map[string]string{
+			"env": {"dev": "dev", "prod": "prod"},
+		},
+	}
+	parser, err := envconfig.GenerateParserWithOptions(reflect.TypeOf(config), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Env          testEnv
+		ExpectedName string
+		ExpectedEnv  string
+		ExpectedWarn string
+	}{
+		"normalized": {
+			Env:          testEnv{"NAME": " Jane \t Doe ", "ENV": "dev"},
+			ExpectedName: "Jane Doe",
+			ExpectedEnv:  "dev",
+		},
+		"before-choices": {
+			Env:          testEnv{"NAME": "x", "ENV": "DEV"},
+			ExpectedName: "x",
+			ExpectedEnv:  "dev",
+		},
+		"invalid-utf8": {
+			Env:          testEnv{"NAME": "caf\xe9", "ENV": "dev"},
+			ExpectedName: "anonymous",
+			ExpectedEnv:  "dev",
+			ExpectedWarn: `invalid Name (falling back to default "anonymous"): invalid UTF-8: "caf\xe9"`,
+		},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Name, config.Env = "", ""
+			warn, fatal := parser.ParseFromEnv(&config, tc.Env.lookup)
+			if tc.ExpectedWarn != "" {
+				if assert.Equal(t, len(warn), 1, "There should be 1 warning") {
+					assert.EqualError(t, warn[0], tc.ExpectedWarn)
+				}
+			} else {
+				assert.Equal(t, len(warn), 0, "There should be no warnings")
+			}
+			assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+			assert.Equal(t, tc.ExpectedName, config.Name)
+			assert.Equal(t, tc.ExpectedEnv, config.Env)
+		})
+	}
+
+	t.Run("invalid-option", func(t *testing.T) {
+		for _, field := range []reflect.StructField{
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=nonempty-string,normalize=nonexistent"`},
+			{Type: reflect.TypeOf(0), Tag: `env:"VALUE,parser=strconv.ParseInt,normalize=lower"`},
+		} {
+			field.Name = "Value"
+			_, err := envconfig.GenerateParserWithOptions(reflect.StructOf([]reflect.StructField{field}), opts)
+			assert.Errorf(t, err, "tag %s should be rejected", field.Tag)
+		}
+		_, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+		assert.Error(t, err, "normalize without GenerateOptions.Normalizers should be rejected")
+	})
+}
+
+func TestOnSet(t *testing.T) {
+	type sub struct {
+		Level string `env:"SUB_LEVEL,parser=nonempty-string,default=info"`
+	}
+	type config struct {
+		Host  string `env:"HOST,parser=nonempty-string"`
+		Port  int    `env:"PORT,parser=strconv.ParseInt,default=80"`
+		Sub   sub
+		Ptr   *sub
+		Other string
+	}
+	type setCall struct {
+		FieldName string
+		Value     interface{}
+	}
+	var calls []setCall
+	parser, err := envconfig.GenerateParserWithOptions(reflect.TypeOf(config{}), envconfig.GenerateOptions{
+		OnSet: func(fieldName string, value interface{}) {
+			calls = append(calls, setCall{FieldName: fieldName, Value: value})
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg config
+	warn, fatal := parser.ParseFromEnv(&cfg, testEnv{"HOST": "example.com", "PORT": "bogus", "SUB_LEVEL": "debug"}.lookup)
+	assert.Equal(t, len(warn), 1, "There should be 1 warning")
+	assert.Equal(t, len(fatal), 0, "There should be no errors")
+	assert.Equal(t, []setCall{
+		{FieldName: "Host", Value: "example.com"},
+		{FieldName: "Port", Value: 80},
+		{FieldName: "Sub.Level", Value: "debug"},
+		{FieldName: "Ptr.Level", Value: "debug"},
+	}, calls)
+
+	t.Run("failed", func(t *testing.T) {
+		calls = nil
+		var cfg config
+		_, fatal := parser.ParseFromEnv(&cfg, testEnv{}.lookup)
+		assert.Equal(t, len(fatal), 1, "There should be 1 error")
+		assert.Equal(t, []setCall{
+			{FieldName: "Port", Value: 80},
+			{FieldName: "Sub.Level", Value: "info"},
+		}, calls)
+	})
+}
+
+func TestParseFromEnvCapturingRest(t *testing.T) {
+	type config struct {
+		Host  string   `env:"APP_HOST  ,parser=nonempty-string                    "`
+		Port  int      `env:"APP_PORT  ,parser=strconv.ParseInt  ,default=80      "`
+		Token string   `env:"APP_TOKEN ,parser=nonempty-string   ,fromFile=true   "`
+		Args  []string `env:"APP_ARGS  ,parser=shell-split       ,mergeNumbered=true ,default= "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config{}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := testEnv{
+		"APP_HOST":        "example.com",
+		"APP_TOKEN":       "secret",
+		"APP_ARGS_1":      "-v",
+		"APP_ARGS_3":      "--debug",
+		"APP_POOL_SIZE":   "10",
+		"APP_RETRY_LIMIT": "3",
+		"HOME":            "/root",
+	}
+	allKeys := func() []string {
+		keys := make([]string, 0, len(env))
+		for key := range env {
+			keys = append(keys, key)
+		}
+		return keys
+	}
+	var cfg config
+	rest, warn, fatal := parser.ParseFromEnvCapturingRest(&cfg, env.lookup, allKeys)
+	assert.Equal(t, len(warn), 0, "There should be no warnings")
+	assert.Equal(t, len(fatal), 0, "There should be no errors")
+	assert.Equal(t, config{Host: "example.com", Port: 80, Token: "secret", Args: []string{"-v", "--debug"}}, cfg)
+	assert.Equal(t, map[string]string{"APP_POOL_SIZE": "10", "APP_RETRY_LIMIT": "3"}, rest)
+
+	t.Run("no-prefix", func(t *testing.T) {
+		var cfg struct {
+			Host string `env:"HOST ,parser=nonempty-string "`
+			Port string `env:"PORT ,parser=nonempty-string "`
+		}
+		parser, err := envconfig.GenerateParser(reflect.TypeOf(cfg), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		env := testEnv{"HOST": "example.com", "PORT": "80", "HOME": "/root"}
+		rest, _, fatal := parser.ParseFromEnvCapturingRest(&cfg, env.lookup, func() []string { return []string{"HOST", "PORT", "HOME"} })
+		assert.Equal(t, len(fatal), 0, "There should be no errors")
+		assert.Equal(t, map[string]string{}, rest)
+	})
+
+	t.Run("sha256", func(t *testing.T) {
+		var cfg struct {
+			Host  string `env:"APP_HOST  ,parser=nonempty-string                        "`
+			Token string `env:"APP_TOKEN ,parser=nonempty-string ,sha256=APP_TOKEN_SHA256 "`
+		}
+		parser, err := envconfig.GenerateParser(reflect.TypeOf(cfg), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256([]byte("secret"))
+		env := testEnv{
+			"APP_HOST":         "example.com",
+			"APP_TOKEN":        "secret",
+			"APP_TOKEN_SHA256": hex.EncodeToString(sum[:]),
+			"APP_POOL_SIZE":    "10",
+		}
+		rest, _, fatal := parser.ParseFromEnvCapturingRest(&cfg, env.lookup, func() []string {
+			return []string{"APP_HOST", "APP_TOKEN", "APP_TOKEN_SHA256", "APP_POOL_SIZE"}
+		})
+		assert.Equal(t, len(fatal), 0, "There should be no errors")
+		assert.Equal(t, map[string]string{"APP_POOL_SIZE": "10"}, rest, "The digest env-var should count as consumed")
+	})
+}
+
+func TestChainLookup(t *testing.T) {
+	var config struct {
+		Host string `env:"HOST ,parser=nonempty-string                 "`
+		Port int    `env:"PORT ,parser=strconv.ParseInt                "`
+		User string `env:"USER ,parser=nonempty-string ,default=nobody "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := testEnv{"HOST": "env.example.com"}
+	flags := map[string]string{"HOST": "flag.example.com", "PORT": "8080"}
+	warn, fatal := parser.ParseFromEnv(&config, envconfig.ChainLookup(env.lookup, envconfig.FlagLookup(flags)))
+	assert.Equal(t, len(warn), 0, "There should be no warnings")
+	assert.Equal(t, len(fatal), 0, "There should be no errors")
+	assert.Equal(t, "env.example.com", config.Host, "env should take precedence over flags")
+	assert.Equal(t, 8080, config.Port, "flags should fill in what env doesn't set")
+	assert.Equal(t, "nobody", config.User, "the default should apply if neither sets it")
+}
+
+func TestRecursive(t *testing.T) {
+	var config struct {
+		ParentThing string `env:"PARENT_THING,parser=nonempty-string"`
+		Child       struct {
+			Thing1 string `env:"CHILD_THING1,parser=nonempty-string"`
+			Thing2 string `env:"CHILD_THING2,parser=nonempty-string"`
+		}
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := testEnv{
+		"PARENT_THING": "foo",
+		"CHILD_THING1": "bar",
+		"CHILD_THING2": "baz",
+	}
+	warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+	assert.Equal(t, len(warn), 0, "There should be no warnings")
+	assert.Equal(t, len(fatal), 0, "There should be no errors")
+	assert.Equal(t, config.ParentThing, "foo")
+	assert.Equal(t, config.Child.Thing1, "bar")
+	assert.Equal(t, config.Child.Thing2, "baz")
+}
+
+func TestRecursiveNamed(t *testing.T) {
+	var config struct {
+		Sub     subConfig
+		Started time.Time `env:"STARTED,parser=RFC3339"`
+	}
+	// time.Time is a struct too, but since it has a handler, it must be handled as a value
+	// rather than being recursed in to.
+	handlers := envconfig.MergeHandlers(envconfig.DefaultFieldTypeHandlers(), map[reflect.Type]envconfig.FieldTypeHandler{
+		reflect.TypeOf(time.Time{}): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"RFC3339": func(str string) (interface{}, error) { return time.Parse(time.RFC3339, str) },
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src)) },
+		},
+	})
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), handlers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := testEnv{
+		"SUB_HOST": "example.com",
+		"STARTED":  "2020-01-02T03:04:05Z",
+	}
+	warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+	assert.Equal(t, len(warn), 0, "There should be no warnings")
+	assert.Equal(t, len(fatal), 0, "There should be no errors")
+	assert.Equal(t, subConfig{Host: "example.com", Port: 80}, config.Sub)
+	assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), config.Started)
+
+	t.Run("no-handler", func(t *testing.T) {
+		type timestamp struct{ Sec int64 }
+		var config struct {
+			Started timestamp `env:"STARTED,parser=RFC3339"`
+		}
+		_, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+		assert.EqualError(t, err, `struct field "Started": unsupported type envconfig_test.timestamp; cannot have tag on nested struct`)
+	})
+}
+
+func TestCommaSplitKVDuration(t *testing.T) {
+	var config struct {
+		Timeouts map[string]time.Duration `env:"TIMEOUTS,parser=comma-split-kv"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		Expected    map[string]time.Duration
+		ExpectError string
+	}{
+		"valid": {
+			Input:    "default=1s, slow = 30s",
+			Expected: map[string]time.Duration{"default": time.Second, "slow": 30 * time.Second},
+		},
+		"empty": {
+			Input:    "",
+			Expected: map[string]time.Duration{},
+		},
+		"malformed-duration": {
+			Input:       "default=1s,slow=30",
+			ExpectError: `key "slow"`,
+		},
+		"malformed-pair": {
+			Input:       "default=1s,slow",
+			ExpectError: `not a key=value pair: "slow"`,
+		},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Timeouts = nil
+			env := testEnv{"TIMEOUTS": tc.Input}
+
+			warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError != "" {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.Contains(t, fatal[0].Error(), tc.ExpectError)
+				}
+				assert.Nil(t, config.Timeouts)
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				assert.NotNil(t, config.Timeouts, "config.Timeouts should not be nil")
+				assert.Equal(t, tc.Expected, config.Timeouts)
+			}
+		})
+	}
+}
+
+func TestCommaSplitKVString(t *testing.T) {
+	var config struct {
+		Labels map[string]string `env:"LABELS,parser=comma-split-kv"`
+		Extra  map[string]string `env:"EXTRA,parser=comma-split-kv,default=env=prod"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		Expected    map[string]string
+		ExpectError string
+	}{
+		"valid": {
+			Input:    "team=infra, tier = backend",
+			Expected: map[string]string{"team": "infra", "tier": "backend"},
+		},
+		"value-with-equals": {
+			Input:    "query=a=b",
+			Expected: map[string]string{"query": "a=b"},
+		},
+		"empty-value": {
+			Input:    "team=",
+			Expected: map[string]string{"team": ""},
+		},
+		"duplicate-key": {
+			Input:    "team=infra,team=platform",
+			Expected: map[string]string{"team": "platform"},
+		},
+		"empty": {
+			Input:    "",
+			Expected: map[string]string{},
+		},
+		"malformed-pair": {
+			Input:       "team=infra, backend",
+			ExpectError: `not a key=value pair: "backend"`,
+		},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Labels = nil
+			env := testEnv{"LABELS": tc.Input}
+
+			warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			assert.Equal(t, map[string]string{"env": "prod"}, config.Extra)
+			if tc.ExpectError != "" {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.Contains(t, fatal[0].Error(), tc.ExpectError)
+				}
+				assert.Nil(t, config.Labels)
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				assert.NotNil(t, config.Labels, "config.Labels should not be nil")
+				assert.Equal(t, tc.Expected, config.Labels)
+			}
+		})
+	}
+}
+
+func TestJSONNumber(t *testing.T) {
+	var config struct {
+		Limit json.Number `env:"LIMIT,parser=json-number"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		Expected    json.Number
+		ExpectError bool
+	}{
+		"integer":       {Input: "42", Expected: "42"},
+		"large-integer": {Input: "123456789012345678901234567890", Expected: "123456789012345678901234567890"},
+		"decimal":       {Input: "-0.000001", Expected: "-0.000001"},
+		"exponent":      {Input: "1.5e300", Expected: "1.5e300"},
+		"malformed":     {Input: "1.2.3", ExpectError: true},
+		"leading-zero":  {Input: "01", ExpectError: true},
+		"plus-sign":     {Input: "+1", ExpectError: true},
+		"quoted":        {Input: `"42"`, ExpectError: true},
+		"empty":         {Input: "", ExpectError: true},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Limit = ""
+			warn, fatal := parser.ParseFromEnv(&config, testEnv{"LIMIT": tc.Input}.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.Contains(t, fatal[0].Error(), "invalid JSON number")
+				}
+				assert.Equal(t, json.Number(""), config.Limit)
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				assert.Equal(t, tc.Expected, config.Limit)
+			}
+		})
+	}
+}
+
+func TestTime(t *testing.T) {
+	var config struct {
+		NotBefore time.Time `env:"NOT_BEFORE ,parser=time.RFC3339 "`
+		BuildTime time.Time `env:"BUILD_TIME ,parser=unix-seconds ,default=0 "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		env := testEnv{"NOT_BEFORE": "2024-01-02T03:04:05+05:30", "BUILD_TIME": "1704164645"}
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		assert.True(t, config.NotBefore.Equal(time.Date(2024, 1, 1, 21, 34, 5, 0, time.UTC)))
+		_, offset := config.NotBefore.Zone()
+		assert.Equal(t, 5*60*60+30*60, offset, "time.RFC3339 should keep the offset")
+		// unix-seconds is in UTC, so that == and reflect.DeepEqual comparisons are stable.
+		assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), config.BuildTime)
+		assert.Equal(t, time.UTC, config.BuildTime.Location())
+	})
+
+	t.Run("default-epoch", func(t *testing.T) {
+		env := testEnv{"NOT_BEFORE": "2024-01-02T03:04:05Z"}
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		assert.Equal(t, time.Unix(0, 0).UTC(), config.BuildTime)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		var config struct {
+			NotBefore time.Time `env:"NOT_BEFORE ,parser=time.RFC3339 "`
+			BuildTime time.Time `env:"BUILD_TIME ,parser=unix-seconds "`
+		}
+		parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		env := testEnv{"NOT_BEFORE": "2024-01-02 03:04:05", "BUILD_TIME": "yesterday"}
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		if assert.Equal(t, len(fatal), 2, "There should be 2 fatal errors") {
+			assert.Contains(t, fatal[0].Error(), "invalid NotBefore (aborting)")
+			assert.Contains(t, fatal[1].Error(), `invalid BuildTime (aborting): strconv.ParseInt: parsing "yesterday": invalid syntax`)
+		}
+		assert.True(t, config.NotBefore.IsZero())
+		assert.True(t, config.BuildTime.IsZero())
+	})
+}
+
+func TestMoney(t *testing.T) {
+	var config struct {
+		Price envconfig.Money `env:"PRICE,parser=currency-amount"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		Expected    envconfig.Money
+		ExpectError string
+	}{
+		"usd":             {Input: "USD 12.34", Expected: envconfig.Money{Currency: "USD", Amount: 1234}},
+		"usd-whole":       {Input: "USD 12", Expected: envconfig.Money{Currency: "USD", Amount: 1200}},
+		"usd-one-decimal": {Input: "USD 12.3", Expected: envconfig.Money{Currency: "USD", Amount: 1230}},
+		"negative":        {Input: "USD -0.05", Expected: envconfig.Money{Currency: "USD", Amount: -5}},
+		"jpy":             {Input: "JPY 1500", Expected: envconfig.Money{Currency: "JPY", Amount: 1500}},
+		"bhd":             {Input: "  BHD   1.005 ", Expected: envconfig.Money{Currency: "BHD", Amount: 1005}},
+		"jpy-decimal": {
+			Input:       "JPY 1500.5",
+			ExpectError: `invalid amount "1500.5": JPY has 0 digits after the decimal point, not 1`,
+		},
+		"too-many-decimals": {
+			Input:       "USD 12.345",
+			ExpectError: `invalid amount "12.345": USD has 2 digits after the decimal point, not 3`,
+		},
+		"unknown-currency": {
+			Input:       "XYZ 12.34",
+			ExpectError: `unknown currency "XYZ"`,
+		},
+		"lowercase-currency": {
+			Input:       "usd 12.34",
+			ExpectError: `unknown currency "usd"`,
+		},
+		"no-currency": {
+			Input:       "12.34",
+			ExpectError: `not a "CURRENCY AMOUNT" string: "12.34"`,
+		},
+		"malformed-amount": {
+			Input:       "USD 12,34",
+			ExpectError: `invalid amount "12,34"`,
+		},
+		"trailing-dot": {
+			Input:       "USD 12.",
+			ExpectError: `invalid amount "12."`,
+		},
+		"leading-dot": {
+			Input:       "USD .5",
+			ExpectError: `invalid amount ".5"`,
+		},
+		"overflow": {
+			Input:       "USD 99999999999999999999",
+			ExpectError: `invalid amount "99999999999999999999"`,
+		},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Price = envconfig.Money{}
+			warn, fatal := parser.ParseFromEnv(&config, testEnv{"PRICE": tc.Input}.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError != "" {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.Contains(t, fatal[0].Error(), tc.ExpectError)
+				}
+				assert.Equal(t, envconfig.Money{}, config.Price)
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				assert.Equal(t, tc.Expected, config.Price)
+			}
+		})
+	}
+
+	t.Run("String", func(t *testing.T) {
+		assert.Equal(t, "USD 12.34", envconfig.Money{Currency: "USD", Amount: 1234}.String())
+		assert.Equal(t, "USD -0.05", envconfig.Money{Currency: "USD", Amount: -5}.String())
+		assert.Equal(t, "JPY 1500", envconfig.Money{Currency: "JPY", Amount: 1500}.String())
+		assert.Equal(t, "BHD 1.005", envconfig.Money{Currency: "BHD", Amount: 1005}.String())
+	})
+}
+
+func TestWeightedEntries(t *testing.T) {
+	var config struct {
+		Backends []envconfig.WeightedEntry `env:"BACKENDS,parser=comma-split-weighted"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		Expected    []envconfig.WeightedEntry
+		ExpectError string
+	}{
+		"explicit": {
+			Input:    "a:3, b : 1",
+			Expected: []envconfig.WeightedEntry{{Name: "a", Weight: 3}, {Name: "b", Weight: 1}},
+		},
+		"defaulted": {
+			Input:    "a:3,b,c:0",
+			Expected: []envconfig.WeightedEntry{{Name: "a", Weight: 3}, {Name: "b", Weight: 1}, {Name: "c", Weight: 0}},
+		},
+		"host-port": {
+			Input:    "10.0.0.1:8080:2,10.0.0.2:8080:1",
+			Expected: []envconfig.WeightedEntry{{Name: "10.0.0.1:8080", Weight: 2}, {Name: "10.0.0.2:8080", Weight: 1}},
+		},
+		"empty": {
+			Input:    "",
+			Expected: []envconfig.WeightedEntry{},
+		},
+		"non-integer-weight": {
+			Input:       "a:3,b:heavy",
+			ExpectError: `entry "b:heavy": invalid weight "heavy"`,
+		},
+		"missing-weight": {
+			Input:       "a:3,b:",
+			ExpectError: `entry "b:": invalid weight ""`,
+		},
+		"negative-weight": {
+			Input:       "a:-1",
+			ExpectError: `entry "a:-1": invalid weight "-1"`,
+		},
+		"empty-name": {
+			Input:       "a:3,:1",
+			ExpectError: `entry ":1": empty name`,
+		},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Backends = nil
+			warn, fatal := parser.ParseFromEnv(&config, testEnv{"BACKENDS": tc.Input}.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError != "" {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.Contains(t, fatal[0].Error(), tc.ExpectError)
+				}
+				assert.Nil(t, config.Backends)
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				assert.Equal(t, tc.Expected, config.Backends)
+			}
+		})
+	}
+}
+
+func TestCommaSplitIPKV(t *testing.T) {
+	var config struct {
+		Labels map[string]string `env:"IP_LABELS,parser=comma-split-ip-kv"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		Expected    map[string]string
+		ExpectError string
+	}{
+		"ipv4": {
+			Input:    "10.0.0.1=gateway, 192.168.1.20 = printer",
+			Expected: map[string]string{"10.0.0.1": "gateway", "192.168.1.20": "printer"},
+		},
+		"ipv6-normalized": {
+			Input:    "2001:DB8:0:0:0:0:0:1=web,::ffff:10.0.0.2=mapped",
+			Expected: map[string]string{"2001:db8::1": "web", "10.0.0.2": "mapped"},
+		},
+		"empty-value": {
+			Input:    "10.0.0.1=",
+			Expected: map[string]string{"10.0.0.1": ""},
+		},
+		"empty": {
+			Input:    "",
+			Expected: map[string]string{},
+		},
+		"invalid-ip": {
+			Input:       "10.0.0.1=gateway,10.0.0.256=bogus",
+			ExpectError: `invalid IP address "10.0.0.256"`,
+		},
+		"hostname": {
+			Input:       "db.example.com=db",
+			ExpectError: `invalid IP address "db.example.com"`,
+		},
+		"duplicate-after-normalization": {
+			Input:    "2001:db8::1=a,2001:DB8::0:1=b",
+			Expected: map[string]string{"2001:db8::1": "b"},
+		},
+		"not-a-pair": {
+			Input:       "10.0.0.1",
+			ExpectError: `not a key=value pair: "10.0.0.1"`,
+		},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Labels = nil
+			warn, fatal := parser.ParseFromEnv(&config, testEnv{"IP_LABELS": tc.Input}.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError != "" {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.Contains(t, fatal[0].Error(), tc.ExpectError)
+				}
+				assert.Nil(t, config.Labels)
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				assert.Equal(t, tc.Expected, config.Labels)
+			}
+		})
+	}
+}
+
+func TestDotenv(t *testing.T) {
+	var config struct {
+		Vars map[string]string `env:"VARS,parser=dotenv"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		Expected    map[string]string
+		ExpectError string
+	}{
+		"simple": {
+			Input:    "A=1\nB = two \n",
+			Expected: map[string]string{"A": "1", "B": "two"},
+		},
+		"comments-and-export": {
+			Input:    "# leading comment\n\nexport A=1 # trailing comment\nB=#\n",
+			Expected: map[string]string{"A": "1", "B": ""},
+		},
+		"double-quoted-equals-comma": {
+			Input:    `DSN="user=a,password=b=c" # comment`,
+			Expected: map[string]string{"DSN": "user=a,password=b=c"},
+		},
+		"single-quoted-equals-comma": {
+			Input:    `LIST='a=1, b=2, # not a comment'`,
+			Expected: map[string]string{"LIST": "a=1, b=2, # not a comment"},
+		},
+		"escapes": {
+			// "$$" survives ${VAR} expansion as "$", which the dotenv parser then sees escaped.
+			Input:    `A="tab\there \"quoted\" \\ \$$HOME"` + "\n" + `B='no\tescape'`,
+			Expected: map[string]string{"A": "tab\there \"quoted\" \\ $HOME", "B": `no\tescape`},
+		},
+		"multiline": {
+			Input:    "KEY=\"-----BEGIN-----\nabc=,\n-----END-----\"\nNEXT=1",
+			Expected: map[string]string{"KEY": "-----BEGIN-----\nabc=,\n-----END-----", "NEXT": "1"},
+		},
+		"last-wins": {
+			Input:    "A=1\nA=2",
+			Expected: map[string]string{"A": "2"},
+		},
+		"crlf": {
+			Input:    "A=1\r\nB=\"2\"\r\n",
+			Expected: map[string]string{"A": "1", "B": "2"},
+		},
+		"empty": {
+			Input:    "",
+			Expected: map[string]string{},
+		},
+		"no-equals": {
+			Input:       "A=1\nB",
+			ExpectError: `line 2: not a KEY=VALUE pair: "B"`,
+		},
+		"empty-key": {
+			Input:       "=1",
+			ExpectError: "line 1: empty key",
+		},
+		"unterminated": {
+			Input:       "A=1\nB=\"abc\nC=2",
+			ExpectError: `line 2: unterminated "-quoted value for "B"`,
+		},
+		"trailing-garbage": {
+			Input:       `A="abc"def`,
+			ExpectError: `line 1: unexpected text after quoted value for "A": "def"`,
+		},
+		"bad-escape": {
+			Input:       `A="\q"`,
+			ExpectError: `invalid escape "\q"`,
+		},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Vars = nil
+			warn, fatal := parser.ParseFromEnv(&config, testEnv{"VARS": tc.Input}.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError != "" {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.Contains(t, fatal[0].Error(), tc.ExpectError)
+				}
+				assert.Nil(t, config.Vars)
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				assert.Equal(t, tc.Expected, config.Vars)
+			}
+		})
+	}
+}
+
+func TestCommaSplitKVBool(t *testing.T) {
+	var config struct {
+		Features map[string]bool `env:"FEATURES,parser=comma-split-kv"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		Expected    map[string]bool
+		ExpectError string
+	}{
+		"key-value": {
+			Input:    "a=true, b = false",
+			Expected: map[string]bool{"a": true, "b": false},
+		},
+		"bare-keys": {
+			Input:    "a,b",
+			Expected: map[string]bool{"a": true, "b": true},
+		},
+		"mixed": {
+			Input:    "a, b=0, c=T",
+			Expected: map[string]bool{"a": true, "b": false, "c": true},
+		},
+		"empty": {
+			Input:    "",
+			Expected: map[string]bool{},
+		},
+		"malformed-bool": {
+			Input:       "a=true,b=maybe",
+			ExpectError: `key "b"`,
+		},
+		"empty-key": {
+			Input:       "a,,b",
+			ExpectError: `empty key in ""`,
+		},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Features = nil
+			warn, fatal := parser.ParseFromEnv(&config, testEnv{"FEATURES": tc.Input}.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError != "" {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.Contains(t, fatal[0].Error(), tc.ExpectError)
+				}
+				assert.Nil(t, config.Features)
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				assert.NotNil(t, config.Features, "config.Features should not be nil")
+				assert.Equal(t, tc.Expected, config.Features)
+			}
+		})
+	}
+}
+
+func TestCommaSplitKVOrdered(t *testing.T) {
+	var config struct {
+		Headers []envconfig.KeyValue `env:"HEADERS,parser=comma-split-kv"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		Expected    []envconfig.KeyValue
+		ExpectError string
+	}{
+		"ordered": {
+			Input: "z=1, a = 2,m=3=4,a=5",
+			Expected: []envconfig.KeyValue{
+				{Key: "z", Value: "1"},
+				{Key: "a", Value: "2"},
+				{Key: "m", Value: "3=4"},
+				{Key: "a", Value: "5"},
+			},
+		},
+		"empty":     {Input: "", Expected: []envconfig.KeyValue{}},
+		"malformed": {Input: "z=1,oops", ExpectError: `not a key=value pair: "oops"`},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Headers = nil
+			warn, fatal := parser.ParseFromEnv(&config, testEnv{"HEADERS": tc.Input}.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError != "" {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.Contains(t, fatal[0].Error(), tc.ExpectError)
+				}
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				assert.Equal(t, tc.Expected, config.Headers)
+			}
+		})
+	}
+}
+
+func TestParseOrError(t *testing.T) {
+	var config struct {
+		A string `env:"A,parser=nonempty-string"`
+		B string `env:"B,parser=nonempty-string"`
+		C string `env:"C,parser=nonempty-string,default=c"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("zero", func(t *testing.T) {
+		env := testEnv{"A": "a", "B": "b", "C": ""}
+		assert.NoError(t, parser.ParseOrError(&config, env.lookup))
+		assert.Error(t, parser.ParseOrErrorStrict(&config, env.lookup), "the warning for C should be promoted")
+	})
+	t.Run("one", func(t *testing.T) {
+		env := testEnv{"A": "a"}
+		err := parser.ParseOrError(&config, env.lookup)
+		require.Error(t, err)
+		var multi envconfig.MultiError
+		assert.False(t, errors.As(err, &multi), "a single error should not be aggregated")
+		assert.True(t, errors.Is(err, envconfig.ErrNotSet))
+		assert.Equal(t, "invalid B (aborting): is not set", err.Error())
+	})
+	t.Run("multiple", func(t *testing.T) {
+		env := testEnv{}
+		err := parser.ParseOrError(&config, env.lookup)
+		require.Error(t, err)
+		var multi envconfig.MultiError
+		require.True(t, errors.As(err, &multi))
+		assert.Len(t, multi, 2)
+		assert.True(t, errors.Is(err, envconfig.ErrNotSet))
+		assert.Equal(t, "2 errors: invalid A (aborting): is not set; invalid B (aborting): is not set", err.Error())
+	})
+}
+
+func TestSQLNullTypes(t *testing.T) {
+	type config struct {
+		String  sql.NullString  `env:"STRING  ,parser=possibly-empty-string "`
+		Int64   sql.NullInt64   `env:"INT64   ,parser=strconv.ParseInt      "`
+		Bool    sql.NullBool    `env:"BOOL    ,parser=strconv.ParseBool     "`
+		Float64 sql.NullFloat64 `env:"FLOAT64 ,parser=strconv.ParseFloat    "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config{}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("set", func(t *testing.T) {
+		env := testEnv{
+			"STRING":  "",
+			"INT64":   "-42",
+			"BOOL":    "true",
+			"FLOAT64": "1.5",
+		}
+		var cfg config
+		warn, fatal := parser.ParseFromEnv(&cfg, env.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no errors")
+		assert.Equal(t, config{
+			String:  sql.NullString{String: "", Valid: true},
+			Int64:   sql.NullInt64{Int64: -42, Valid: true},
+			Bool:    sql.NullBool{Bool: true, Valid: true},
+			Float64: sql.NullFloat64{Float64: 1.5, Valid: true},
+		}, cfg)
+	})
+	t.Run("unset", func(t *testing.T) {
+		env := testEnv{}
+		cfg := config{
+			String:  sql.NullString{String: "stale", Valid: true},
+			Int64:   sql.NullInt64{Int64: 1, Valid: true},
+			Bool:    sql.NullBool{Bool: true, Valid: true},
+			Float64: sql.NullFloat64{Float64: 1, Valid: true},
+		}
+		warn, fatal := parser.ParseFromEnv(&cfg, env.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no errors")
+		assert.Equal(t, config{}, cfg)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		env := testEnv{"INT64": "x", "BOOL": "x", "FLOAT64": "x"}
+		var cfg config
+		warn, fatal := parser.ParseFromEnv(&cfg, env.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 3, "There should be 3 errors")
+	})
+}
+
+type defaultFuncConfig struct {
+	Worker  string        `env:"WORKER  ,parser=nonempty-string    ,defaultFunc=DefaultWorker  "`
+	Timeout time.Duration `env:"TIMEOUT ,parser=time.ParseDuration ,defaultFunc=DefaultTimeout "`
+}
+
+func (defaultFuncConfig) DefaultWorker() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+	return "worker-" + hostname, nil
+}
+
+func (*defaultFuncConfig) DefaultTimeout() string {
+	return "5s"
+}
+
+func TestDefaultFunc(t *testing.T) {
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(defaultFuncConfig{}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("unset", func(t *testing.T) {
+		var config defaultFuncConfig
+		warn, fatal := parser.ParseFromEnv(&config, testEnv{}.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no errors")
+		assert.Equal(t, "worker-"+hostname, config.Worker)
+		assert.Equal(t, 5*time.Second, config.Timeout)
+	})
+	t.Run("set", func(t *testing.T) {
+		var config defaultFuncConfig
+		env := testEnv{"WORKER": "w1", "TIMEOUT": "1m"}
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no errors")
+		assert.Equal(t, "w1", config.Worker)
+		assert.Equal(t, time.Minute, config.Timeout)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		var config defaultFuncConfig
+		env := testEnv{"WORKER": "", "TIMEOUT": "x"}
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		assert.Equal(t, len(warn), 2, "There should be 2 warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no errors")
+		assert.Equal(t, "worker-"+hostname, config.Worker)
+		assert.Equal(t, 5*time.Second, config.Timeout)
+	})
+	t.Run("bad-method", func(t *testing.T) {
+		var config struct {
+			Value string `env:"VALUE,parser=nonempty-string,defaultFunc=Missing"`
+		}
+		_, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+		assert.EqualError(t, err, `struct field "Value": env option "defaultFunc": referenced method "Missing" does not exist (is it exported?)`)
+	})
+	t.Run("bad-signature", func(t *testing.T) {
+		var config struct {
+			Value int `env:"VALUE,parser=strconv.ParseInt,defaultFunc=DefaultWorker"`
+			defaultFuncConfig
+		}
+		_, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+		assert.Error(t, err)
+	})
+	t.Run("multiple-defaults", func(t *testing.T) {
+		var config struct {
+			defaultFuncConfig
+			Value string `env:"VALUE,parser=nonempty-string,defaultFunc=DefaultWorker,default=x"`
+		}
+		_, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+		assert.EqualError(t, err, `struct field "Value": has more than one of default and defaultFunc`)
+	})
+}
+
+type defaulterConfig struct {
+	Host    string        `env:"HOST    ,parser=nonempty-string                 "`
+	Port    int           `env:"PORT    ,parser=strconv.ParseInt                "`
+	Timeout time.Duration `env:"TIMEOUT ,parser=time.ParseDuration ,default=5s "`
+}
+
+func (c *defaulterConfig) Default() {
+	c.Host = "localhost"
+	c.Port = 8080
+	c.Timeout = time.Minute
+}
+
+func TestDefaulter(t *testing.T) {
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(defaulterConfig{}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("override", func(t *testing.T) {
+		var config defaulterConfig
+		warn, fatal := parser.ParseFromEnv(&config, testEnv{"PORT": "9090"}.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no errors")
+		assert.Equal(t, "localhost", config.Host, "Default() should be kept when env doesn't set it")
+		assert.Equal(t, 9090, config.Port, "env should override Default()")
+		assert.Equal(t, 5*time.Second, config.Timeout, "a default in the tag should override Default()")
+	})
+	t.Run("invalid", func(t *testing.T) {
+		config := defaulterConfig{Port: 1}
+		warn, fatal := parser.ParseFromEnv(&config, testEnv{"PORT": "x"}.lookup)
+		assert.Equal(t, len(warn), 1, "There should be 1 warning")
+		assert.Equal(t, len(fatal), 0, "There should be no errors")
+		assert.Equal(t, 8080, config.Port, "an invalid value should fall back to Default()")
+	})
+	t.Run("not-required", func(t *testing.T) {
+		assert.Empty(t, parser.CheckRequired(testEnv{}.lookup))
+	})
+}
+
+// smallUintHandlers returns handlers for the unsigned integer types that DefaultFieldTypeHandlers doesn't
+// have, for testing options that apply to any integer.
+func smallUintHandlers() map[reflect.Type]envconfig.FieldTypeHandler {
+	ret := make(map[reflect.Type]envconfig.FieldTypeHandler)
+	for _, typ := range []reflect.Type{reflect.TypeOf(uint8(0)), reflect.TypeOf(uint16(0)), reflect.TypeOf(uint32(0)), reflect.TypeOf(uintptr(0))} {
+		typ := typ // capture loop variable
+		ret[typ] = envconfig.FieldTypeHandler{
+			Parsers: map[string]func(string) (interface{}, error){
+				"strconv.ParseUint": func(str string) (interface{}, error) {
+					n, err := strconv.ParseUint(str, 10, typ.Bits())
+					if err != nil {
+						return nil, err
+					}
+					return reflect.ValueOf(n).Convert(typ).Interface(), nil
+				},
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src)) },
+		}
+	}
+	return ret
+}
+
+func TestClamp(t *testing.T) {
+	var config struct {
+		Workers int   `env:"WORKERS ,parser=strconv.ParseInt ,clamp=1:runtime.NumCPU "`
+		Limit   int64 `env:"LIMIT   ,parser=strconv.ParseInt ,clamp=:10             ,default=20 "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input    string
+		Expected int
+		Warnings int
+	}{
+		"below":    {Input: "0", Expected: 1, Warnings: 2},
+		"above":    {Input: strconv.Itoa(runtime.NumCPU() + 1), Expected: runtime.NumCPU(), Warnings: 2},
+		"in-range": {Input: "1", Expected: 1, Warnings: 1},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			env := testEnv{"WORKERS": tc.Input}
+			warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+			assert.Equalf(t, tc.Warnings, len(warn), "There should be %d warnings", tc.Warnings)
+			assert.Equal(t, len(fatal), 0, "There should be no errors")
+			assert.Equal(t, tc.Expected, config.Workers)
+			assert.Equal(t, int64(10), config.Limit, "the default should be clamped too")
+		})
+	}
+
+	t.Run("invalid-option", func(t *testing.T) {
+		for _, field := range []reflect.StructField{
+			{Type: reflect.TypeOf(0), Tag: `env:"VALUE,parser=strconv.ParseInt,clamp=1"`},
+			{Type: reflect.TypeOf(0), Tag: `env:"VALUE,parser=strconv.ParseInt,clamp=2:1"`},
+			{Type: reflect.TypeOf(0), Tag: `env:"VALUE,parser=strconv.ParseInt,clamp=1:NumGoroutine"`},
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=nonempty-string,clamp=1:2"`},
+			{Type: reflect.TypeOf(uint8(0)), Tag: `env:"VALUE,parser=strconv.ParseUint,clamp=-1:2"`},
+			{Type: reflect.TypeOf(uint8(0)), Tag: `env:"VALUE,parser=strconv.ParseUint,clamp=:-1"`},
+			{Type: reflect.TypeOf(uint8(0)), Tag: `env:"VALUE,parser=strconv.ParseUint,clamp=1:256"`},
+		} {
+			field.Name = "Value"
+			handlers := envconfig.MergeHandlers(envconfig.DefaultFieldTypeHandlers(), smallUintHandlers())
+			_, err := envconfig.GenerateParser(reflect.StructOf([]reflect.StructField{field}), handlers)
+			assert.Errorf(t, err, "tag %s should be rejected", field.Tag)
+		}
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		var config struct {
+			Uint    uint    `env:"UINT    ,parser=strconv.ParseUint ,clamp=2:8    "`
+			Uint8   uint8   `env:"UINT8   ,parser=strconv.ParseUint ,clamp=2:8    "`
+			Uint16  uint16  `env:"UINT16  ,parser=strconv.ParseUint ,clamp=:1000  "`
+			Uint32  uint32  `env:"UINT32  ,parser=strconv.ParseUint ,clamp=2:     "`
+			Uint64  uint64  `env:"UINT64  ,parser=strconv.ParseUint ,clamp=2:     "`
+			Uintptr uintptr `env:"UINTPTR ,parser=strconv.ParseUint ,clamp=2:8    "`
+		}
+		handlers := envconfig.MergeHandlers(envconfig.DefaultFieldTypeHandlers(), smallUintHandlers())
+		parser, err := envconfig.GenerateParser(reflect.TypeOf(config), handlers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		env := testEnv{
+			"UINT":    "100",
+			"UINT8":   "1",
+			"UINT16":  "65535",
+			"UINT32":  "0",
+			"UINT64":  "18446744073709551615",
+			"UINTPTR": "4",
+		}
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		assert.Equal(t, len(fatal), 0, "There should be no errors")
+		if assert.Equal(t, 4, len(warn), "There should be a warning for each clamped value") {
+			assert.EqualError(t, warn[0], "invalid Uint: value 100 is out of range [2:8] (clamping to 8)")
+			assert.EqualError(t, warn[2], "invalid Uint16: value 65535 is out of range [:1000] (clamping to 1000)")
+		}
+		assert.Equal(t, uint(8), config.Uint)
+		assert.Equal(t, uint8(2), config.Uint8)
+		assert.Equal(t, uint16(1000), config.Uint16)
+		assert.Equal(t, uint32(2), config.Uint32)
+		assert.Equal(t, uint64(math.MaxUint64), config.Uint64, "an unbounded maximum should not be MaxInt64")
+		assert.Equal(t, uintptr(4), config.Uintptr)
+	})
+}
+
+func TestPowerOfTwo(t *testing.T) {
+	var config struct {
+		BufSize int64 `env:"BUF_SIZE,parser=strconv.ParseInt,powerOfTwo=true"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		Expected    int64
+		ExpectError bool
+	}{
+		"1024":     {Input: "1024", Expected: 1024},
+		"1":        {Input: "1", Expected: 1},
+		"2^62":     {Input: "4611686018427387904", Expected: 1 << 62},
+		"1000":     {Input: "1000", ExpectError: true},
+		"0":        {Input: "0", ExpectError: true},
+		"negative": {Input: "-1024", ExpectError: true},
+		"min":      {Input: "-9223372036854775808", ExpectError: true},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.BufSize = 0
+			warn, fatal := parser.ParseFromEnv(&config, testEnv{"BUF_SIZE": tc.Input}.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.Contains(t, fatal[0].Error(), "is not a power of two")
+				}
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				assert.Equal(t, tc.Expected, config.BufSize)
+			}
+		})
+	}
+
+	t.Run("invalid-option", func(t *testing.T) {
+		for _, field := range []reflect.StructField{
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=nonempty-string,powerOfTwo=true"`},
+			{Type: reflect.TypeOf(0), Tag: `env:"VALUE,parser=strconv.ParseInt,powerOfTwo=true,default=1000"`},
+			{Type: reflect.TypeOf(uint(0)), Tag: `env:"VALUE,parser=strconv.ParseUint,powerOfTwo=true,default=0"`},
+		} {
+			field.Name = "Value"
+			_, err := envconfig.GenerateParser(reflect.StructOf([]reflect.StructField{field}), nil)
+			assert.Errorf(t, err, "tag %s should be rejected", field.Tag)
+		}
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		var config struct {
+			Uint    uint    `env:"UINT    ,parser=strconv.ParseUint ,powerOfTwo=true ,default=1 "`
+			Uint8   uint8   `env:"UINT8   ,parser=strconv.ParseUint ,powerOfTwo=true ,default=1 "`
+			Uint16  uint16  `env:"UINT16  ,parser=strconv.ParseUint ,powerOfTwo=true ,default=1 "`
+			Uint32  uint32  `env:"UINT32  ,parser=strconv.ParseUint ,powerOfTwo=true ,default=1 "`
+			Uint64  uint64  `env:"UINT64  ,parser=strconv.ParseUint ,powerOfTwo=true ,default=1 "`
+			Uintptr uintptr `env:"UINTPTR ,parser=strconv.ParseUint ,powerOfTwo=true ,default=1 "`
+		}
+		handlers := envconfig.MergeHandlers(envconfig.DefaultFieldTypeHandlers(), smallUintHandlers())
+		parser, err := envconfig.GenerateParser(reflect.TypeOf(config), handlers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		env := testEnv{
+			"UINT":    "64",
+			"UINT8":   "128",
+			"UINT16":  "48",
+			"UINT32":  "0",
+			"UINT64":  "9223372036854775808",
+			"UINTPTR": "4096",
+		}
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		assert.Equal(t, len(fatal), 0, "There should be no errors")
+		if assert.Equal(t, 2, len(warn), "There should be a warning for each value that isn't a power of two") {
+			assert.Contains(t, warn[0].Error(), "48 is not a power of two")
+			assert.Contains(t, warn[1].Error(), "0 is not a power of two")
+		}
+		assert.Equal(t, uint(64), config.Uint)
+		assert.Equal(t, uint8(128), config.Uint8)
+		assert.Equal(t, uint16(1), config.Uint16)
+		assert.Equal(t, uint32(1), config.Uint32)
+		assert.Equal(t, uint64(1)<<63, config.Uint64, "2^63 doesn't fit in an int64, but is a power of two")
+		assert.Equal(t, uintptr(4096), config.Uintptr)
+	})
+}
+
+func TestNumericOverflow(t *testing.T) {
+	if strconv.IntSize != 64 {
+		t.Skip("the int boundaries in this test assume a 64-bit platform")
+	}
+	var config struct {
+		Int     int           `env:"INT     ,parser=strconv.ParseInt   "`
+		Int64   int64         `env:"INT64   ,parser=strconv.ParseInt   "`
+		Float32 float32       `env:"FLOAT32 ,parser=strconv.ParseFloat "`
+		Seconds time.Duration `env:"SECONDS ,parser=integer-seconds    "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("at-boundary", func(t *testing.T) {
+		env := testEnv{
+			"INT":     "9223372036854775807",
+			"INT64":   "-9223372036854775808",
+			"FLOAT32": "3.4e38",
+			"SECONDS": "9223372036",
+		}
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		assert.Equal(t, math.MaxInt64, config.Int)
+		assert.Equal(t, int64(math.MinInt64), config.Int64)
+		assert.Equal(t, float32(3.4e38), config.Float32)
+		assert.Equal(t, 9223372036*time.Second, config.Seconds)
+	})
+
+	t.Run("past-boundary", func(t *testing.T) {
+		env := testEnv{
+			"INT":     "9223372036854775808",
+			"INT64":   "-9223372036854775809",
+			"FLOAT32": "3.5e38",
+			"SECONDS": "9223372037",
+		}
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		if assert.Equal(t, len(fatal), 4, "There should be 4 fatal errors") {
+			assert.EqualError(t, fatal[0], `invalid Int (aborting): "9223372036854775808" is out of range for a 64-bit integer (must be between -9223372036854775808 and 9223372036854775807)`)
+			assert.EqualError(t, fatal[1], `invalid Int64 (aborting): "-9223372036854775809" is out of range for a 64-bit integer (must be between -9223372036854775808 and 9223372036854775807)`)
+			assert.EqualError(t, fatal[2], `invalid Float32 (aborting): "3.5e38" is out of range for float32 (maximum magnitude is 3.4028234663852886e+38)`)
+			assert.EqualError(t, fatal[3], `invalid Seconds (aborting): "9223372037" seconds is out of range for time.Duration (must be between -9223372036 and 9223372036)`)
+		}
+	})
+}
+
+func TestCommaSplitInt(t *testing.T) {
+	var config struct {
+		Retries []int `env:"RETRIES,parser=comma-split-int,default=1,2,4,8"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Env          testEnv
+		Expected     []int
+		ExpectedWarn string
+	}{
+		"set": {
+			Env:      testEnv{"RETRIES": " 1, 3 ,9"},
+			Expected: []int{1, 3, 9},
+		},
+		"unset-uses-default": {
+			Env:      testEnv{},
+			Expected: []int{1, 2, 4, 8},
+		},
+		"empty-overrides-default": {
+			Env:      testEnv{"RETRIES": ""},
+			Expected: []int{},
+		},
+		"bad-element": {
+			Env:          testEnv{"RETRIES": "1,2,four,8"},
+			Expected:     []int{1, 2, 4, 8},
+			ExpectedWarn: `invalid Retries (falling back to default "1,2,4,8"): item 2 ("four"): strconv.ParseInt: parsing "four": invalid syntax`,
+		},
+		"empty-element": {
+			Env:          testEnv{"RETRIES": "1,,8"},
+			Expected:     []int{1, 2, 4, 8},
+			ExpectedWarn: `item 1 (""): strconv.ParseInt: parsing "": invalid syntax`,
+		},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Retries = nil
+			warn, fatal := parser.ParseFromEnv(&config, tc.Env.lookup)
+			assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+			if tc.ExpectedWarn != "" {
+				if assert.Equal(t, len(warn), 1, "There should be 1 warning") {
+					assert.Contains(t, warn[0].Error(), tc.ExpectedWarn)
+				}
+			} else {
+				assert.Equal(t, len(warn), 0, "There should be no warnings")
+			}
+			assert.Equal(t, tc.Expected, config.Retries)
+		})
+	}
+
+	t.Run("bad-element-without-default", func(t *testing.T) {
+		var config struct {
+			Retries []int `env:"RETRIES,parser=comma-split-int"`
+		}
+		parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, fatal := parser.ParseFromEnv(&config, testEnv{"RETRIES": "1,2,x"}.lookup)
+		if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+			assert.Contains(t, fatal[0].Error(), `item 2 ("x")`)
+		}
+	})
+}
+
+func TestSmallIntegers(t *testing.T) {
+	var config struct {
+		Int32 int32 `env:"INT32 ,parser=strconv.ParseInt "`
+		Int16 int16 `env:"INT16 ,parser=strconv.ParseInt "`
+		Int8  int8  `env:"INT8  ,parser=strconv.ParseInt ,default=1 "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("in-range", func(t *testing.T) {
+		env := testEnv{"INT32": "-2147483648", "INT16": "32767", "INT8": "100"}
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		assert.Equal(t, int32(math.MinInt32), config.Int32)
+		assert.Equal(t, int16(math.MaxInt16), config.Int16)
+		assert.Equal(t, int8(100), config.Int8)
+	})
+
+	t.Run("out-of-range", func(t *testing.T) {
+		env := testEnv{"INT32": "2147483648", "INT16": "-32769", "INT8": "200"}
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		if assert.Equal(t, len(warn), 1, "There should be 1 warning") {
+			assert.EqualError(t, warn[0], `invalid Int8 (falling back to default "1"): "200" is out of range for an 8-bit integer (must be between -128 and 127)`)
+		}
+		if assert.Equal(t, len(fatal), 2, "There should be 2 fatal errors") {
+			assert.EqualError(t, fatal[0], `invalid Int32 (aborting): "2147483648" is out of range for a 32-bit integer (must be between -2147483648 and 2147483647)`)
+			assert.EqualError(t, fatal[1], `invalid Int16 (aborting): "-32769" is out of range for a 16-bit integer (must be between -32768 and 32767)`)
+		}
+		assert.Equal(t, int8(1), config.Int8, "int8 should fall back to the default rather than truncating")
+	})
+
+	t.Run("invalid-default", func(t *testing.T) {
+		var config struct {
+			Int8 int8 `env:"INT8,parser=strconv.ParseInt,default=128"`
+		}
+		_, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestUnsignedIntegers(t *testing.T) {
+	if strconv.IntSize != 64 {
+		t.Skip("the uint boundaries in this test assume a 64-bit platform")
+	}
+	var config struct {
+		Uint   uint   `env:"UINT   ,parser=strconv.ParseUint "`
+		Uint64 uint64 `env:"UINT64 ,parser=strconv.ParseUint "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("max", func(t *testing.T) {
+		env := testEnv{"UINT": "18446744073709551615", "UINT64": "18446744073709551615"}
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		assert.Equal(t, uint(math.MaxUint64), config.Uint)
+		assert.Equal(t, uint64(math.MaxUint64), config.Uint64)
+	})
+
+	t.Run("zero", func(t *testing.T) {
+		env := testEnv{"UINT": "0", "UINT64": "0"}
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		assert.Equal(t, uint(0), config.Uint)
+		assert.Equal(t, uint64(0), config.Uint64)
+	})
+
+	t.Run("negative", func(t *testing.T) {
+		env := testEnv{"UINT": "-5", "UINT64": "-0"}
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		if assert.Equal(t, len(fatal), 2, "There should be 2 fatal errors") {
+			assert.EqualError(t, fatal[0], `invalid Uint (aborting): "-5" is negative, but must be an unsigned integer`)
+			assert.EqualError(t, fatal[1], `invalid Uint64 (aborting): "-0" is negative, but must be an unsigned integer`)
+		}
+	})
+
+	t.Run("past-boundary", func(t *testing.T) {
+		env := testEnv{"UINT": "18446744073709551616", "UINT64": "99999999999999999999"}
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		if assert.Equal(t, len(fatal), 2, "There should be 2 fatal errors") {
+			assert.EqualError(t, fatal[0], `invalid Uint (aborting): "18446744073709551616" is out of range for a 64-bit unsigned integer (maximum is 18446744073709551615)`)
+			assert.EqualError(t, fatal[1], `invalid Uint64 (aborting): "99999999999999999999" is out of range for a 64-bit unsigned integer (maximum is 18446744073709551615)`)
+		}
+	})
+}
+
+func TestNetAddr(t *testing.T) {
+	var config struct {
+		Listen net.Addr `env:"LISTEN,parser=network-addr"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input           string
+		ExpectedNetwork string
+		ExpectedString  string
+		ExpectedType    net.Addr
+		ExpectError     string
+	}{
+		"tcp-any-host": {
+			Input:           "tcp://:8080",
+			ExpectedNetwork: "tcp",
+			ExpectedString:  ":8080",
+			ExpectedType:    &net.TCPAddr{},
+		},
+		"tcp6": {
+			Input:           "tcp6://[::1]:443",
+			ExpectedNetwork: "tcp",
+			ExpectedString:  "[::1]:443",
+			ExpectedType:    &net.TCPAddr{},
+		},
+		"udp": {
+			Input:           "udp://127.0.0.1:53",
+			ExpectedNetwork: "udp",
+			ExpectedString:  "127.0.0.1:53",
+			ExpectedType:    &net.UDPAddr{},
+		},
+		"unix": {
+			Input:           "unix:///tmp/x.sock",
+			ExpectedNetwork: "unix",
+			ExpectedString:  "/tmp/x.sock",
+			ExpectedType:    &net.UnixAddr{},
+		},
+		"unix-relative": {
+			Input:           "unix://run/x.sock",
+			ExpectedNetwork: "unix",
+			ExpectedString:  "run/x.sock",
+			ExpectedType:    &net.UnixAddr{},
+		},
+		"unknown-network": {
+			Input:       "sctp://:8080",
+			ExpectError: `unknown network "sctp" in "sctp://:8080"`,
+		},
+		"no-network": {
+			Input:       ":8080",
+			ExpectError: `not a NETWORK://ADDRESS address: ":8080"`,
+		},
+		"bad-port": {
+			Input:       "tcp://:http-alt-nonexistent",
+			ExpectError: "invalid Listen (aborting)",
+		},
+		"empty-socket-path": {
+			Input:       "unix://",
+			ExpectError: `empty socket path in "unix://"`,
+		},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Listen = nil
+			warn, fatal := parser.ParseFromEnv(&config, testEnv{"LISTEN": tc.Input}.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError != "" {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.Contains(t, fatal[0].Error(), tc.ExpectError)
+				}
+				assert.Nil(t, config.Listen)
+				return
+			}
+			assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+			if assert.NotNil(t, config.Listen) {
+				assert.IsType(t, tc.ExpectedType, config.Listen)
+				assert.Equal(t, tc.ExpectedNetwork, config.Listen.Network())
+				assert.Equal(t, tc.ExpectedString, config.Listen.String())
+			}
+		})
+	}
+
+	t.Run("resolver", func(t *testing.T) {
+		var config struct {
+			Listen net.Addr `env:"LISTEN,parser=network-addr,default=tcp://fallback.example:80"`
+		}
+		parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+		if err != nil {
+			t.Fatal(err, "the default should not be looked up while generating the parser")
+		}
+		ctx := envconfig.WithResolver(context.Background(), fakeResolver{
+			"db.example":       {"10.0.0.1", "::1"},
+			"v6.example":       {"::2"},
+			"fallback.example": {"10.0.0.9"},
+		})
+
+		testcases := map[string]struct {
+			Input          string
+			ExpectedString string
+			ExpectError    string
+		}{
+			"tcp":          {Input: "tcp://db.example:5432", ExpectedString: "10.0.0.1:5432"},
+			"tcp6":         {Input: "tcp6://db.example:5432", ExpectedString: "[::1]:5432"},
+			"udp":          {Input: "udp://v6.example:53", ExpectedString: "[::2]:53"},
+			"ip":           {Input: "tcp://10.0.0.2:80", ExpectedString: "10.0.0.2:80"},
+			"empty":        {Input: "tcp://", ExpectedString: ":0"},
+			"wrong-family": {Input: "udp4://v6.example:53", ExpectError: `host "v6.example" has no udp4 address`},
+			"unresolvable": {Input: "tcp://nonexistent.example:80", ExpectError: `host "nonexistent.example" does not resolve`},
+		}
+		for name, tc := range testcases {
+			tc := tc // capture loop variable
+			t.Run(name, func(t *testing.T) {
+				config.Listen = nil
+				warn, fatal := parser.ParseFromEnvContext(ctx, &config, testEnv{"LISTEN": tc.Input}.lookup)
+				if tc.ExpectError != "" {
+					assertFellBack(t, warn, fatal, tc.ExpectError)
+					if assert.NotNil(t, config.Listen) {
+						assert.Equal(t, "10.0.0.9:80", config.Listen.String(), "the default should use the resolver too")
+					}
+					return
+				}
+				assert.Equal(t, len(warn), 0, "There should be no warnings")
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				if assert.NotNil(t, config.Listen) {
+					assert.Equal(t, tc.ExpectedString, config.Listen.String())
+				}
+			})
+		}
+	})
+}
+
+func TestFloat64(t *testing.T) {
+	var config struct {
+		Ratio      float64 `env:"RATIO      ,parser=strconv.ParseFloat            "`
+		Multiplier float64 `env:"MULTIPLIER ,parser=strconv.ParseFloat ,default=1.5 "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Env                testEnv
+		ExpectedRatio      float64
+		ExpectedMultiplier float64
+		ExpectedFatal      string
+	}{
+		"basic": {
+			Env:                testEnv{"RATIO": "0.1"},
+			ExpectedRatio:      0.1,
+			ExpectedMultiplier: 1.5,
+		},
+		"overflows-float32": {
+			Env:                testEnv{"RATIO": "3.5e38", "MULTIPLIER": "-1e300"},
+			ExpectedRatio:      3.5e38,
+			ExpectedMultiplier: -1e300,
+		},
+		"full-precision": {
+			Env:                testEnv{"RATIO": "0.1234567890123456789"},
+			ExpectedRatio:      0.1234567890123456789,
+			ExpectedMultiplier: 1.5,
+		},
+		"overflows-float64": {
+			Env:           testEnv{"RATIO": "1e309"},
+			ExpectedFatal: `invalid Ratio (aborting): "1e309" is out of range for float64 (maximum magnitude is 1.7976931348623157e+308)`,
+		},
+		"malformed": {
+			Env:           testEnv{"RATIO": "half"},
+			ExpectedFatal: `invalid Ratio (aborting): strconv.ParseFloat: parsing "half": invalid syntax`,
+		},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Ratio, config.Multiplier = 0, 0
+			warn, fatal := parser.ParseFromEnv(&config, tc.Env.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectedFatal != "" {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.EqualError(t, fatal[0], tc.ExpectedFatal)
+				}
+				return
+			}
+			assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+			assert.Equal(t, tc.ExpectedRatio, config.Ratio)
+			assert.Equal(t, tc.ExpectedMultiplier, config.Multiplier)
+		})
+	}
+}
+
+func TestBasisPoints(t *testing.T) {
+	var config struct {
+		Fee int `env:"FEE,parser=basis-points"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		Expected    int
+		ExpectError bool
+	}{
+		"1.5%":         {Input: "1.5%", Expected: 150},
+		"0.01%":        {Input: "0.01%", Expected: 1},
+		"bare":         {Input: "100", Expected: 100},
+		"whole":        {Input: "2%", Expected: 200},
+		"no-leading":   {Input: ".25%", Expected: 25},
+		"negative":     {Input: "-0.5%", Expected: -50},
+		"too-precise":  {Input: "0.001%", ExpectError: true},
+		"bare-decimal": {Input: "1.5", ExpectError: true},
+		"garbage":      {Input: "1.x%", ExpectError: true},
+		"just-percent": {Input: "%", ExpectError: true},
+		"empty":        {Input: "", ExpectError: true},
+		"out-of-range": {Input: "999999999999999999%", ExpectError: true},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Fee = 0
+			warn, fatal := parser.ParseFromEnv(&config, testEnv{"FEE": tc.Input}.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError {
+				assert.Equal(t, len(fatal), 1, "There should be 1 fatal error")
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				assert.Equal(t, tc.Expected, config.Fee)
+			}
+		})
+	}
+}
+
+func TestReader(t *testing.T) {
+	var config struct {
+		Content io.Reader `env:"CONTENT,parser=inline-or-@file"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "content.txt")
+	require.NoError(t, os.WriteFile(path, []byte("from a file\n"), 0o600))
+
+	testcases := map[string]struct {
+		Input       string
+		Expected    string
+		ExpectError bool
+	}{
+		"inline":  {Input: "inline content", Expected: "inline content"},
+		"file":    {Input: "@" + path, Expected: "from a file\n"},
+		"missing": {Input: "@" + path + ".missing", ExpectError: true},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Content = nil
+			warn, fatal := parser.ParseFromEnv(&config, testEnv{"CONTENT": tc.Input}.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError {
+				assert.Equal(t, len(fatal), 1, "There should be 1 fatal error")
+				assert.Nil(t, config.Content)
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				content, err := io.ReadAll(config.Content)
+				require.NoError(t, err)
+				assert.Equal(t, tc.Expected, string(content))
+			}
+		})
+	}
+
+	t.Run("default-file", func(t *testing.T) {
+		// The default's file is read when parsing, not when generating the parser, so it needn't exist yet.
+		path := filepath.Join(t.TempDir(), "default.txt")
+		configType := reflect.StructOf([]reflect.StructField{{
+			Name: "Content",
+			Type: reflect.TypeOf((*io.Reader)(nil)).Elem(),
+			Tag:  reflect.StructTag(`env:"CONTENT,parser=inline-or-@file,default=@` + path + `"`),
+		}})
+		parser, err := envconfig.GenerateParser(configType, nil)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, []byte("default content"), 0o600))
+
+		config := reflect.New(configType)
+		warn, fatal := parser.ParseFromEnv(config.Interface(), testEnv{}.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		content, err := io.ReadAll(config.Elem().Field(0).Interface().(io.Reader))
+		require.NoError(t, err)
+		assert.Equal(t, "default content", string(content))
+	})
+}
+
+func TestBytesAuto(t *testing.T) {
+	var config struct {
+		Value []byte `env:"VALUE,parser=bytes-auto"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input    string
+		Expected []byte
+	}{
+		"hex":               {Input: "DEADbeef", Expected: []byte{0xde, 0xad, 0xbe, 0xef}},
+		"odd-length-hex":    {Input: "abc", Expected: []byte("abc")},
+		"base64":            {Input: "aGVsbG8gd29ybGQ=", Expected: []byte("hello world")},
+		"unpadded-base64":   {Input: "aGVsbG8", Expected: []byte("aGVsbG8")},
+		"plain":             {Input: "hello, world", Expected: []byte("hello, world")},
+		"empty":             {Input: "", Expected: []byte{}},
+		"hex-before-base64": {Input: "0000", Expected: []byte{0, 0}},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Value = nil
+			env := testEnv{"VALUE": tc.Input}
+			warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			assert.Equal(t, len(fatal), 0, "There should be no errors")
+			assert.Equal(t, tc.Expected, config.Value)
+		})
+	}
+}
+
+func TestValidUTF8(t *testing.T) {
+	var config struct {
+		Name string `env:"NAME,parser=valid-utf8"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		ExpectError bool
+	}{
+		"ascii":        {Input: "cafe"},
+		"multibyte":    {Input: "caf\u00e9 \u65e5\u672c"},
+		"empty":        {Input: ""},
+		"invalid-byte": {Input: "caf\xe9", ExpectError: true},
+		"truncated":    {Input: "\xe6\x97", ExpectError: true},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Name = ""
+			warn, fatal := parser.ParseFromEnv(&config, testEnv{"NAME": tc.Input}.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.Contains(t, fatal[0].Error(), "invalid UTF-8")
+				}
+				assert.Equal(t, "", config.Name)
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				assert.Equal(t, tc.Input, config.Name)
+			}
+		})
+	}
+}
+
+func TestUnquote(t *testing.T) {
+	var config struct {
+		Str   string `env:"VALUE,parser=unquote"`
+		Bytes []byte `env:"VALUE,parser=unquote"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		Expected    string
+		ExpectError bool
+	}{
+		"escaped":    {Input: `"line1\nline2\ttab\x41\u00e9"`, Expected: "line1\nline2\ttabA\u00e9"},
+		"empty":      {Input: `""`, Expected: ""},
+		"backquoted": {Input: "`raw\\n`", Expected: `raw\n`},
+		"unquoted":   {Input: `line1\nline2`, ExpectError: true},
+		"unbalanced": {Input: `"line1`, ExpectError: true},
+		"bad-escape": {Input: `"\q"`, ExpectError: true},
+		"bare-empty": {Input: ``, ExpectError: true},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Str, config.Bytes = "", nil
+			warn, fatal := parser.ParseFromEnv(&config, testEnv{"VALUE": tc.Input}.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError {
+				assert.Equal(t, len(fatal), 2, "There should be 2 fatal errors")
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				assert.Equal(t, tc.Expected, config.Str)
+				assert.Equal(t, []byte(tc.Expected), config.Bytes)
+			}
+		})
+	}
+}
+
+func TestGzipBase64(t *testing.T) {
+	var config struct {
+		Str   string `env:"VALUE,parser=gzip-base64"`
+		Bytes []byte `env:"VALUE,parser=gzip-base64"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gzipBase64 := func(content string) string {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, _ = zw.Write([]byte(content))
+		_ = zw.Close()
+		return base64.StdEncoding.EncodeToString(buf.Bytes())
+	}
+
+	content := strings.Repeat("key=value\n", 1000)
+	warn, fatal := parser.ParseFromEnv(&config, testEnv{"VALUE": gzipBase64(content)}.lookup)
+	assert.Equal(t, len(warn), 0, "There should be no warnings")
+	assert.Equal(t, len(fatal), 0, "There should be no errors")
+	assert.Equal(t, content, config.Str)
+	assert.Equal(t, []byte(content), config.Bytes)
+
+	for name, input := range map[string]string{
+		"not-base64": "not base64!",
+		"not-gzip":   base64.StdEncoding.EncodeToString([]byte("hello")),
+		"truncated":  gzipBase64(content)[:40],
+		"empty":      "",
+	} {
+		input := input // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			_, fatal := parser.ParseFromEnv(&config, testEnv{"VALUE": input}.lookup)
+			assert.Equal(t, len(fatal), 2, "There should be 2 errors")
+		})
+	}
+}
+
+func TestDurationRange(t *testing.T) {
+	var config struct {
+		Backoff envconfig.DurationRange `env:"BACKOFF,parser=duration-range"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		Expected    envconfig.DurationRange
+		ExpectError string
+	}{
+		"valid":     {Input: "1s..5s", Expected: envconfig.DurationRange{Min: time.Second, Max: 5 * time.Second}},
+		"spaces":    {Input: "500ms .. 1m", Expected: envconfig.DurationRange{Min: 500 * time.Millisecond, Max: time.Minute}},
+		"equal":     {Input: "2s..2s", Expected: envconfig.DurationRange{Min: 2 * time.Second, Max: 2 * time.Second}},
+		"inverted":  {Input: "5s..1s", ExpectError: `invalid range "5s..1s": minimum 5s is greater than maximum 1s`},
+		"bad-min":   {Input: "1x..5s", ExpectError: `unknown unit "x" in duration "1x"`},
+		"bad-max":   {Input: "1s..", ExpectError: `invalid duration ""`},
+		"not-range": {Input: "1s", ExpectError: `not a MIN..MAX range: "1s"`},
+		"too-many":  {Input: "1s..2s..3s", ExpectError: `not a MIN..MAX range`},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Backoff = envconfig.DurationRange{}
+			warn, fatal := parser.ParseFromEnv(&config, testEnv{"BACKOFF": tc.Input}.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError != "" {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.Contains(t, fatal[0].Error(), tc.ExpectError)
+				}
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				assert.Equal(t, tc.Expected, config.Backoff)
+			}
+		})
+	}
+}
+
+func TestCommaSplitPorts(t *testing.T) {
+	var config struct {
+		Ports []uint16 `env:"PORTS,parser=comma-split-ports"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		Expected    []uint16
+		ExpectError string
+	}{
+		"valid":        {Input: "22, 80,443,0,65535", Expected: []uint16{22, 80, 443, 0, 65535}},
+		"empty":        {Input: "", Expected: []uint16{}},
+		"out-of-range": {Input: "80,65536", ExpectError: `invalid port "65536"`},
+		"negative":     {Input: "-1", ExpectError: `invalid port "-1"`},
+		"not-a-number": {Input: "80,http", ExpectError: `invalid port "http"`},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Ports = nil
+			warn, fatal := parser.ParseFromEnv(&config, testEnv{"PORTS": tc.Input}.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError != "" {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.Contains(t, fatal[0].Error(), tc.ExpectError)
+				}
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				assert.Equal(t, tc.Expected, config.Ports)
+				assert.NotNil(t, config.Ports)
+			}
+		})
+	}
+}
+
+func TestIPMask(t *testing.T) {
+	var config struct {
+		Mask net.IPMask `env:"MASK,parser=mask-or-prefix-length"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		Expected    net.IPMask
+		ExpectError string
+	}{
+		"dotted":          {Input: "255.255.255.0", Expected: net.CIDRMask(24, 32)},
+		"dotted-zero":     {Input: "0.0.0.0", Expected: net.CIDRMask(0, 32)},
+		"prefix-length":   {Input: "/24", Expected: net.CIDRMask(24, 32)},
+		"prefix-32":       {Input: "/32", Expected: net.CIDRMask(32, 32)},
+		"non-canonical":   {Input: "255.0.255.0", ExpectError: `non-canonical netmask "255.0.255.0"`},
+		"malformed":       {Input: "255.255.0", ExpectError: `invalid netmask "255.255.0"`},
+		"ipv6":            {Input: "ffff:ffff::", ExpectError: `invalid netmask "ffff:ffff::"`},
+		"prefix-too-long": {Input: "/33", ExpectError: `invalid prefix length "/33"`},
+		"prefix-garbage":  {Input: "/x", ExpectError: `invalid prefix length "/x"`},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Mask = nil
+			warn, fatal := parser.ParseFromEnv(&config, testEnv{"MASK": tc.Input}.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError != "" {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.Contains(t, fatal[0].Error(), tc.ExpectError)
+				}
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				assert.Equal(t, tc.Expected, config.Mask)
+			}
+		})
+	}
+}
+
+func TestFlagSetParser(t *testing.T) {
+	handlers := envconfig.MergeHandlers(envconfig.DefaultFieldTypeHandlers(), map[reflect.Type]envconfig.FieldTypeHandler{
+		reflect.TypeOf(int64(0)): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"perms": envconfig.FlagSetParser(map[string]int64{"read": 1, "write": 2, "exec": 4}),
+			},
+		},
+	})
+	var config struct {
+		Perms int64 `env:"PERMS,parser=perms"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), handlers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		Expected    int64
+		ExpectError string
+	}{
+		"single":   {Input: "read", Expected: 1},
+		"multiple": {Input: "read, write", Expected: 3},
+		"all":      {Input: "exec,write,read", Expected: 7},
+		"repeated": {Input: "write,write", Expected: 2},
+		"empty":    {Input: "", Expected: 0},
+		"unknown":  {Input: "read,delete", ExpectError: `unknown flag "delete": must be one of [exec read write]`},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Perms = -1
+			warn, fatal := parser.ParseFromEnv(&config, testEnv{"PERMS": tc.Input}.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError != "" {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.Contains(t, fatal[0].Error(), tc.ExpectError)
+				}
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				assert.Equal(t, tc.Expected, config.Perms)
+			}
+		})
+	}
+}
+
+func TestLogrusLevel(t *testing.T) {
+	var config struct {
+		Level logrus.Level `env:"LOG_LEVEL,parser=logrus-level"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		Expected    logrus.Level
+		ExpectError bool
+	}{
+		"WARN":    {Input: "WARN", Expected: logrus.WarnLevel},
+		"warning": {Input: "warning", Expected: logrus.WarnLevel},
+		"Debug":   {Input: " Debug ", Expected: logrus.DebugLevel},
+		"nope":    {Input: "nope", ExpectError: true},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Level = logrus.PanicLevel
+			env := testEnv{"LOG_LEVEL": tc.Input}
+			warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError {
+				assert.Equal(t, len(fatal), 1, "There should be 1 fatal error")
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				assert.Equal(t, tc.Expected, config.Level)
+			}
+		})
+	}
+}
+
+func TestCommaSplitCIDR(t *testing.T) {
+	var config struct {
+		Allow []*net.IPNet `env:"ALLOW,parser=comma-split-CIDR"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		Expected    []string
+		ExpectError string
+	}{
+		"valid": {
+			Input:    "10.1.2.3/8, 192.168.0.0/16,fd00::1/8",
+			Expected: []string{"10.0.0.0/8", "192.168.0.0/16", "fd00::/8"},
+		},
+		"empty": {
+			Input:    "",
+			Expected: []string{},
+		},
+		"bad-element": {
+			Input:       "10.0.0.0/8,10.0.0.0/33",
+			ExpectError: "10.0.0.0/33",
+		},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Allow = nil
+			env := testEnv{"ALLOW": tc.Input}
+			warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError != "" {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.Contains(t, fatal[0].Error(), tc.ExpectError)
+				}
+				assert.Nil(t, config.Allow)
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				if assert.NotNil(t, config.Allow) {
+					actual := make([]string, 0, len(config.Allow))
+					for _, ipnet := range config.Allow {
+						actual = append(actual, ipnet.String())
+					}
+					assert.Equal(t, tc.Expected, actual)
+				}
+			}
+		})
+	}
+}
+
+type subConfig struct {
+	Host string `env:"SUB_HOST ,parser=nonempty-string           "`
+	Port int    `env:"SUB_PORT ,parser=strconv.ParseInt ,default=80 "`
+}
+
+type recursiveConfig struct {
+	Name   string `env:"NAME,parser=nonempty-string"`
+	Parent *recursiveConfig
+}
+
+func TestRecursivePointer(t *testing.T) {
+	var config struct {
+		Sub *subConfig
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("populated", func(t *testing.T) {
+		config.Sub = nil
+		env := testEnv{"SUB_HOST": "example.com", "SUB_PORT": "8080"}
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no errors")
+		assert.Equal(t, &subConfig{Host: "example.com", Port: 8080}, config.Sub)
+	})
+	t.Run("partial", func(t *testing.T) {
+		config.Sub = nil
+		env := testEnv{"SUB_PORT": "8080"}
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 1, "There should be 1 error for the missing SUB_HOST")
+		assert.NotNil(t, config.Sub)
+	})
+	t.Run("absent", func(t *testing.T) {
+		config.Sub = nil
+		env := testEnv{}
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no errors")
+		assert.Nil(t, config.Sub)
+	})
+	t.Run("self-referential", func(t *testing.T) {
+		parser, err := envconfig.GenerateParser(reflect.TypeOf(recursiveConfig{}), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var config recursiveConfig
+		env := testEnv{"NAME": "name"}
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no errors")
+		assert.Equal(t, recursiveConfig{Name: "name"}, config)
+	})
+}
+
+func TestRecoverParserPanics(t *testing.T) {
+	handlers := envconfig.DefaultFieldTypeHandlers()
+	handlers[reflect.TypeOf("")].Parsers["panicky"] = func(str string) (interface{}, error) {
+		if str == "boom" {
+			panic("boom")
+		}
+		return str, nil
+	}
+	handlers[reflect.TypeOf("")].Parsers["wrong-type"] = func(str string) (interface{}, error) {
+		return len(str), nil
+	}
+	var config struct {
+		A string `env:"A ,parser=panicky                     "`
+		B string `env:"B ,parser=panicky       ,default=b    "`
+		C string `env:"C ,parser=nonempty-string              "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), handlers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := testEnv{"A": "boom", "B": "boom", "C": "c"}
+
+	t.Run("disabled", func(t *testing.T) {
+		assert.Panics(t, func() { parser.ParseFromEnv(&config, env.lookup) })
+	})
+	t.Run("enabled", func(t *testing.T) {
+		parser, err := envconfig.GenerateParserWithOptions(reflect.TypeOf(config), envconfig.GenerateOptions{
+			TypeHandlers:        handlers,
+			RecoverParserPanics: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		config.C = ""
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		if assert.Equal(t, len(fatal), 2, "There should be 2 errors") {
+			assert.Equal(t, `invalid A (aborting): parser "panicky" panicked: boom`, fatal[0].Error())
+			assert.Equal(t, `invalid B (aborting): parser "panicky" panicked: boom`, fatal[1].Error())
+		}
+		assert.Equal(t, "c", config.C, "parsing should continue after the panic")
+	})
+	t.Run("wrong-type", func(t *testing.T) {
+		var config struct {
+			Value string `env:"VALUE,parser=wrong-type"`
+		}
+		parser, err := envconfig.GenerateParserWithOptions(reflect.TypeOf(config), envconfig.GenerateOptions{
+			TypeHandlers:        handlers,
+			RecoverParserPanics: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Panics(t, func() { parser.ParseFromEnv(&config, testEnv{"VALUE": "x"}.lookup) },
+			"a parser returning the wrong type is a bug that should not be recovered from")
+	})
+}
+
+func TestNewlineSplitTrim(t *testing.T) {
+	var config struct {
+		Lines    []string `env:"LINES ,parser=newline-split-trim                "`
+		NonEmpty []string `env:"LINES ,parser=newline-split-trim ,dropEmpty=true "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input            string
+		ExpectedLines    []string
+		ExpectedNonEmpty []string
+	}{
+		"lf": {
+			Input:            "ssh-ed25519 AAAA one\n  ssh-rsa BBBB two  \n",
+			ExpectedLines:    []string{"ssh-ed25519 AAAA one", "ssh-rsa BBBB two"},
+			ExpectedNonEmpty: []string{"ssh-ed25519 AAAA one", "ssh-rsa BBBB two"},
+		},
+		"crlf": {
+			Input:            "one\r\ntwo\r\n",
+			ExpectedLines:    []string{"one", "two"},
+			ExpectedNonEmpty: []string{"one", "two"},
+		},
+		"no-trailing-newline": {
+			Input:            "one\ntwo",
+			ExpectedLines:    []string{"one", "two"},
+			ExpectedNonEmpty: []string{"one", "two"},
+		},
+		"blank-lines": {
+			Input:            "\none\n\n  \ntwo\n\n",
+			ExpectedLines:    []string{"", "one", "", "", "two", ""},
+			ExpectedNonEmpty: []string{"one", "two"},
+		},
+		"empty": {
+			Input:            "",
+			ExpectedLines:    []string{},
+			ExpectedNonEmpty: []string{},
+		},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			env := testEnv{"LINES": tc.Input}
+			warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			assert.Equal(t, len(fatal), 0, "There should be no errors")
+			assert.Equal(t, tc.ExpectedLines, config.Lines)
+			assert.Equal(t, tc.ExpectedNonEmpty, config.NonEmpty)
+		})
+	}
+
+	t.Run("invalid-option", func(t *testing.T) {
+		var config struct {
+			Value string `env:"VALUE,parser=nonempty-string,dropEmpty=true"`
+		}
+		_, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestParseFromEnvThen(t *testing.T) {
+	type config struct {
+		Start int `env:"START ,parser=strconv.ParseInt "`
+		End   int `env:"END   ,parser=strconv.ParseInt "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config{}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	validate := func(structPtr interface{}) []error {
+		cfg := structPtr.(*config)
+		if cfg.Start >= cfg.End {
+			return []error{fmt.Errorf("START (%d) must be less than END (%d)", cfg.Start, cfg.End)}
+		}
+		return nil
+	}
+
+	testcases := map[string]struct {
+		Env         testEnv
+		ExpectError string
+	}{
+		"valid": {
+			Env: testEnv{"START": "1", "END": "2"},
+		},
+		"invalid": {
+			Env:         testEnv{"START": "2", "END": "2"},
+			ExpectError: "START (2) must be less than END (2)",
+		},
+		"field-error": {
+			Env:         testEnv{"START": "3"},
+			ExpectError: "invalid End (aborting): is not set",
+		},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			var cfg config
+			warn, fatal := parser.ParseFromEnvThen(&cfg, tc.Env.lookup, validate)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError != "" {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.Equal(t, tc.ExpectError, fatal[0].Error())
+				}
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+			}
+		})
+	}
+}
+
+func TestMergeNumbered(t *testing.T) {
+	var config struct {
+		Args []string `env:"EXTRA_ARGS ,parser=shell-split ,mergeNumbered=true ,default= "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := testEnv{
+		"EXTRA_ARGS":    "--base",
+		"EXTRA_ARGS_1":  "--one 1",
+		"EXTRA_ARGS_2":  "--two",
+		"EXTRA_ARGS_4":  "--four",
+		"EXTRA_ARGS_10": "--ten",
+		"EXTRA_ARGS_X":  "--not-numbered",
+		"OTHER_1":       "--other",
+	}
+	keys := func() []string {
+		ret := make([]string, 0, len(env))
+		for key := range env {
+			ret = append(ret, key)
+		}
+		return ret
+	}
+
+	t.Run("with-keys", func(t *testing.T) {
+		config.Args = nil
+		ctx := envconfig.WithEnvKeys(context.Background(), keys)
+		warn, fatal := parser.ParseFromEnvContext(ctx, &config, env.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		assert.Equal(t, []string{"--base", "--one", "1", "--two", "--four", "--ten"}, config.Args,
+			"should be in numeric order, and tolerate gaps")
+	})
+	t.Run("without-keys", func(t *testing.T) {
+		config.Args = nil
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		if assert.Equal(t, len(warn), 1, "There should be a warning about the gap") {
+			assert.EqualError(t, warn[0], "EXTRA_ARGS_4 is set, but is ignored because EXTRA_ARGS_3 is not set (use WithEnvKeys to allow gaps in the numbering)")
+		}
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		assert.Equal(t, []string{"--base", "--one", "1", "--two"}, config.Args, "should stop at the first gap")
+	})
+	t.Run("unset", func(t *testing.T) {
+		config.Args = nil
+		warn, fatal := parser.ParseFromEnv(&config, testEnv{}.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		assert.Equal(t, []string{}, config.Args)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		config.Args = nil
+		warn, fatal := parser.ParseFromEnv(&config, testEnv{"EXTRA_ARGS_1": "ok", "EXTRA_ARGS_2": `"unterminated`}.lookup)
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		if assert.Equal(t, len(warn), 1, "There should be 1 warning") {
+			assert.Contains(t, warn[0].Error(), "EXTRA_ARGS_2")
+		}
+	})
+}
+
+func TestDurationBounds(t *testing.T) {
+	var config struct {
+		Required time.Duration `env:"TIMEOUT ,parser=time.ParseDuration ,minDuration=1s ,maxDuration=1h                "`
+		Default  time.Duration `env:"TIMEOUT ,parser=time.ParseDuration ,minDuration=1s ,maxDuration=1h ,default=30s "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		Expected    time.Duration
+		ExpectError string
+	}{
+		"below-min": {Input: "500ms", ExpectError: "500ms is less than the minimum of 1s"},
+		"above-max": {Input: "1000h", ExpectError: "1000h0m0s is greater than the maximum of 1h0m0s"},
+		"in-range":  {Input: "5m", Expected: 5 * time.Minute},
+		"min":       {Input: "1s", Expected: time.Second},
+		"max":       {Input: "1h", Expected: time.Hour},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Required, config.Default = 0, 0
+			env := testEnv{"TIMEOUT": tc.Input}
+			warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+			if tc.ExpectError != "" {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.Contains(t, fatal[0].Error(), tc.ExpectError)
+				}
+				if assert.Equal(t, len(warn), 1, "There should be 1 warning") {
+					assert.Contains(t, warn[0].Error(), tc.ExpectError)
+				}
+				assert.Equal(t, 30*time.Second, config.Default, "should fall back to the default")
+			} else {
+				assert.Equal(t, len(warn), 0, "There should be no warnings")
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				assert.Equal(t, tc.Expected, config.Required)
+				assert.Equal(t, tc.Expected, config.Default)
+			}
+		})
+	}
+
+	t.Run("invalid-option", func(t *testing.T) {
+		for _, field := range []reflect.StructField{
+			{Type: reflect.TypeOf(time.Duration(0)), Tag: `env:"VALUE,parser=time.ParseDuration,minDuration=1"`},
+			{Type: reflect.TypeOf(time.Duration(0)), Tag: `env:"VALUE,parser=time.ParseDuration,minDuration=2s,maxDuration=1s"`},
+			{Type: reflect.TypeOf(time.Duration(0)), Tag: `env:"VALUE,parser=time.ParseDuration,maxDuration=1s,default=2s"`},
+			{Type: reflect.TypeOf(0), Tag: `env:"VALUE,parser=strconv.ParseInt,maxDuration=1s"`},
+		} {
+			field.Name = "Value"
+			_, err := envconfig.GenerateParser(reflect.StructOf([]reflect.StructField{field}), nil)
+			assert.Errorf(t, err, "tag %s should be rejected", field.Tag)
+		}
+	})
+}
+
+func TestCollapseSeparators(t *testing.T) {
+	var config struct {
+		Default  []string `env:"PATHS ,parser=filepath.SplitList                          "`
+		Collapse []string `env:"PATHS ,parser=filepath.SplitList ,collapseSeparators=true "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sep := string(os.PathListSeparator)
+
+	testcases := map[string]struct {
+		Input            string
+		ExpectedDefault  []string
+		ExpectedCollapse []string
+	}{
+		"collapse": {
+			Input:            "a" + sep + sep + "b" + sep + sep + sep + "c",
+			ExpectedDefault:  []string{"a", "", "b", "", "", "c"},
+			ExpectedCollapse: []string{"a", "b", "c"},
+		},
+		"leading-and-trailing": {
+			Input:            sep + sep + "a" + sep + sep,
+			ExpectedDefault:  []string{"", "", "a", "", ""},
+			ExpectedCollapse: []string{"", "a", ""},
+		},
+		"empty": {
+			Input:            "",
+			ExpectedDefault:  []string{},
+			ExpectedCollapse: []string{},
+		},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			warn, fatal := parser.ParseFromEnv(&config, testEnv{"PATHS": tc.Input}.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+			assert.Equal(t, tc.ExpectedDefault, config.Default)
+			assert.Equal(t, tc.ExpectedCollapse, config.Collapse)
+		})
+	}
+}
+
+func TestEmptyKeepsDefault(t *testing.T) {
+	var config struct {
+		Empties []string `env:"HOSTS ,parser=comma-split-trim                         ,default=a,b "`
+		Keeps   []string `env:"HOSTS ,parser=comma-split-trim ,emptyKeepsDefault=true ,default=a,b "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Env             testEnv
+		ExpectedEmpties []string
+		ExpectedKeeps   []string
+	}{
+		"unset": {Env: testEnv{}, ExpectedEmpties: []string{"a", "b"}, ExpectedKeeps: []string{"a", "b"}},
+		"empty": {Env: testEnv{"HOSTS": ""}, ExpectedEmpties: []string{}, ExpectedKeeps: []string{"a", "b"}},
+		"set":   {Env: testEnv{"HOSTS": "c"}, ExpectedEmpties: []string{"c"}, ExpectedKeeps: []string{"c"}},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			warn, fatal := parser.ParseFromEnv(&config, tc.Env.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+			assert.Equal(t, tc.ExpectedEmpties, config.Empties)
+			assert.Equal(t, tc.ExpectedKeeps, config.Keeps)
+		})
+	}
+
+	t.Run("invalid-option", func(t *testing.T) {
+		for _, field := range []reflect.StructField{
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=possibly-empty-string,emptyKeepsDefault=true,default=x"`},
+			{Type: reflect.TypeOf([]string{}), Tag: `env:"VALUE,parser=comma-split-trim,emptyKeepsDefault=true"`},
+		} {
+			field.Name = "Value"
+			_, err := envconfig.GenerateParser(reflect.StructOf([]reflect.StructField{field}), nil)
+			assert.Errorf(t, err, "tag %s should be rejected", field.Tag)
+		}
+	})
+}
+
+func TestNullValue(t *testing.T) {
+	var config struct {
+		Foo      string `env:"FOO      ,parser=nonempty-string  ,nullValue=none|-  ,default=dflt "`
+		Required string `env:"REQUIRED ,parser=nonempty-string  ,nullValue=none             "`
+		Plain    string `env:"FOO      ,parser=nonempty-string                    ,default=dflt "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Env             testEnv
+		ExpectedFoo     string
+		ExpectedPlain   string
+		ExpectedMissing []string
+		ExpectedFatal   int
+	}{
+		"none":  {Env: testEnv{"FOO": "none", "REQUIRED": "x"}, ExpectedFoo: "dflt", ExpectedPlain: "none"},
+		"dash":  {Env: testEnv{"FOO": "-", "REQUIRED": "x"}, ExpectedFoo: "dflt", ExpectedPlain: "-"},
+		"set":   {Env: testEnv{"FOO": "bar", "REQUIRED": "x"}, ExpectedFoo: "bar", ExpectedPlain: "bar"},
+		"case":  {Env: testEnv{"FOO": "NONE", "REQUIRED": "x"}, ExpectedFoo: "NONE", ExpectedPlain: "NONE"},
+		"unset": {Env: testEnv{"REQUIRED": "x"}, ExpectedFoo: "dflt", ExpectedPlain: "dflt"},
+		"required": {
+			Env:             testEnv{"FOO": "bar", "REQUIRED": "none"},
+			ExpectedFoo:     "bar",
+			ExpectedPlain:   "bar",
+			ExpectedMissing: []string{"REQUIRED"},
+			ExpectedFatal:   1,
+		},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			warn, fatal := parser.ParseFromEnv(&config, tc.Env.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			assert.Equal(t, tc.ExpectedFatal, len(fatal))
+			assert.Equal(t, tc.ExpectedFoo, config.Foo)
+			assert.Equal(t, tc.ExpectedPlain, config.Plain)
+			assert.Equal(t, tc.ExpectedMissing, parser.CheckRequired(tc.Env.lookup))
+		})
+	}
+
+	t.Run("invalid-option", func(t *testing.T) {
+		field := reflect.StructField{Name: "Value", Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=nonempty-string,nullValue="`}
+		_, err := envconfig.GenerateParser(reflect.StructOf([]reflect.StructField{field}), nil)
+		assert.Errorf(t, err, "tag %s should be rejected", field.Tag)
+	})
+}
+
+func TestIndirect(t *testing.T) {
+	var config struct {
+		DSN  string `env:"DSN  ,parser=nonempty-string   ,indirect=true                "`
+		Port int    `env:"PORT ,parser=strconv.ParseInt  ,indirect=true ,default=80    "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Env           testEnv
+		ExpectedDSN   string
+		ExpectedPort  int
+		ExpectedWarn  int
+		ExpectedFatal string
+	}{
+		"resolved": {
+			Env:          testEnv{"DSN": "LEGACY_DSN", "LEGACY_DSN": "postgres://db", "PORT": "LEGACY_PORT", "LEGACY_PORT": "5432"},
+			ExpectedDSN:  "postgres://db",
+			ExpectedPort: 5432,
+		},
+		"unset": {
+			Env:           testEnv{"LEGACY_DSN": "postgres://db"},
+			ExpectedPort:  80,
+			ExpectedFatal: "invalid DSN (aborting): is not set",
+		},
+		"missing-target": {
+			Env:           testEnv{"DSN": "LEGACY_DSN"},
+			ExpectedPort:  80,
+			ExpectedFatal: `invalid DSN (aborting): DSN=LEGACY_DSN refers to env-var "LEGACY_DSN", which is not set`,
+		},
+		"invalid-target-value": {
+			Env:          testEnv{"DSN": "LEGACY_DSN", "LEGACY_DSN": "postgres://db", "PORT": "LEGACY_PORT", "LEGACY_PORT": "http"},
+			ExpectedDSN:  "postgres://db",
+			ExpectedPort: 80,
+			ExpectedWarn: 1,
+		},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.DSN, config.Port = "", 0
+			warn, fatal := parser.ParseFromEnv(&config, tc.Env.lookup)
+			assert.Equal(t, tc.ExpectedWarn, len(warn))
+			if tc.ExpectedFatal != "" {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.EqualError(t, fatal[0], tc.ExpectedFatal)
+				}
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+			}
+			assert.Equal(t, tc.ExpectedDSN, config.DSN)
+			assert.Equal(t, tc.ExpectedPort, config.Port)
+		})
+	}
+
+	t.Run("invalid-option", func(t *testing.T) {
+		for _, field := range []reflect.StructField{
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=nonempty-string,indirect=maybe"`},
+			{Type: reflect.TypeOf([]string{}), Tag: `env:"VALUE,parser=shell-split,indirect=true,mergeNumbered=true"`},
+		} {
+			field.Name = "Value"
+			_, err := envconfig.GenerateParser(reflect.StructOf([]reflect.StructField{field}), nil)
+			assert.Errorf(t, err, "tag %s should be rejected", field.Tag)
+		}
+	})
+}
+
+func TestDeprecated(t *testing.T) {
+	var config struct {
+		Old    string `env:"OLD    ,parser=possibly-empty-string ,deprecated=true                ,default=  "`
+		Legacy int    `env:"LEGACY ,parser=strconv.ParseInt      ,deprecated=true ,removeIn=v2.0 ,default=1 "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Env            testEnv
+		ExpectedOld    string
+		ExpectedLegacy int
+		ExpectedWarn   []string
+	}{
+		"unset": {
+			Env:            testEnv{},
+			ExpectedLegacy: 1,
+		},
+		"set": {
+			Env:            testEnv{"OLD": "x", "LEGACY": "2"},
+			ExpectedOld:    "x",
+			ExpectedLegacy: 2,
+			ExpectedWarn:   []string{"OLD is deprecated", "LEGACY is deprecated, will be removed in v2.0"},
+		},
+		"set-invalid": {
+			Env:            testEnv{"LEGACY": "two"},
+			ExpectedLegacy: 1,
+			ExpectedWarn:   []string{"LEGACY is deprecated, will be removed in v2.0", `invalid Legacy (falling back to default "1")`},
+		},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Old, config.Legacy = "", 0
+			warn, fatal := parser.ParseFromEnv(&config, tc.Env.lookup)
+			assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+			if assert.Equal(t, len(tc.ExpectedWarn), len(warn)) {
+				for i := range warn {
+					assert.Contains(t, warn[i].Error(), tc.ExpectedWarn[i])
+				}
+			}
+			assert.Equal(t, tc.ExpectedOld, config.Old)
+			assert.Equal(t, tc.ExpectedLegacy, config.Legacy)
+		})
+	}
+
+	t.Run("invalid-option", func(t *testing.T) {
+		for _, field := range []reflect.StructField{
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=nonempty-string,deprecated=maybe"`},
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=nonempty-string,removeIn=v2.0"`},
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=nonempty-string,deprecated=false,removeIn=v2.0"`},
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=nonempty-string,deprecated=true,removeIn="`},
+		} {
+			field.Name = "Value"
+			_, err := envconfig.GenerateParser(reflect.StructOf([]reflect.StructField{field}), nil)
+			assert.Errorf(t, err, "tag %s should be rejected", field.Tag)
+		}
+	})
+}
+
+func TestSHA256(t *testing.T) {
+	var config struct {
+		Token string `env:"TOKEN ,parser=nonempty-string ,sha256=TOKEN_SHA256 ,default=anonymous "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := func(str string) string {
+		sum := sha256.Sum256([]byte(str))
+		return hex.EncodeToString(sum[:])
+	}
+
+	testcases := map[string]struct {
+		Env         testEnv
+		Expected    string
+		ExpectError string
+	}{
+		"match": {
+			Env:      testEnv{"TOKEN": "hunter2", "TOKEN_SHA256": digest("hunter2")},
+			Expected: "hunter2",
+		},
+		"match-uppercase": {
+			Env:      testEnv{"TOKEN": "hunter2", "TOKEN_SHA256": strings.ToUpper(digest("hunter2"))},
+			Expected: "hunter2",
+		},
+		"unset-uses-default": {
+			Env:      testEnv{},
+			Expected: "anonymous",
+		},
+		"mismatch": {
+			Env:         testEnv{"TOKEN": "hunter3", "TOKEN_SHA256": digest("hunter2")},
+			ExpectError: "invalid Token (aborting): sha256 digest does not match TOKEN_SHA256",
+		},
+		"digest-unset": {
+			Env:         testEnv{"TOKEN": "hunter2"},
+			ExpectError: "invalid Token (aborting): TOKEN_SHA256, which has the expected sha256 digest, is not set",
+		},
+		"digest-malformed": {
+			Env:         testEnv{"TOKEN": "hunter2", "TOKEN_SHA256": "abc123"},
+			ExpectError: `invalid Token (aborting): TOKEN_SHA256="abc123" is not a hex-encoded sha256 digest`,
+		},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Token = ""
+			warn, fatal := parser.ParseFromEnv(&config, tc.Env.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError != "" {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.EqualError(t, fatal[0], tc.ExpectError)
+				}
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				assert.Equal(t, tc.Expected, config.Token)
+			}
+		})
+	}
+
+	t.Run("fromFile", func(t *testing.T) {
+		var config struct {
+			Token string `env:"TOKEN ,parser=nonempty-string ,fromFile=true ,sha256=TOKEN_SHA256"`
+		}
+		parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(t.TempDir(), "token")
+		if err := os.WriteFile(path, []byte("hunter2\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		warn, fatal := parser.ParseFromEnv(&config, testEnv{"TOKEN_FILE": path, "TOKEN_SHA256": digest("hunter2")}.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		assert.Equal(t, "hunter2", config.Token)
+	})
+
+	t.Run("expand", func(t *testing.T) {
+		// The digest pins the value as it was set, not what it expands to; so changing an env-var that
+		// it refers to doesn't invalidate the digest.
+		var config struct {
+			URL string `env:"URL,parser=nonempty-string,expand=true,sha256=URL_SHA256"`
+		}
+		parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		env := testEnv{"URL": "https://${HOST}/", "HOST": "example.com", "URL_SHA256": digest("https://${HOST}/")}
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		assert.Equal(t, "https://example.com/", config.URL)
+
+		env["URL_SHA256"] = digest("https://example.com/")
+		_, fatal = parser.ParseFromEnv(&config, env.lookup)
+		if assert.Equal(t, len(fatal), 1, "The digest of the expanded value should not match") {
+			assert.EqualError(t, fatal[0], "invalid URL (aborting): sha256 digest does not match URL_SHA256")
+		}
+	})
+
+	t.Run("invalid-option", func(t *testing.T) {
+		for _, field := range []reflect.StructField{
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=nonempty-string,sha256="`},
+			{Type: reflect.TypeOf([]string{}), Tag: `env:"VALUE,parser=comma-split-trim,mergeNumbered=true,sha256=VALUE_SHA256"`},
+		} {
+			field.Name = "Value"
+			_, err := envconfig.GenerateParser(reflect.StructOf([]reflect.StructField{field}), nil)
+			assert.Errorf(t, err, "tag %s should be rejected", field.Tag)
+		}
+	})
+}
+
+func TestElemTrim(t *testing.T) {
+	var config struct {
+		Hosts []string `env:"HOSTS ,parser=comma-split-trim ,elemTrimPrefix=https:// ,elemTrimSuffix=/ "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := testEnv{"HOSTS": "https://a.example.com/, b.example.com, https://c.example.com//, http://d.example.com/"}
+	warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+	assert.Equal(t, len(warn), 0, "There should be no warnings")
+	assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+	assert.Equal(t, []string{"a.example.com", "b.example.com", "c.example.com/", "http://d.example.com"}, config.Hosts)
+
+	t.Run("invalid-option", func(t *testing.T) {
+		for _, field := range []reflect.StructField{
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=nonempty-string,elemTrimPrefix=https://"`},
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=nonempty-string,elemTrimSuffix=/"`},
+		} {
+			field.Name = "Value"
+			_, err := envconfig.GenerateParser(reflect.StructOf([]reflect.StructField{field}), nil)
+			assert.Errorf(t, err, "tag %s should be rejected", field.Tag)
+		}
+	})
+}
+
+func TestOutputCase(t *testing.T) {
+	var config struct {
+		Lower     string `env:"ID ,parser=nonempty-string ,outputCase=lower "`
+		Upper     string `env:"ID ,parser=nonempty-string ,outputCase=upper "`
+		Unchanged string `env:"ID ,parser=nonempty-string                   "`
+		Default   string `env:"UNSET ,parser=nonempty-string ,outputCase=lower ,default=Mixed-Case "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	warn, fatal := parser.ParseFromEnv(&config, testEnv{"ID": "Us-East-1"}.lookup)
+	assert.Equal(t, len(warn), 0, "There should be no warnings")
+	assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+	assert.Equal(t, "us-east-1", config.Lower)
+	assert.Equal(t, "US-EAST-1", config.Upper)
+	assert.Equal(t, "Us-East-1", config.Unchanged)
+	assert.Equal(t, "mixed-case", config.Default)
+
+	t.Run("invalid-option", func(t *testing.T) {
+		for _, field := range []reflect.StructField{
+			{Type: reflect.TypeOf(0), Tag: `env:"VALUE,parser=strconv.ParseInt,outputCase=lower"`},
+			{Type: reflect.TypeOf([]string{}), Tag: `env:"VALUE,parser=comma-split-trim,outputCase=upper"`},
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=nonempty-string,outputCase=title"`},
+		} {
+			field.Name = "Value"
+			_, err := envconfig.GenerateParser(reflect.StructOf([]reflect.StructField{field}), nil)
+			assert.Errorf(t, err, "tag %s should be rejected", field.Tag)
+		}
+	})
+}
+
+func TestAbsPath(t *testing.T) {
+	var config struct {
+		Path string `env:"DATA_DIR ,parser=possibly-empty-string ,absPath=true "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+	abs, err := filepath.Abs(filepath.FromSlash("/srv/data"))
+	require.NoError(t, err)
+
+	testcases := map[string]struct {
+		Input    string
+		Expected string
+	}{
+		"relative":     {Input: filepath.FromSlash("data/cache"), Expected: filepath.Join(cwd, "data", "cache")},
+		"dot-relative": {Input: filepath.FromSlash("./data/../cache"), Expected: filepath.Join(cwd, "cache")},
+		"absolute":     {Input: abs, Expected: abs},
+		"empty":        {Input: "", Expected: ""},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			warn, fatal := parser.ParseFromEnv(&config, testEnv{"DATA_DIR": tc.Input}.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+			assert.Equal(t, tc.Expected, config.Path)
+		})
+	}
+
+	t.Run("invalid-option", func(t *testing.T) {
+		for _, field := range []reflect.StructField{
+			{Type: reflect.TypeOf([]string{}), Tag: `env:"VALUE,parser=comma-split-trim,absPath=true"`},
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=nonempty-string,absPath=sure"`},
+		} {
+			field.Name = "Value"
+			_, err := envconfig.GenerateParser(reflect.StructOf([]reflect.StructField{field}), nil)
+			assert.Errorf(t, err, "tag %s should be rejected", field.Tag)
+		}
+	})
+}
+
+func TestSort(t *testing.T) {
+	var config struct {
+		Hosts []string `env:"HOSTS ,parser=comma-split-trim ,dropEmpty=true ,elemTrimPrefix=https:// ,sort=asc "`
+		Ports []uint16 `env:"PORTS ,parser=comma-split-ports ,sort=desc "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := testEnv{
+		"HOSTS": "https://c.example.com, a.example.com,, https://b.example.com, a.example.com",
+		"PORTS": "80,8080,443,9",
+	}
+	warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+	assert.Equal(t, len(warn), 0, "There should be no warnings")
+	assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+	assert.Equal(t, []string{"a.example.com", "a.example.com", "b.example.com", "c.example.com"}, config.Hosts)
+	assert.Equal(t, []uint16{8080, 443, 80, 9}, config.Ports)
+
+	t.Run("invalid-option", func(t *testing.T) {
+		for _, field := range []reflect.StructField{
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=nonempty-string,sort=asc"`},
+			{Type: reflect.TypeOf([]string{}), Tag: `env:"VALUE,parser=comma-split-trim,sort=up"`},
+			{Type: reflect.TypeOf([]envconfig.KeyValue{}), Tag: `env:"VALUE,parser=comma-split-kv,sort=asc"`},
+		} {
+			field.Name = "Value"
+			_, err := envconfig.GenerateParser(reflect.StructOf([]reflect.StructField{field}), nil)
+			assert.Errorf(t, err, "tag %s should be rejected", field.Tag)
+		}
+	})
+}
+
+func TestUniqueItems(t *testing.T) {
+	var config struct {
+		Hosts []string `env:"HOSTS ,parser=comma-split-trim ,elemTrimPrefix=https:// ,uniqueItems=true "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		Expected    []string
+		ExpectError string
+	}{
+		"unique":  {Input: "a.example.com, b.example.com", Expected: []string{"a.example.com", "b.example.com"}},
+		"empty":   {Input: "", Expected: []string{}},
+		"repeat":  {Input: "a.example.com, b.example.com, a.example.com", ExpectError: `duplicate item "a.example.com"`},
+		"trimmed": {Input: "a.example.com, https://a.example.com", ExpectError: `duplicate item "a.example.com"`},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Hosts = nil
+			warn, fatal := parser.ParseFromEnv(&config, testEnv{"HOSTS": tc.Input}.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError != "" {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.Contains(t, fatal[0].Error(), tc.ExpectError)
+				}
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				assert.Equal(t, tc.Expected, config.Hosts)
+			}
+		})
+	}
+
+	t.Run("with-sort", func(t *testing.T) {
+		// The list is sorted before it is checked, so the repeat that is reported is the first one in
+		// sorted order, not in the env-var's order.
+		var sorted struct {
+			Hosts []string `env:"HOSTS,parser=comma-split-trim,uniqueItems=true,sort=desc"`
+		}
+		parser, err := envconfig.GenerateParser(reflect.TypeOf(sorted), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		warn, fatal := parser.ParseFromEnv(&sorted, testEnv{"HOSTS": "b, c, a"}.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		assert.Equal(t, []string{"c", "b", "a"}, sorted.Hosts)
+
+		warn, fatal = parser.ParseFromEnv(&sorted, testEnv{"HOSTS": "a, b, a, c, c"}.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+			assert.Contains(t, fatal[0].Error(), `duplicate item "c"`)
+		}
+	})
+
+	t.Run("invalid-option", func(t *testing.T) {
+		for _, field := range []reflect.StructField{
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=nonempty-string,uniqueItems=true"`},
+			{Type: reflect.TypeOf([]string{}), Tag: `env:"VALUE,parser=comma-split-trim,uniqueItems=yes-please"`},
+			{Type: reflect.TypeOf([]uint16{}), Tag: `env:"VALUE,parser=comma-split-ports,uniqueItems=true,default=80,80"`},
+		} {
+			field.Name = "Value"
+			_, err := envconfig.GenerateParser(reflect.StructOf([]reflect.StructField{field}), nil)
+			assert.Errorf(t, err, "tag %s should be rejected", field.Tag)
+		}
+	})
+}
+
+func TestStrictlyIncreasing(t *testing.T) {
+	var config struct {
+		Buckets []uint16 `env:"BUCKETS ,parser=comma-split-ports ,strictlyIncreasing=true "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		Expected    []uint16
+		ExpectError string
+	}{
+		"increasing":     {Input: "1, 5, 10, 100", Expected: []uint16{1, 5, 10, 100}},
+		"single":         {Input: "7", Expected: []uint16{7}},
+		"empty":          {Input: "", Expected: []uint16{}},
+		"equal-adjacent": {Input: "1, 5, 5, 10", ExpectError: "item 2 (5) is not greater than item 1 (5)"},
+		"decreasing":     {Input: "100, 10, 1", ExpectError: "item 1 (10) is not greater than item 0 (100)"},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Buckets = nil
+			warn, fatal := parser.ParseFromEnv(&config, testEnv{"BUCKETS": tc.Input}.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError != "" {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.Contains(t, fatal[0].Error(), tc.ExpectError)
+				}
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				assert.Equal(t, tc.Expected, config.Buckets)
+			}
+		})
+	}
+
+	t.Run("with-sort", func(t *testing.T) {
+		// The list is sorted before it is checked, so only duplicates are rejected.
+		var sorted struct {
+			Buckets []uint16 `env:"BUCKETS,parser=comma-split-ports,sort=asc,strictlyIncreasing=true"`
+		}
+		parser, err := envconfig.GenerateParser(reflect.TypeOf(sorted), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		warn, fatal := parser.ParseFromEnv(&sorted, testEnv{"BUCKETS": "100, 1, 10"}.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		assert.Equal(t, []uint16{1, 10, 100}, sorted.Buckets)
+
+		warn, fatal = parser.ParseFromEnv(&sorted, testEnv{"BUCKETS": "10, 1, 10"}.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+			assert.Contains(t, fatal[0].Error(), "item 2 (10) is not greater than item 1 (10)")
+		}
+	})
+
+	t.Run("invalid-option", func(t *testing.T) {
+		for _, field := range []reflect.StructField{
+			{Type: reflect.TypeOf([]string{}), Tag: `env:"VALUE,parser=comma-split-trim,strictlyIncreasing=true"`},
+			{Type: reflect.TypeOf(0), Tag: `env:"VALUE,parser=strconv.ParseInt,strictlyIncreasing=true"`},
+			{Type: reflect.TypeOf([]uint16{}), Tag: `env:"VALUE,parser=comma-split-ports,strictlyIncreasing=true,default=2,1"`},
+		} {
+			field.Name = "Value"
+			_, err := envconfig.GenerateParser(reflect.StructOf([]reflect.StructField{field}), nil)
+			assert.Errorf(t, err, "tag %s should be rejected", field.Tag)
+		}
+	})
+}
+
+func TestJSONMap(t *testing.T) {
+	var config struct {
+		Labels map[string]string `env:"LABELS,parser=json"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		Expected    map[string]string
+		ExpectError bool
+	}{
+		"object":     {Input: `{"team": "infra", "tier": "a=b,c"}`, Expected: map[string]string{"team": "infra", "tier": "a=b,c"}},
+		"empty":      {Input: `{}`, Expected: map[string]string{}},
+		"null":       {Input: `null`, Expected: map[string]string{}},
+		"array":      {Input: `["team", "infra"]`, ExpectError: true},
+		"malformed":  {Input: `{"team": `, ExpectError: true},
+		"non-string": {Input: `{"replicas": 3}`, ExpectError: true},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Labels = nil
+			env := testEnv{"LABELS": tc.Input}
+			warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError {
+				assert.Equal(t, len(fatal), 1, "There should be 1 fatal error")
+				assert.Nil(t, config.Labels)
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				assert.Equal(t, tc.Expected, config.Labels)
+			}
+		})
+	}
+}
+
+func TestShellSplit(t *testing.T) {
+	var config struct {
+		Value []string `env:"VALUE,parser=shell-split"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		Expected    []string
+		ExpectError string
+	}{
+		"commas":              {Input: `A,B ,  C`, Expected: []string{"A", "B", "C"}},
+		"whitespace":          {Input: "A B\tC\n", Expected: []string{"A", "B", "C"}},
+		"double-quoted-comma": {Input: `A,"B,C",D`, Expected: []string{"A", "B,C", "D"}},
+		"single-quoted-space": {Input: `A 'B C' D`, Expected: []string{"A", "B C", "D"}},
+		"adjacent-quotes":     {Input: `a"b c"'d e'f`, Expected: []string{"ab cd ef"}},
+		"escaped-comma":       {Input: `A\,B,C`, Expected: []string{"A,B", "C"}},
+		"escape-in-double":    {Input: `"say \"hi\" \\ \n"`, Expected: []string{`say "hi" \ \n`}},
+		"no-escape-in-single": {Input: `'\"'`, Expected: []string{`\"`}},
+		"quoted-empty":        {Input: `A,"",B`, Expected: []string{"A", "", "B"}},
+		"empty":               {Input: ``, Expected: []string{}},
+		"unterminated-double": {Input: `A,"B`, ExpectError: "unterminated double quote"},
+		"unterminated-single": {Input: `A,'B`, ExpectError: "unterminated single quote"},
+		"trailing-backslash":  {Input: `A\`, ExpectError: "trailing backslash"},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Value = nil
+			env := testEnv{"VALUE": tc.Input}
+			warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError != "" {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.Contains(t, fatal[0].Error(), tc.ExpectError)
+				}
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				assert.Equal(t, tc.Expected, config.Value)
+			}
+		})
+	}
+}
+
+func TestDurationOrNever(t *testing.T) {
+	var config struct {
+		TTL time.Duration `env:"TTL,parser=duration-or-never"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		Expected    time.Duration
+		ExpectError bool
+	}{
+		"never":    {Input: "never", Expected: envconfig.DurationNever},
+		"Infinite": {Input: "Infinite", Expected: envconfig.DurationNever},
+		"5m":       {Input: "5m", Expected: 5 * time.Minute},
+		"0":        {Input: "0", Expected: envconfig.DurationNever},
+		"0s":       {Input: "0s", Expected: envconfig.DurationNever},
+		"invalid":  {Input: "forever", ExpectError: true},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.TTL = 0
+			env := testEnv{"TTL": tc.Input}
+			warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError {
+				assert.Equal(t, len(fatal), 1, "There should be 1 fatal error")
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				assert.Equal(t, tc.Expected, config.TTL)
+			}
+		})
+	}
+}
+
+func TestMergeHandlers(t *testing.T) {
+	type celsius float64
+	base := envconfig.DefaultFieldTypeHandlers()
+	overrides := map[reflect.Type]envconfig.FieldTypeHandler{
+		// A new type
+		reflect.TypeOf(celsius(0)): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"strconv.ParseFloat": func(str string) (interface{}, error) {
+					f, err := strconv.ParseFloat(str, 64)
+					return celsius(f), err
+				},
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.SetFloat(float64(src.(celsius))) },
+		},
+		// A new parser for an existing type
+		reflect.TypeOf(""): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"upper": func(str string) (interface{}, error) { return strings.ToUpper(str), nil },
+			},
+		},
+	}
+	handlers := envconfig.MergeHandlers(base, overrides)
+
+	assert.NotContains(t, base[reflect.TypeOf("")].Parsers, "upper", "base should not be modified")
+	assert.Len(t, handlers, len(base)+1)
+
+	var config struct {
+		Temp  celsius `env:"TEMP  ,parser=strconv.ParseFloat    "`
+		Upper string  `env:"UPPER ,parser=upper                 "`
+		Lower string  `env:"LOWER ,parser=nonempty-string       "`
+		Port  int     `env:"PORT  ,parser=strconv.ParseInt      "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), handlers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := testEnv{"TEMP": "21.5", "UPPER": "shout", "LOWER": "quiet", "PORT": "8080"}
+	warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+	assert.Equal(t, len(warn), 0, "There should be no warnings")
+	assert.Equal(t, len(fatal), 0, "There should be no errors")
+	assert.Equal(t, celsius(21.5), config.Temp)
+	assert.Equal(t, "SHOUT", config.Upper)
+	assert.Equal(t, "quiet", config.Lower)
+	assert.Equal(t, 8080, config.Port)
+}
+
+func TestParserAliases(t *testing.T) {
+	handlers := envconfig.MergeHandlers(envconfig.DefaultFieldTypeHandlers(), map[reflect.Type]envconfig.FieldTypeHandler{
+		reflect.TypeOf(""): {
+			Aliases: map[string]string{"maybe-empty-string": "possibly-empty-string"},
+		},
+	})
+	var config struct {
+		Old string `env:"OLD,parser=maybe-empty-string"`
+		New string `env:"NEW,parser=possibly-empty-string"`
+		Sub struct {
+			Old string `env:"SUB_OLD,parser=maybe-empty-string"`
+		}
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), handlers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := testEnv{"OLD": "a", "NEW": "b", "SUB_OLD": ""}
+	warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+	assert.Equal(t, len(fatal), 0, "There should be no errors")
+	if assert.Equal(t, len(warn), 2, "There should be 2 warnings") {
+		assert.EqualError(t, warn[0], `struct field "Old": parser "maybe-empty-string" is deprecated; use "possibly-empty-string" instead`)
+		assert.EqualError(t, warn[1], `struct field "Sub": struct field "Old": parser "maybe-empty-string" is deprecated; use "possibly-empty-string" instead`)
+	}
+	assert.Equal(t, "a", config.Old)
+	assert.Equal(t, "b", config.New)
+	assert.Equal(t, "", config.Sub.Old)
+
+	_, err = envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	assert.Error(t, err, "the alias should only exist in the handlers that define it")
+
+	t.Run("GenerateParserV2", func(t *testing.T) {
+		parser, genWarn, err := envconfig.GenerateParserV2(reflect.TypeOf(config), handlers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if assert.Equal(t, len(genWarn), 2, "There should be 2 generation warnings") {
+			assert.EqualError(t, genWarn[0], `struct field "Old": parser "maybe-empty-string" is deprecated; use "possibly-empty-string" instead`)
+			assert.EqualError(t, genWarn[1], `struct field "Sub": struct field "Old": parser "maybe-empty-string" is deprecated; use "possibly-empty-string" instead`)
+		}
+
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		assert.Equal(t, len(warn), 0, "The generation warnings should not be repeated")
+		assert.Equal(t, len(fatal), 0, "There should be no errors")
+
+		_, genWarn, err = envconfig.GenerateParserV2(reflect.TypeOf(config), nil)
+		assert.Error(t, err)
+		assert.Nil(t, genWarn)
+	})
+
+	t.Run("GenerateParserV2WithOptions", func(t *testing.T) {
+		var config struct {
+			Old string `config:"OLD,parser=maybe-empty-string"`
+		}
+		var set []string
+		parser, genWarn, err := envconfig.GenerateParserV2WithOptions(reflect.TypeOf(config), envconfig.GenerateOptions{
+			TypeHandlers: handlers,
+			TagName:      "config",
+			OnSet:        func(fieldName string, _ interface{}) { set = append(set, fieldName) },
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if assert.Equal(t, len(genWarn), 1, "There should be 1 generation warning") {
+			assert.EqualError(t, genWarn[0], `struct field "Old": parser "maybe-empty-string" is deprecated; use "possibly-empty-string" instead`)
+		}
+
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		assert.Equal(t, len(warn), 0, "The generation warnings should not be repeated")
+		assert.Equal(t, len(fatal), 0, "There should be no errors")
+		assert.Equal(t, "a", config.Old)
+		assert.Equal(t, []string{"Old"}, set, "The options should be honored")
+	})
+}
+
+func TestEmptyOrBool(t *testing.T) {
+	var config struct {
+		Flag bool `env:"FLAG,parser=empty-or-bool"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		Expected    bool
+		ExpectError bool
+	}{
+		"empty":   {Input: "", Expected: false},
+		"true":    {Input: "true", Expected: true},
+		"false":   {Input: "false", Expected: false},
+		"garbage": {Input: "garbage", ExpectError: true},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Flag = !tc.Expected
+			env := testEnv{"FLAG": tc.Input}
+			warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError {
+				assert.Equal(t, len(fatal), 1, "There should be 1 fatal error")
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				assert.Equal(t, tc.Expected, config.Flag)
+			}
+		})
+	}
+}
+
+func TestNotBlank(t *testing.T) {
+	var config struct {
+		Required string `env:"NAME ,parser=possibly-empty-string ,notBlank=true              "`
+		Default  string `env:"NAME ,parser=possibly-empty-string ,notBlank=true ,default=anon "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("normal", func(t *testing.T) {
+		warn, fatal := parser.ParseFromEnv(&config, testEnv{"NAME": " bob "}.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		assert.Equal(t, " bob ", config.Required, "the value should not be trimmed")
+		assert.Equal(t, " bob ", config.Default, "the value should not be trimmed")
+	})
+	t.Run("whitespace-only", func(t *testing.T) {
+		warn, fatal := parser.ParseFromEnv(&config, testEnv{"NAME": " \t "}.lookup)
+		if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+			assert.Contains(t, fatal[0].Error(), "is blank")
+		}
+		assert.Equal(t, len(warn), 1, "There should be 1 warning")
+		assert.Equal(t, "anon", config.Default)
+	})
+	t.Run("blank-default", func(t *testing.T) {
+		var config struct {
+			Value string `env:"VALUE,parser=possibly-empty-string,notBlank=true,default= "`
+		}
+		_, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestNegate(t *testing.T) {
+	var config struct {
+		EnableCache   bool `env:"DISABLE_CACHE   ,parser=empty-or-bool     ,negate=true               "`
+		EnableMetrics bool `env:"DISABLE_METRICS ,parser=strconv.ParseBool ,negate=true ,default=false "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input    string
+		Expected bool
+	}{
+		"true":  {Input: "true", Expected: false},
+		"false": {Input: "false", Expected: true},
+		"empty": {Input: "", Expected: true},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.EnableCache = !tc.Expected
+			env := testEnv{"DISABLE_CACHE": tc.Input}
+			warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+			assert.Equal(t, tc.Expected, config.EnableCache)
+			assert.True(t, config.EnableMetrics, "the default should be negated too")
+		})
+	}
+
+	t.Run("invalid-option", func(t *testing.T) {
+		for _, field := range []reflect.StructField{
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=nonempty-string,negate=true"`},
+			{Type: reflect.TypeOf(false), Tag: `env:"VALUE,parser=strconv.ParseBool,negate=yes"`},
+		} {
+			field.Name = "Value"
+			_, err := envconfig.GenerateParser(reflect.StructOf([]reflect.StructField{field}), nil)
+			assert.Errorf(t, err, "tag %s should be rejected", field.Tag)
+		}
+	})
+}
+
+func TestOctalFileMode(t *testing.T) {
+	var config struct {
+		Mode os.FileMode `env:"MODE,parser=octal-filemode"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		Expected    os.FileMode
+		ExpectError bool
+	}{
+		"0600":         {Input: "0600", Expected: 0o600},
+		"755":          {Input: "755", Expected: 0o755},
+		"malformed":    {Input: "0689", ExpectError: true},
+		"out-of-range": {Input: "1777", ExpectError: true},
+		"negative":     {Input: "-644", ExpectError: true},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Mode = 0
+			env := testEnv{"MODE": tc.Input}
+			warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError {
+				assert.Equal(t, len(fatal), 1, "There should be 1 fatal error")
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				assert.Equal(t, tc.Expected, config.Mode)
+			}
+		})
+	}
+}
+
+func TestRegexp(t *testing.T) {
+	var config struct {
+		Sensitive   *regexp.Regexp `env:"PATTERN ,parser=regexp.Compile                        "`
+		Insensitive *regexp.Regexp `env:"PATTERN ,parser=regexp.Compile ,reCaseInsensitive=true "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	warn, fatal := parser.ParseFromEnv(&config, testEnv{"PATTERN": "^foo-[a-z]+$"}.lookup)
+	assert.Equal(t, len(warn), 0, "There should be no warnings")
+	assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+	for _, input := range []string{"foo-bar", "FOO-Bar", "Foo-BAR"} {
+		assert.Equal(t, input == "foo-bar", config.Sensitive.MatchString(input), input)
+		assert.True(t, config.Insensitive.MatchString(input), input)
+	}
+	assert.False(t, config.Insensitive.MatchString("foo-123"))
+
+	_, fatal = parser.ParseFromEnv(&config, testEnv{"PATTERN": "(unclosed"}.lookup)
+	assert.Equal(t, len(fatal), 2, "There should be 2 fatal errors")
+
+	t.Run("invalid-option", func(t *testing.T) {
+		for _, field := range []reflect.StructField{
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=nonempty-string,reCaseInsensitive=true"`},
+			{Type: reflect.TypeOf((*regexp.Regexp)(nil)), Tag: `env:"VALUE,parser=regexp.Compile,reCaseInsensitive=maybe"`},
+		} {
+			field.Name = "Value"
+			_, err := envconfig.GenerateParser(reflect.StructOf([]reflect.StructField{field}), nil)
+			assert.Errorf(t, err, "tag %s should be rejected", field.Tag)
+		}
+	})
+}
+
+func TestTextTemplate(t *testing.T) {
+	var config struct {
+		Greeting *template.Template `env:"GREETING,parser=text-template"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	warn, fatal := parser.ParseFromEnv(&config, testEnv{"GREETING": "Hello, {{.Name}}!"}.lookup)
+	assert.Equal(t, len(warn), 0, "There should be no warnings")
+	assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+	var out strings.Builder
+	require.NoError(t, config.Greeting.Execute(&out, map[string]string{"Name": "world"}))
+	assert.Equal(t, "Hello, world!", out.String())
+
+	config.Greeting = nil
+	warn, fatal = parser.ParseFromEnv(&config, testEnv{"GREETING": "Hello, {{.Name}!"}.lookup)
+	assert.Equal(t, len(warn), 0, "There should be no warnings")
+	if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+		assert.Contains(t, fatal[0].Error(), "template: ")
+	}
+	assert.Nil(t, config.Greeting)
+}
+
+func TestWeekdayMonth(t *testing.T) {
+	var config struct {
+		Day   time.Weekday `env:"DAY   ,parser=weekday "`
+		Month time.Month   `env:"MONTH ,parser=month   "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Day           string
+		Month         string
+		ExpectedDay   time.Weekday
+		ExpectedMonth time.Month
+		ExpectErrors  int
+	}{
+		"names":         {Day: "Monday", Month: "February", ExpectedDay: time.Monday, ExpectedMonth: time.February},
+		"case":          {Day: "SATURDAY", Month: "june", ExpectedDay: time.Saturday, ExpectedMonth: time.June},
+		"numbers":       {Day: "0", Month: "12", ExpectedDay: time.Sunday, ExpectedMonth: time.December},
+		"invalid-names": {Day: "Mondy", Month: "Smarch", ExpectErrors: 2},
+		"out-of-range":  {Day: "7", Month: "0", ExpectErrors: 2},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Day, config.Month = 0, 0
+			env := testEnv{"DAY": tc.Day, "MONTH": tc.Month}
+			warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			assert.Equal(t, tc.ExpectErrors, len(fatal), "There should be %d fatal errors", tc.ExpectErrors)
+			if tc.ExpectErrors == 0 {
+				assert.Equal(t, tc.ExpectedDay, config.Day)
+				assert.Equal(t, tc.ExpectedMonth, config.Month)
+			}
+		})
+	}
+}
+
+func TestRunes(t *testing.T) {
+	var config struct {
+		Charset []rune `env:"CHARSET,parser=runes"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input    string
+		Expected []rune
+	}{
+		"ascii":     {Input: "abc", Expected: []rune{'a', 'b', 'c'}},
+		"multibyte": {Input: "héllo, 世界", Expected: []rune{'h', 'é', 'l', 'l', 'o', ',', ' ', '世', '界'}},
+		"empty":     {Input: "", Expected: []rune{}},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Charset = nil
+			env := testEnv{"CHARSET": tc.Input}
+			warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			assert.Equal(t, len(fatal), 0, "There should be no errors")
+			assert.Equal(t, tc.Expected, config.Charset)
+			assert.Len(t, config.Charset, len(tc.Expected))
+		})
+	}
+}
+
+func TestDefaultFromCycle(t *testing.T) {
+	testcases := map[string]struct {
+		Object        interface{}
+		ExpectedError string
+	}{
+		"two-field-cycle": {
+			Object: struct {
+				A string `env:"A ,parser=nonempty-string ,defaultFrom=B "`
+				B string `env:"B ,parser=nonempty-string ,defaultFrom=A "`
+			}{},
+			ExpectedError: `struct field "A": env option "defaultFrom": referenced field "B" creates a cycle: A -> B -> A`,
+		},
+		"self": {
+			Object: struct {
+				A string `env:"A ,parser=nonempty-string ,defaultFrom=A "`
+			}{},
+			ExpectedError: `struct field "A": env option "defaultFrom": referenced field "A" creates a cycle: A -> A`,
+		},
+		"three-field-cycle": {
+			Object: struct {
+				A string `env:"A ,parser=nonempty-string ,defaultFrom=C "`
+				B string `env:"B ,parser=nonempty-string ,defaultFrom=A "`
+				C string `env:"C ,parser=nonempty-string ,defaultFrom=B "`
+			}{},
+			ExpectedError: `struct field "A": env option "defaultFrom": referenced field "C" creates a cycle: A -> C -> B -> A`,
+		},
+		"missing": {
+			Object: struct {
+				A string `env:"A ,parser=nonempty-string ,defaultFrom=Z "`
+			}{},
+			ExpectedError: `struct field "A": env option "defaultFrom": referenced field "Z" does not exist`,
+		},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			_, err := envconfig.GenerateParser(reflect.TypeOf(tc.Object), nil)
+			assert.EqualError(t, err, tc.ExpectedError)
+		})
+	}
+}
+
+func TestDefaultFromForward(t *testing.T) {
+	type config struct {
+		// Timeout is declared before the fields that it (indirectly) defaults from.
+		Timeout     time.Duration `env:",const=true ,parser=none               ,defaultFrom=TimeoutNew "`
+		TimeoutNew  time.Duration `env:"TIMEOUT     ,parser=time.ParseDuration ,defaultFrom=TimeoutOld "`
+		TimeoutOld  time.Duration `env:"TIMEOUT_OLD ,parser=time.ParseDuration ,default=5s             "`
+		Independent string        `env:"OTHER       ,parser=possibly-empty-string ,default=x           "`
+	}
+	var setOrder []string
+	parser, err := envconfig.GenerateParserWithOptions(reflect.TypeOf(config{}), envconfig.GenerateOptions{
+		OnSet: func(fieldName string, _ interface{}) { setOrder = append(setOrder, fieldName) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Env      testEnv
+		Expected config
+	}{
+		"unset":   {Env: testEnv{}, Expected: config{Timeout: 5 * time.Second, TimeoutNew: 5 * time.Second, TimeoutOld: 5 * time.Second, Independent: "x"}},
+		"old-set": {Env: testEnv{"TIMEOUT_OLD": "1m"}, Expected: config{Timeout: time.Minute, TimeoutNew: time.Minute, TimeoutOld: time.Minute, Independent: "x"}},
+		"new-set": {Env: testEnv{"TIMEOUT": "1s", "TIMEOUT_OLD": "1m"}, Expected: config{Timeout: time.Second, TimeoutNew: time.Second, TimeoutOld: time.Minute, Independent: "x"}},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			setOrder = nil
+			var cfg config
+			warn, fatal := parser.ParseFromEnv(&cfg, tc.Env.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			assert.Equal(t, len(fatal), 0, "There should be no errors")
+			assert.Equal(t, tc.Expected, cfg)
+			assert.Equal(t, []string{"TimeoutOld", "TimeoutNew", "Timeout", "Independent"}, setOrder)
+		})
+	}
+}
+
+func TestCheckRequired(t *testing.T) {
+	var config struct {
+		A     string         `env:"A ,parser=nonempty-string                "`
+		B     string         `env:"B ,parser=nonempty-string ,default=b      "`
+		C     string         `env:"C ,parser=nonempty-string ,defaultFrom=A  "`
+		D     string         `env:"D ,parser=nonempty-string                "`
+		E     sql.NullString `env:"E ,parser=possibly-empty-string          "`
+		F     string         `env:",const=true ,parser=nonempty-string ,default=f "`
+		Child struct {
+			G string `env:"G ,parser=nonempty-string "`
+		}
+		Sub *subConfig
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Env      testEnv
+		Expected []string
+	}{
+		"empty":         {Env: testEnv{}, Expected: []string{"A", "D", "G"}},
+		"partial":       {Env: testEnv{"A": "", "SUB_PORT": "80"}, Expected: []string{"D", "G", "SUB_HOST"}},
+		"complete":      {Env: testEnv{"A": "a", "D": "d", "G": "g"}, Expected: nil},
+		"complete-sub":  {Env: testEnv{"A": "a", "D": "d", "G": "g", "SUB_HOST": "h"}, Expected: nil},
+		"only-optional": {Env: testEnv{"B": "b", "C": "c", "E": "e"}, Expected: []string{"A", "D", "G"}},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.Expected, parser.CheckRequired(tc.Env.lookup))
+		})
+	}
+}
+
+func TestAtomicValue(t *testing.T) {
+	var config struct {
+		Timeout *atomic.Value `env:"TIMEOUT,parser=time.ParseDuration"`
+		URL     *atomic.Value `env:"URL,parser=absolute-URL"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	warn, fatal := parser.ParseFromEnv(&config, testEnv{"TIMEOUT": "1s", "URL": "https://a.example.com/"}.lookup)
+	assert.Equal(t, len(warn), 0, "There should be no warnings")
+	assert.Equal(t, len(fatal), 0, "There should be no errors")
+	require.NotNil(t, config.Timeout)
+	assert.Equal(t, time.Second, config.Timeout.Load())
+	assert.Equal(t, "https://a.example.com/", config.URL.Load().(*url.URL).String())
+
+	// A reader that holds on to the *atomic.Value, concurrently with a re-parse.
+	timeout := config.Timeout
+	done := make(chan time.Duration)
+	go func() {
+		for {
+			if val := timeout.Load().(time.Duration); val != time.Second {
+				done <- val
+				return
+			}
+			runtime.Gosched()
+		}
+	}()
+
+	warn, fatal = parser.ParseFromEnv(&config, testEnv{"TIMEOUT": "2s", "URL": "https://b.example.com/"}.lookup)
+	assert.Equal(t, len(warn), 0, "There should be no warnings")
+	assert.Equal(t, len(fatal), 0, "There should be no errors")
+	assert.Same(t, timeout, config.Timeout, "the re-parse should store in to the existing atomic.Value")
+	assert.Equal(t, 2*time.Second, <-done)
+	assert.Equal(t, "https://b.example.com/", config.URL.Load().(*url.URL).String())
+}
+
+func TestTemplate(t *testing.T) {
+	type config struct {
+		Host string   `env:"HOST,parser=nonempty-string,default=localhost"`
+		Port int      `env:"PORT,parser=strconv.ParseInt,default=8080"`
+		URL  *url.URL `env:"URL,parser=absolute-URL,template=http://{{.Host}}:{{.Port}}/"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config{}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Env         testEnv
+		Expected    string
+		NumWarnings int
+	}{
+		"unset":   {Env: testEnv{}, Expected: "http://localhost:8080/"},
+		"derived": {Env: testEnv{"HOST": "example.com", "PORT": "80"}, Expected: "http://example.com:80/"},
+		"set":     {Env: testEnv{"HOST": "example.com", "URL": "https://other.example.com/"}, Expected: "https://other.example.com/"},
+		"invalid": {Env: testEnv{"HOST": "example.com", "URL": "/relative"}, Expected: "http://example.com:8080/", NumWarnings: 1},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			var cfg config
+			warn, fatal := parser.ParseFromEnv(&cfg, tc.Env.lookup)
+			assert.Equal(t, tc.NumWarnings, len(warn), "There should be %d warnings", tc.NumWarnings)
+			assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+			if assert.NotNil(t, cfg.URL) {
+				assert.Equal(t, tc.Expected, cfg.URL.String())
+			}
+		})
+	}
+
+	t.Run("execution-error", func(t *testing.T) {
+		var cfg struct {
+			Value string `env:"VALUE,parser=nonempty-string,template={{.Missing}}"`
+		}
+		parser, err := envconfig.GenerateParser(reflect.TypeOf(cfg), nil)
+		require.NoError(t, err)
+		warn, fatal := parser.ParseFromEnv(&cfg, testEnv{}.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 1, "There should be 1 fatal error")
+	})
+
+	t.Run("invalid-option", func(t *testing.T) {
+		for _, field := range []reflect.StructField{
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=nonempty-string,template={{.Host"`},
+		} {
+			field.Name = "Value"
+			_, err := envconfig.GenerateParser(reflect.StructOf([]reflect.StructField{field}), nil)
+			assert.Errorf(t, err, "tag %s should be rejected", field.Tag)
+		}
+	})
+
+	t.Run("default-containing-template", func(t *testing.T) {
+		// Everything after the first "default=" is the default, even if it looks like another option.
+		var cfg struct {
+			Value string `env:"VALUE,parser=nonempty-string,default=x,template={{.Host}}"`
+		}
+		parser, err := envconfig.GenerateParser(reflect.TypeOf(cfg), nil)
+		require.NoError(t, err)
+		warn, fatal := parser.ParseFromEnv(&cfg, testEnv{}.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		assert.Equal(t, "x,template={{.Host}}", cfg.Value)
+	})
+}
+
+func TestConst(t *testing.T) {
+	var config struct {
+		Timeout    time.Duration `env:",const=true,parser=time.ParseDuration,default=30s"`
+		URL        *url.URL      `env:",const=true,parser=absolute-URL,default=https://example.com/"`
+		TimeoutTwo time.Duration `env:",const=true,parser=none,defaultFrom=Timeout"`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The env-vars should not be consulted, even if something happens to be named the same as the field.
+	env := testEnv{"": "1s", "Timeout": "1s", "URL": "https://other.example.com/"}
+	warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+	assert.Equal(t, len(warn), 0, "There should be no warnings")
+	assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+	assert.Equal(t, 30*time.Second, config.Timeout)
+	if assert.NotNil(t, config.URL) {
+		assert.Equal(t, "https://example.com/", config.URL.String())
+	}
+	assert.Equal(t, 30*time.Second, config.TimeoutTwo)
+
+	t.Run("invalid-option", func(t *testing.T) {
+		for _, field := range []reflect.StructField{
+			{Type: reflect.TypeOf(time.Duration(0)), Tag: `env:",const=true,parser=none"`},
+			{Type: reflect.TypeOf(time.Duration(0)), Tag: `env:",const=true,parser=none,default=30s"`},
+			{Type: reflect.TypeOf(time.Duration(0)), Tag: `env:"VALUE,parser=none"`},
+			{Type: reflect.TypeOf(time.Duration(0)), Tag: `env:",const=true,parser=time.ParseDuration,default=30"`},
+		} {
+			field.Name = "Value"
+			_, err := envconfig.GenerateParser(reflect.StructOf([]reflect.StructField{field}), nil)
+			assert.Errorf(t, err, "tag %s should be rejected", field.Tag)
+		}
+	})
+}
+
+func TestFromFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(t *testing.T, name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+	withNewline := writeFile(t, "with-newline", "s3cret\n")
+	withCRLF := writeFile(t, "with-crlf", "s3cret\r\n")
+	withTwoNewlines := writeFile(t, "with-two-newlines", "s3cret\n\n")
+	withoutNewline := writeFile(t, "without-newline", "s3cret")
+
+	type config struct {
+		Trimmed   string `env:"TRIMMED   ,parser=possibly-empty-string ,fromFile=true                       "`
+		Untrimmed string `env:"UNTRIMMED ,parser=possibly-empty-string ,fromFile=true ,trimFileNewline=false "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config{}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Path      string
+		Trimmed   string
+		Untrimmed string
+	}{
+		"with-newline":      {Path: withNewline, Trimmed: "s3cret", Untrimmed: "s3cret\n"},
+		"with-crlf":         {Path: withCRLF, Trimmed: "s3cret", Untrimmed: "s3cret\r\n"},
+		"with-two-newlines": {Path: withTwoNewlines, Trimmed: "s3cret\n", Untrimmed: "s3cret\n\n"},
+		"without-newline":   {Path: withoutNewline, Trimmed: "s3cret", Untrimmed: "s3cret"},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			var cfg config
+			env := testEnv{"TRIMMED_FILE": tc.Path, "UNTRIMMED_FILE": tc.Path}
+			warn, fatal := parser.ParseFromEnv(&cfg, env.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+			assert.Equal(t, tc.Trimmed, cfg.Trimmed)
+			assert.Equal(t, tc.Untrimmed, cfg.Untrimmed)
+			assert.Empty(t, parser.CheckRequired(env.lookup))
+		})
+	}
+
+	t.Run("env-takes-precedence", func(t *testing.T) {
+		var cfg config
+		env := testEnv{"TRIMMED": "direct", "TRIMMED_FILE": withNewline, "UNTRIMMED_FILE": withNewline}
+		_, fatal := parser.ParseFromEnv(&cfg, env.lookup)
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		assert.Equal(t, "direct", cfg.Trimmed)
+	})
+	t.Run("missing-file", func(t *testing.T) {
+		var cfg config
+		env := testEnv{"TRIMMED_FILE": filepath.Join(dir, "missing"), "UNTRIMMED_FILE": withNewline}
+		_, fatal := parser.ParseFromEnv(&cfg, env.lookup)
+		if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+			assert.Contains(t, fatal[0].Error(), "TRIMMED_FILE")
+		}
+	})
+	t.Run("invalid-option", func(t *testing.T) {
+		for _, field := range []reflect.StructField{
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=nonempty-string,trimFileNewline=false"`},
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=nonempty-string,fromFile=yes"`},
+			{Type: reflect.TypeOf(""), Tag: `env:",const=true,parser=nonempty-string,fromFile=true,default=x"`},
+		} {
+			field.Name = "Value"
+			_, err := envconfig.GenerateParser(reflect.StructOf([]reflect.StructField{field}), nil)
+			assert.Errorf(t, err, "tag %s should be rejected", field.Tag)
+		}
+	})
+}
+
+func TestAllOrNone(t *testing.T) {
+	type proxy struct {
+		User     string `env:"PROXY_USER     ,parser=possibly-empty-string ,default= "`
+		Password string `env:"PROXY_PASSWORD ,parser=possibly-empty-string ,default= "`
+	}
+	type config struct {
+		TLSCert string `env:"TLS_CERT ,parser=possibly-empty-string                ,default=                 "`
+		TLSKey  string `env:"TLS_KEY  ,parser=possibly-empty-string ,fromFile=true ,default=/etc/tls/tls.key "`
+		Proxy   *proxy
+	}
+	base, err := envconfig.GenerateParser(reflect.TypeOf(config{}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser := base.AllOrNone("TLSCert", "TLSKey").AllOrNone("Proxy.User", "Proxy.Password")
+
+	keyFile := filepath.Join(t.TempDir(), "tls.key")
+	if err := os.WriteFile(keyFile, []byte("KEY"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Env           testEnv
+		ExpectedFatal []string
+	}{
+		"none-set": {
+			Env: testEnv{},
+		},
+		"all-set": {
+			Env: testEnv{"TLS_CERT": "/etc/tls/tls.crt", "TLS_KEY": "/etc/tls/tls.key", "PROXY_USER": "u", "PROXY_PASSWORD": "p"},
+		},
+		"all-set-from-file": {
+			Env: testEnv{"TLS_CERT": "/etc/tls/tls.crt", "TLS_KEY_FILE": keyFile},
+		},
+		"set-to-empty": {
+			Env: testEnv{"TLS_CERT": "", "TLS_KEY": ""},
+		},
+		"partial-set": {
+			Env:           testEnv{"TLS_CERT": "/etc/tls/tls.crt"},
+			ExpectedFatal: []string{"fields TLSCert, TLSKey must be set all together or not at all (set: TLSCert; not set: TLSKey)"},
+		},
+		"partial-set-nested": {
+			Env:           testEnv{"PROXY_PASSWORD": "p"},
+			ExpectedFatal: []string{"fields Proxy.User, Proxy.Password must be set all together or not at all (set: Proxy.Password; not set: Proxy.User)"},
+		},
+		"partial-set-both": {
+			Env: testEnv{"TLS_KEY": "/etc/tls/tls.key", "PROXY_USER": "u"},
+			ExpectedFatal: []string{
+				"fields TLSCert, TLSKey must be set all together or not at all (set: TLSKey; not set: TLSCert)",
+				"fields Proxy.User, Proxy.Password must be set all together or not at all (set: Proxy.User; not set: Proxy.Password)",
+			},
+		},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			var cfg config
+			warn, fatal := parser.ParseFromEnv(&cfg, tc.Env.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if assert.Equal(t, len(tc.ExpectedFatal), len(fatal)) {
+				for i := range fatal {
+					assert.EqualError(t, fatal[i], tc.ExpectedFatal[i])
+				}
+			}
+		})
+	}
+
+	t.Run("copy-is-independent", func(t *testing.T) {
+		var cfg config
+		_, fatal := base.ParseFromEnv(&cfg, testEnv{"TLS_CERT": "/etc/tls/tls.crt"}.lookup)
+		assert.Equal(t, len(fatal), 0, "The original parser should not have the constraint")
+	})
+
+	t.Run("unknown-field", func(t *testing.T) {
+		assert.Panics(t, func() { base.AllOrNone("TLSCert", "TLSKeyy") })
+		assert.Panics(t, func() { base.AllOrNone("Proxy") })
+	})
+}
+
+func TestOpenFile(t *testing.T) {
+	dir := t.TempDir()
+	inPath := filepath.Join(dir, "in.txt")
+	if err := os.WriteFile(inPath, []byte("hello\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	logPath := filepath.Join(dir, "out.log")
+	if err := os.WriteFile(logPath, []byte("first\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var config struct {
+		Input  *os.File `env:"INPUT  ,parser=os.OpenFile                             "`
+		Log    *os.File `env:"LOG    ,parser=os.OpenFile ,fileMode=append            "`
+		Output *os.File `env:"OUTPUT ,parser=os.OpenFile ,fileMode=write  ,default=- "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("read-and-append", func(t *testing.T) {
+		warn, fatal := parser.ParseFromEnv(&config, testEnv{"INPUT": inPath, "LOG": logPath}.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		if !assert.Equal(t, len(fatal), 0, "There should be no fatal errors") {
+			return
+		}
+		defer config.Input.Close()
+		defer config.Log.Close()
+
+		content, err := io.ReadAll(config.Input)
+		assert.NoError(t, err)
+		assert.Equal(t, "hello\n", string(content))
+		_, err = config.Input.WriteString("x")
+		assert.Error(t, err, "the input should be read-only")
+
+		_, err = config.Log.WriteString("second\n")
+		assert.NoError(t, err)
+		content, err = os.ReadFile(logPath)
+		assert.NoError(t, err)
+		assert.Equal(t, "first\nsecond\n", string(content))
+
+		assert.Equal(t, os.Stdout, config.Output)
+	})
+
+	t.Run("stdio", func(t *testing.T) {
+		warn, fatal := parser.ParseFromEnv(&config, testEnv{"INPUT": "-", "LOG": "-", "OUTPUT": "-"}.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		assert.Equal(t, os.Stdin, config.Input)
+		assert.Equal(t, os.Stdout, config.Log)
+		assert.Equal(t, os.Stdout, config.Output)
+	})
+
+	t.Run("write-truncates", func(t *testing.T) {
+		outPath := filepath.Join(dir, "out.txt")
+		if err := os.WriteFile(outPath, []byte("stale contents\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		warn, fatal := parser.ParseFromEnv(&config, testEnv{"INPUT": "-", "LOG": "-", "OUTPUT": outPath}.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		if !assert.Equal(t, len(fatal), 0, "There should be no fatal errors") {
+			return
+		}
+		defer config.Output.Close()
+		content, err := os.ReadFile(outPath)
+		assert.NoError(t, err)
+		assert.Equal(t, "", string(content))
+	})
+
+	t.Run("open-error", func(t *testing.T) {
+		warn, fatal := parser.ParseFromEnv(&config, testEnv{"INPUT": filepath.Join(dir, "missing.txt"), "LOG": "-"}.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+			assert.True(t, errors.Is(fatal[0], os.ErrNotExist), "the error should be from opening the file: %v", fatal[0])
+		}
+	})
+
+	t.Run("lint", func(t *testing.T) {
+		outPath := filepath.Join(dir, "lint-out.txt")
+		if err := os.WriteFile(outPath, []byte("keep me\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		newLogPath := filepath.Join(dir, "lint-new.log")
+		openFiles := func() int {
+			fds, _ := os.ReadDir("/proc/self/fd")
+			return len(fds)
+		}
+		before := openFiles()
+
+		type result struct {
+			EnvName       string
+			Parses        bool
+			EqualsDefault bool
+		}
+		lint := func(env testEnv) []result {
+			var ret []result
+			for _, r := range parser.Lint(context.Background(), env.lookup) {
+				ret = append(ret, result{r.EnvName, r.Parses, r.EqualsDefault})
+			}
+			return ret
+		}
+		assert.Equal(t, []result{
+			{EnvName: "INPUT", Parses: true},
+			{EnvName: "LOG", Parses: true},
+			{EnvName: "OUTPUT", Parses: true},
+		}, lint(testEnv{"INPUT": inPath, "LOG": newLogPath, "OUTPUT": outPath}))
+		assert.Equal(t, []result{
+			{EnvName: "INPUT"},
+			{EnvName: "LOG"},
+			{EnvName: "OUTPUT", Parses: true, EqualsDefault: true},
+		}, lint(testEnv{
+			"INPUT":  filepath.Join(dir, "missing.txt"),
+			"LOG":    filepath.Join(dir, "missing", "x.log"),
+			"OUTPUT": "-",
+		}))
+
+		content, err := os.ReadFile(outPath)
+		assert.NoError(t, err)
+		assert.Equal(t, "keep me\n", string(content), "Lint should not truncate the file")
+		_, err = os.Stat(newLogPath)
+		assert.True(t, os.IsNotExist(err), "Lint should not create the file")
+		assert.Equal(t, before, openFiles(), "Lint should not leave files open")
+	})
+
+	t.Run("invalid-option", func(t *testing.T) {
+		for _, field := range []reflect.StructField{
+			{Type: reflect.TypeOf((*os.File)(nil)), Tag: `env:"VALUE,parser=os.OpenFile,fileMode=readwrite"`},
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=nonempty-string,fileMode=read"`},
+		} {
+			field.Name = "Value"
+			_, err := envconfig.GenerateParser(reflect.StructOf([]reflect.StructField{field}), nil)
+			assert.Errorf(t, err, "tag %s should be rejected", field.Tag)
+		}
+	})
+}
+
+func TestLint(t *testing.T) {
+	type Config struct {
+		Host    string        `env:"HOST    ,parser=nonempty-string                      "`
+		Port    int           `env:"PORT    ,parser=strconv.ParseInt          ,default=80  "`
+		Timeout time.Duration `env:"TIMEOUT ,parser=time.ParseDuration        ,default=5s  "`
+		Workers int           `env:"WORKERS ,parser=strconv.ParseInt          ,default=4   "`
+		Token   string        `env:"TOKEN   ,parser=nonempty-string                      "`
+		Name    string        `env:"NAME    ,parser=nonempty-string ,maxBytes=4 ,default=anon "`
+		Cert    string        `env:"CERT    ,parser=possibly-empty-string     ,default=    "`
+		Key     string        `env:"KEY     ,parser=possibly-empty-string     ,default=    "`
+		Const   string        `env:",const=true ,parser=nonempty-string       ,default=x   "`
+	}
+	var onSetCalls int
+	parser, err := envconfig.GenerateParserWithOptions(reflect.TypeOf(Config{}), envconfig.GenerateOptions{
+		OnSet: func(string, interface{}) { onSetCalls++ },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser = parser.AllOrNone("Cert", "Key")
+
+	env := testEnv{
+		"HOST":    "example.com",
+		"PORT":    "80",
+		"TIMEOUT": "soon",
+		"NAME":    "administrator",
+		"CERT":    "/etc/tls/cert.pem",
+	}
+	results := parser.Lint(context.Background(), env.lookup)
+
+	type result struct {
+		EnvName       string
+		Present       bool
+		Parses        bool
+		EqualsDefault bool
+		Errors        []string
+	}
+	var actual []result
+	for _, r := range results {
+		var errs []string
+		for _, err := range r.Errors {
+			errs = append(errs, err.Error())
+		}
+		assert.Equal(t, r.EnvName, strings.ToUpper(r.FieldName))
+		actual = append(actual, result{r.EnvName, r.Present, r.Parses, r.EqualsDefault, errs})
+	}
+	const allOrNoneErr = "fields Cert, Key must be set all together or not at all (set: Cert; not set: Key)"
+	assert.Equal(t, []result{
+		{EnvName: "HOST", Present: true, Parses: true},
+		{EnvName: "PORT", Present: true, Parses: true, EqualsDefault: true},
+		{EnvName: "TIMEOUT", Present: true, Errors: []string{`invalid Timeout (falling back to default "5s"): time: invalid duration "soon"`}},
+		{EnvName: "WORKERS"},
+		{EnvName: "TOKEN", Errors: []string{"invalid Token (aborting): is not set"}},
+		{EnvName: "NAME", Present: true, Errors: []string{`invalid Name (falling back to default "anon"): is 13 bytes, which is more than the maximum of 4`}},
+		{EnvName: "CERT", Present: true, Parses: true, Errors: []string{allOrNoneErr}},
+		{EnvName: "KEY", Errors: []string{allOrNoneErr}},
+	}, actual)
+
+	assert.Equal(t, 0, onSetCalls, "Lint should not call OnSet")
+
+	t.Run("does-not-affect-parsed-struct", func(t *testing.T) {
+		var cfg Config
+		env := testEnv{"HOST": "example.com", "TOKEN": "secret"}
+		prov, _, fatal := parser.ParseWithProvenance(&cfg, env.lookup)
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		before := parser.SnapshotWithProvenance(&cfg, prov)
+
+		parser.Lint(context.Background(), testEnv{"HOST": "other.example.com"}.lookup)
+		assert.Equal(t, before, parser.SnapshotWithProvenance(&cfg, prov))
+		assert.Equal(t, "example.com", cfg.Host)
+	})
+
+	t.Run("context", func(t *testing.T) {
+		var config struct {
+			Host string `env:"HOST ,parser=resolvable-host "`
+		}
+		parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx := envconfig.WithResolver(context.Background(), fakeResolver{"db.example": {"10.0.0.1"}})
+
+		results := parser.Lint(ctx, testEnv{"HOST": "db.example"}.lookup)
+		if assert.Len(t, results, 1) {
+			assert.True(t, results[0].Parses, "Lint should use the Resolver from ctx")
+			assert.Empty(t, results[0].Errors)
+		}
+
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		results = parser.Lint(canceled, testEnv{"HOST": "db.example"}.lookup)
+		if assert.Len(t, results, 1) {
+			assert.False(t, results[0].Parses, "Lint should honor ctx's cancellation")
+		}
+	})
+}
+
+func TestSnapshot(t *testing.T) {
+	type config struct {
+		Host     string         `env:"HOST     ,parser=nonempty-string                              "`
+		Timeout  time.Duration  `env:"TIMEOUT  ,parser=time.ParseDuration  ,default=5s             "`
+		Timeout2 time.Duration  `env:"TIMEOUT2 ,parser=time.ParseDuration  ,defaultFrom=Timeout    "`
+		Password string         `env:"PASSWORD ,parser=possibly-empty-string ,redact=true ,default= "`
+		Name     sql.NullString `env:"NAME     ,parser=possibly-empty-string                        "`
+		Sub      struct {
+			Thing string `env:"SUB_THING ,parser=nonempty-string "`
+		}
+		Ptr *subConfig
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config{}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg config
+	env := testEnv{"HOST": "example.com", "PASSWORD": "hunter2", "SUB_THING": "x"}
+	prov, warn, fatal := parser.ParseWithProvenance(&cfg, env.lookup)
+	assert.Equal(t, len(warn), 0, "There should be no warnings")
+	assert.Equal(t, len(fatal), 0, "There should be no errors")
+	assert.Equal(t, []envconfig.FieldSnapshot{
+		{EnvName: "HOST", FieldName: "Host", Type: "string", Source: envconfig.SourceEnv, Value: "example.com"},
+		{EnvName: "TIMEOUT", FieldName: "Timeout", Type: "time.Duration", Source: envconfig.SourceDefault, Value: "5s"},
+		{EnvName: "TIMEOUT2", FieldName: "Timeout2", Type: "time.Duration", Source: envconfig.SourceDefaultFrom, Value: "5s"},
+		{EnvName: "PASSWORD", FieldName: "Password", Type: "string", Source: envconfig.SourceEnv, Value: "<redacted>"},
+		{EnvName: "NAME", FieldName: "Name", Type: "sql.NullString", Source: envconfig.SourceUnset, Value: "{ false}"},
+		{EnvName: "SUB_THING", FieldName: "Sub.Thing", Type: "string", Source: envconfig.SourceEnv, Value: "x"},
+	}, parser.SnapshotWithProvenance(&cfg, prov))
+
+	// Setting a nested pointer's env-var includes its fields; an empty redacted value isn't hidden.
+	env = testEnv{"HOST": "example.com", "SUB_THING": "x", "SUB_HOST": "sub.example.com", "TIMEOUT2": "1s"}
+	prov2, _, fatal := parser.ParseWithProvenance(&cfg, env.lookup)
+	assert.Equal(t, len(fatal), 0, "There should be no errors")
+	snapshot := parser.SnapshotWithProvenance(&cfg, prov2)
+	if assert.Len(t, snapshot, 8) {
+		assert.Equal(t, envconfig.FieldSnapshot{EnvName: "TIMEOUT2", FieldName: "Timeout2", Type: "time.Duration", Source: envconfig.SourceEnv, Value: "1s"}, snapshot[2])
+		assert.Equal(t, envconfig.FieldSnapshot{EnvName: "PASSWORD", FieldName: "Password", Type: "string", Source: envconfig.SourceDefault, Value: ""}, snapshot[3])
+		assert.Equal(t, envconfig.FieldSnapshot{EnvName: "SUB_HOST", FieldName: "Ptr.Host", Type: "string", Source: envconfig.SourceEnv, Value: "sub.example.com"}, snapshot[6])
+		assert.Equal(t, envconfig.FieldSnapshot{EnvName: "SUB_PORT", FieldName: "Ptr.Port", Type: "int", Source: envconfig.SourceDefault, Value: "80"}, snapshot[7])
+	}
+
+	// The sources are those of the given Provenance, not of whatever was last parsed in to the same
+	// struct.
+	assert.Equal(t, envconfig.SourceDefaultFrom, parser.SnapshotWithProvenance(&cfg, prov)[2].Source)
+
+	// Without a Provenance, there are no sources, but the values are the same.
+	plain := parser.Snapshot(&cfg)
+	if assert.Len(t, plain, 8) {
+		for i, field := range plain {
+			assert.Equal(t, envconfig.SourceUnset, field.Source)
+			field.Source = snapshot[i].Source
+			assert.Equal(t, snapshot[i], field)
+		}
+	}
+
+	t.Run("const-and-mergeNumbered", func(t *testing.T) {
+		var cfg struct {
+			Mode  string   `env:",const=true ,parser=nonempty-string ,default=prod             "`
+			Hosts []string `env:"HOSTS       ,parser=comma-split-trim ,mergeNumbered=true     "`
+		}
+		parser, err := envconfig.GenerateParser(reflect.TypeOf(cfg), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prov, _, fatal := parser.ParseWithProvenance(&cfg, testEnv{"HOSTS_1": "a", "HOSTS_2": "b"}.lookup)
+		assert.Equal(t, len(fatal), 0, "There should be no errors")
+		snapshot := parser.SnapshotWithProvenance(&cfg, prov)
+		if assert.Len(t, snapshot, 2) {
+			assert.Equal(t, envconfig.SourceDefault, snapshot[0].Source)
+			assert.Equal(t, envconfig.SourceEnv, snapshot[1].Source)
+		}
+	})
+}
+
+func TestParseWithProvenance(t *testing.T) {
+	type config struct {
+		Host    string        `env:"HOST    ,parser=nonempty-string                    "`
+		Timeout time.Duration `env:"TIMEOUT ,parser=time.ParseDuration ,default=5s    "`
+		Port    int           `env:"PORT    ,parser=strconv.ParseInt   ,default=80    "`
+		Sub     subConfig
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config{}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg config
+	before, warn, fatal := parser.ParseWithProvenance(&cfg, testEnv{"HOST": "a.example.com", "PORT": "8080", "SUB_HOST": "sub"}.lookup)
+	assert.Equal(t, len(warn), 0, "There should be no warnings")
+	assert.Equal(t, len(fatal), 0, "There should be no errors")
+	assert.Equal(t, envconfig.Provenance{
+		"HOST":     {Raw: "a.example.com", Source: envconfig.SourceEnv},
+		"TIMEOUT":  {Raw: "5s", Source: envconfig.SourceDefault},
+		"PORT":     {Raw: "8080", Source: envconfig.SourceEnv},
+		"SUB_HOST": {Raw: "sub", Source: envconfig.SourceEnv},
+		"SUB_PORT": {Raw: "80", Source: envconfig.SourceDefault},
+	}, before)
+
+	// Reload with a changed HOST, an unchanged PORT, and TIMEOUT set to the same value as its default.
+	after, warn, fatal := parser.ParseWithProvenance(&cfg, testEnv{"HOST": "b.example.com", "PORT": "8080", "TIMEOUT": "5s", "SUB_HOST": "sub"}.lookup)
+	assert.Equal(t, len(warn), 0, "There should be no warnings")
+	assert.Equal(t, len(fatal), 0, "There should be no errors")
+	assert.Equal(t, []string{"HOST", "TIMEOUT"}, after.Diff(before))
+	assert.Equal(t, []string{"HOST", "TIMEOUT"}, before.Diff(after))
+	assert.Empty(t, after.Diff(after))
+	assert.Equal(t, []string{"HOST", "PORT", "SUB_HOST", "SUB_PORT", "TIMEOUT"}, after.Diff(nil))
+}
+
+// fakeResolver is an envconfig.Resolver that resolves only the hosts in the map.
+type fakeResolver map[string][]string
+
+func (r fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		return nil, errors.New("lookup should have a deadline")
+	}
+	if addrs, ok := r[host]; ok {
+		return addrs, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func TestResolvableHost(t *testing.T) {
+	type config struct {
+		Required string `env:"REQUIRED ,parser=resolvable-host                    "`
+		Default  string `env:"DEFAULT  ,parser=resolvable-host ,default=fallback "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config{}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := envconfig.WithResolver(context.Background(), fakeResolver{
+		"db.example.com": {"192.0.2.1"},
+		"fallback":       {"192.0.2.2"},
+	})
+
+	t.Run("resolves", func(t *testing.T) {
+		var cfg config
+		env := testEnv{"REQUIRED": "db.example.com", "DEFAULT": "db.example.com"}
+		warn, fatal := parser.ParseFromEnvContext(ctx, &cfg, env.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		assert.Equal(t, config{Required: "db.example.com", Default: "db.example.com"}, cfg)
+	})
+	t.Run("does-not-resolve", func(t *testing.T) {
+		var cfg config
+		env := testEnv{"REQUIRED": "nxdomain.example.com", "DEFAULT": "nxdomain.example.com"}
+		warn, fatal := parser.ParseFromEnvContext(ctx, &cfg, env.lookup)
+		assert.Equal(t, len(warn), 1, "There should be 1 warning")
+		if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+			assert.Contains(t, fatal[0].Error(), `host "nxdomain.example.com" does not resolve`)
+		}
+		assert.Equal(t, "fallback", cfg.Default)
+	})
+	t.Run("canceled", func(t *testing.T) {
+		var cfg config
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		env := testEnv{"REQUIRED": "db.example.com"}
+		_, fatal := parser.ParseFromEnvContext(ctx, &cfg, env.lookup)
+		if assert.Equal(t, len(fatal), 2, "There should be 2 fatal errors") {
+			assert.ErrorIs(t, fatal[0], context.Canceled)
+		}
+	})
+}
+
+func TestDefaultFile(t *testing.T) {
+	dir := t.TempDir()
+	policyFile := filepath.Join(dir, "policy.txt")
+	if err := os.WriteFile(policyFile, []byte("allow ${USER}\ndeny *\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	portFile := filepath.Join(dir, "port.txt")
+	if err := os.WriteFile(portFile, []byte("8080"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The tags name the temp files, so the struct type has to be built at runtime.
+	configType := reflect.StructOf([]reflect.StructField{
+		{Name: "Policy", Type: reflect.TypeOf(""), Tag: reflect.StructTag(`env:"POLICY,parser=possibly-empty-string,defaultFile=` + policyFile + `"`)},
+		{Name: "Port", Type: reflect.TypeOf(0), Tag: reflect.StructTag(`env:"PORT,parser=strconv.ParseInt,defaultFile=` + portFile + `"`)},
+	})
+	parser, err := envconfig.GenerateParser(configType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parse := func(env testEnv) (policy string, port int, warn, fatal []error) {
+		cfg := reflect.New(configType)
+		warn, fatal = parser.ParseFromEnv(cfg.Interface(), env.lookup)
+		return cfg.Elem().Field(0).String(), int(cfg.Elem().Field(1).Int()), warn, fatal
+	}
+
+	t.Run("unset-uses-file", func(t *testing.T) {
+		policy, port, warn, fatal := parse(testEnv{"USER": "alice"})
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		assert.Equal(t, "allow ${USER}\ndeny *\n", policy, "the file contents should not be expanded")
+		assert.Equal(t, 8080, port)
+	})
+	t.Run("set-overrides-file", func(t *testing.T) {
+		policy, port, warn, fatal := parse(testEnv{"POLICY": "deny *", "PORT": "9090"})
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		assert.Equal(t, "deny *", policy)
+		assert.Equal(t, 9090, port)
+	})
+	t.Run("invalid-falls-back-to-file", func(t *testing.T) {
+		_, port, warn, fatal := parse(testEnv{"PORT": "http"})
+		if assert.Equal(t, len(warn), 1, "There should be 1 warning") {
+			assert.Contains(t, warn[0].Error(), fmt.Sprintf("invalid Port (falling back to defaultFile %q)", portFile))
+		}
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		assert.Equal(t, 8080, port)
+	})
+	t.Run("fs", func(t *testing.T) {
+		var cfg struct {
+			Policy string `env:"POLICY,parser=possibly-empty-string,defaultFile=defaults/policy.txt"`
+		}
+		parser, err := envconfig.GenerateParserWithOptions(reflect.TypeOf(cfg), envconfig.GenerateOptions{
+			DefaultFS: fstest.MapFS{"defaults/policy.txt": {Data: []byte("allow all")}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, fatal := parser.ParseFromEnv(&cfg, testEnv{}.lookup)
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		assert.Equal(t, "allow all", cfg.Policy)
+	})
+
+	t.Run("invalid-option", func(t *testing.T) {
+		badPortFile := filepath.Join(dir, "bad-port.txt")
+		if err := os.WriteFile(badPortFile, []byte("http"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		for _, field := range []reflect.StructField{
+			{Type: reflect.TypeOf(""), Tag: reflect.StructTag(`env:"VALUE,parser=nonempty-string,defaultFile=` + filepath.Join(dir, "missing.txt") + `"`)},
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=nonempty-string,defaultFile="`},
+			{Type: reflect.TypeOf(""), Tag: reflect.StructTag(`env:"VALUE,parser=nonempty-string,defaultFile=` + policyFile + `,default=x"`)},
+			{Type: reflect.TypeOf(0), Tag: reflect.StructTag(`env:"VALUE,parser=strconv.ParseInt,defaultFile=` + badPortFile + `"`)},
+		} {
+			field.Name = "Value"
+			_, err := envconfig.GenerateParser(reflect.StructOf([]reflect.StructField{field}), nil)
+			assert.Errorf(t, err, "tag %s should be rejected", field.Tag)
+		}
+	})
+}
+
+func TestMaxBytes(t *testing.T) {
+	var config struct {
+		Hosts []string          `env:"HOSTS ,parser=comma-split-trim ,maxBytes=16                    "`
+		Token string            `env:"TOKEN ,parser=nonempty-string  ,maxBytes=8  ,default=anon      "`
+		Tags  map[string]string `env:"TAGS  ,parser=json             ,maxBytes=32 ,default={}        "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("under-threshold", func(t *testing.T) {
+		env := testEnv{"HOSTS": "a.example,b.test", "TOKEN": "s3cr3t!!", "TAGS": `{"a":"1"}`}
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		assert.Equal(t, []string{"a.example", "b.test"}, config.Hosts)
+		assert.Equal(t, "s3cr3t!!", config.Token)
+		assert.Equal(t, map[string]string{"a": "1"}, config.Tags)
+	})
+
+	t.Run("over-threshold", func(t *testing.T) {
+		env := testEnv{
+			"HOSTS": "a.example,b.test,c",
+			"TOKEN": "s3cr3t!!!",
+			"TAGS":  `{"a":"` + strings.Repeat("x", 1<<20) + `"}`,
+		}
+		warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+		if assert.Equal(t, len(warn), 2, "There should be 2 warnings") {
+			assert.EqualError(t, warn[0], `invalid Token (falling back to default "anon"): is 9 bytes, which is more than the maximum of 8`)
+			assert.EqualError(t, warn[1], `invalid Tags (falling back to default "{}"): is 1048584 bytes, which is more than the maximum of 32`)
+		}
+		if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+			assert.EqualError(t, fatal[0], `invalid Hosts (aborting): is 18 bytes, which is more than the maximum of 16`)
+		}
+		assert.Equal(t, "anon", config.Token)
+		assert.Equal(t, map[string]string{}, config.Tags)
+	})
+
+	t.Run("invalid-option", func(t *testing.T) {
+		for _, field := range []reflect.StructField{
+			{Type: reflect.TypeOf(0), Tag: `env:"VALUE,parser=strconv.ParseInt,maxBytes=8"`},
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=nonempty-string,maxBytes=0"`},
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=nonempty-string,maxBytes=lots"`},
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=nonempty-string,maxBytes=4,default=too-long"`},
+		} {
+			field.Name = "Value"
+			_, err := envconfig.GenerateParser(reflect.StructOf([]reflect.StructField{field}), nil)
+			assert.Errorf(t, err, "tag %s should be rejected", field.Tag)
+		}
+	})
+}
+
+func TestSchemeAllow(t *testing.T) {
+	var config struct {
+		Endpoint *url.URL   `env:"ENDPOINT ,parser=absolute-URL             ,schemeAllow=https            ,default=https://example.com/ "`
+		Peers    []*url.URL `env:"PEERS    ,parser=comma-split-absolute-URL ,schemeAllow=http|https|grpc                               "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Env              testEnv
+		ExpectedEndpoint string
+		ExpectedPeers    []string
+		ExpectedWarn     string
+		ExpectedFatal    string
+	}{
+		"allowed": {
+			Env:              testEnv{"ENDPOINT": "https://api.example.com/", "PEERS": "http://a:80/, HTTPS://b/,grpc://c:9000"},
+			ExpectedEndpoint: "https://api.example.com/",
+			ExpectedPeers:    []string{"http://a:80/", "https://b/", "grpc://c:9000"},
+		},
+		"empty-list": {
+			Env:              testEnv{"PEERS": ""},
+			ExpectedEndpoint: "https://example.com/",
+			ExpectedPeers:    []string{},
+		},
+		"disallowed-single": {
+			Env:              testEnv{"ENDPOINT": "http://api.example.com/", "PEERS": "http://a/"},
+			ExpectedEndpoint: "https://example.com/",
+			ExpectedPeers:    []string{"http://a/"},
+			ExpectedWarn:     `scheme "http" is not one of ["https"]`,
+		},
+		"disallowed-element": {
+			Env:           testEnv{"PEERS": "http://a/,ftp://b/,https://c/"},
+			ExpectedFatal: `invalid Peers (aborting): item 1 ("ftp://b/"): scheme "ftp" is not one of ["http" "https" "grpc"]`,
+		},
+		"malformed-element": {
+			Env:           testEnv{"PEERS": "http://a/,b:80"},
+			ExpectedFatal: `invalid Peers (aborting): item 1 ("b:80"): not an absolute URL`,
+		},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Endpoint, config.Peers = nil, nil
+			warn, fatal := parser.ParseFromEnv(&config, tc.Env.lookup)
+			if tc.ExpectedWarn != "" {
+				if assert.Equal(t, len(warn), 1, "There should be 1 warning") {
+					assert.Contains(t, warn[0].Error(), tc.ExpectedWarn)
+				}
+			} else {
+				assert.Equal(t, len(warn), 0, "There should be no warnings")
+			}
+			if tc.ExpectedFatal != "" {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.EqualError(t, fatal[0], tc.ExpectedFatal)
+				}
+				return
+			}
+			assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+			assert.Equal(t, tc.ExpectedEndpoint, config.Endpoint.String())
+			peers := make([]string, 0, len(config.Peers))
+			for _, peer := range config.Peers {
+				peers = append(peers, peer.String())
+			}
+			assert.Equal(t, tc.ExpectedPeers, peers)
+		})
+	}
+
+	t.Run("invalid-option", func(t *testing.T) {
+		for _, field := range []reflect.StructField{
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=nonempty-string,schemeAllow=https"`},
+			{Type: reflect.TypeOf((*url.URL)(nil)), Tag: `env:"VALUE,parser=absolute-URL,schemeAllow="`},
+			{Type: reflect.TypeOf((*url.URL)(nil)), Tag: `env:"VALUE,parser=absolute-URL,schemeAllow=https,default=http://example.com/"`},
+		} {
+			field.Name = "Value"
+			_, err := envconfig.GenerateParser(reflect.StructOf([]reflect.StructField{field}), nil)
+			assert.Errorf(t, err, "tag %s should be rejected", field.Tag)
+		}
+	})
+}
+
+func TestResolveHost(t *testing.T) {
+	type config struct {
+		Endpoint *url.URL `env:"ENDPOINT ,parser=absolute-URL ,resolveHost=true                                  "`
+		Fallback *url.URL `env:"FALLBACK ,parser=absolute-URL ,resolveHost=true ,default=https://fallback:8443/ "`
+	}
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config{}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := envconfig.WithResolver(context.Background(), fakeResolver{
+		"api.example.com": {"192.0.2.1"},
+		"fallback":        {"192.0.2.2"},
+	})
+
+	testcases := map[string]struct {
+		Env              testEnv
+		ExpectedEndpoint string
+		ExpectedFallback string
+		ExpectedWarn     int
+		ExpectedFatal    string
+	}{
+		"resolves": {
+			Env:              testEnv{"ENDPOINT": "https://api.example.com:8443/v1", "FALLBACK": "https://api.example.com/"},
+			ExpectedEndpoint: "https://api.example.com:8443/v1",
+			ExpectedFallback: "https://api.example.com/",
+		},
+		"does-not-resolve": {
+			Env:              testEnv{"ENDPOINT": "https://api.exmaple.com/", "FALLBACK": "https://api.exmaple.com/"},
+			ExpectedFallback: "https://fallback:8443/",
+			ExpectedWarn:     1,
+			ExpectedFatal:    `host "api.exmaple.com" does not resolve`,
+		},
+		"no-host": {
+			Env:              testEnv{"ENDPOINT": "file:///etc/config"},
+			ExpectedFallback: "https://fallback:8443/",
+			ExpectedFatal:    `URL "file:///etc/config" has no host to resolve`,
+		},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			var cfg config
+			warn, fatal := parser.ParseFromEnvContext(ctx, &cfg, tc.Env.lookup)
+			assert.Equal(t, tc.ExpectedWarn, len(warn))
+			if tc.ExpectedFatal != "" {
+				if assert.Equal(t, len(fatal), 1, "There should be 1 fatal error") {
+					assert.Contains(t, fatal[0].Error(), tc.ExpectedFatal)
+				}
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				assert.Equal(t, tc.ExpectedEndpoint, cfg.Endpoint.String())
+			}
+			if assert.NotNil(t, cfg.Fallback) {
+				assert.Equal(t, tc.ExpectedFallback, cfg.Fallback.String())
+			}
+		})
+	}
+
+	t.Run("invalid-option", func(t *testing.T) {
+		for _, field := range []reflect.StructField{
+			{Type: reflect.TypeOf((*url.URL)(nil)), Tag: `env:"VALUE,parser=absolute-URL,resolveHost=maybe"`},
+			{Type: reflect.TypeOf(""), Tag: `env:"VALUE,parser=resolvable-host,resolveHost=true"`},
+		} {
+			field.Name = "Value"
+			_, err := envconfig.GenerateParser(reflect.StructOf([]reflect.StructField{field}), nil)
+			assert.Errorf(t, err, "tag %s should be rejected", field.Tag)
+		}
+	})
+}
+
+// executeTemplate executes a struct's *template.Template field with no data, for
+// TestSmokeTestAllParsers.
+func executeTemplate(obj interface{}) interface{} {
+	var out strings.Builder
+	_ = reflect.ValueOf(obj).Elem().Field(0).Interface().(*template.Template).Execute(&out, nil)
+	return &struct{ Value string }{Value: out.String()}
+}
+
+// readReader reads the contents of a struct's io.Reader field, for TestSmokeTestAllParsers.
+func readReader(obj interface{}) interface{} {
+	content, _ := io.ReadAll(reflect.ValueOf(obj).Elem().Field(0).Interface().(io.Reader))
+	return &struct{ Value string }{Value: string(content)}
+}
+
+// loadAtomicValue loads the value of a struct's *atomic.Value field, for TestSmokeTestAllParsers.
+func loadAtomicValue(obj interface{}) interface{} {
+	return &struct{ Value interface{} }{
+		Value: reflect.ValueOf(obj).Elem().Field(0).Interface().(*atomic.Value).Load(),
+	}
+}
+
+// A smokeTestcase is a TestSmokeTestAllParsers test of a single parser.
+type smokeTestcase struct {
+	Object   interface{}
+	EnvVar   string
+	Format   string
+	Expected string
+	Errors   int
+	Warnings int
+	// Value, if set, is used to get the value to format from the Object; for types that
+	// don't format nicely.
+	Value func(obj interface{}) interface{}
+}
+
+// versionedSmokeTests are TestSmokeTestAllParsers tests for types that only exist in some Go versions;
+// they are added by init functions in files with build constraints.
+var versionedSmokeTests = map[string]map[string]smokeTestcase{}
+
+func TestSmokeTestAllParsers(t *testing.T) {
+	// This isn't going in to any depth on any of the types; just
+	// checking that the parser and setter don't panic.
+	tests := map[string]map[string]smokeTestcase{
+		"string": {
+			"nonempty-string": {
+				Object: &struct {
+					Value string `env:"VALUE,parser=nonempty-string"`
+				}{},
+				EnvVar:   "str",
+				Expected: `&{str}`,
+			},
+			"nonempty-string-unset": {
+				// Error, required value with unset environment variable,
+				Object: &struct {
+					Value string `env:"UNSET_VALUE,parser=nonempty-string"`
+				}{},
+				Errors:   1,
+				Expected: `&{}`,
+			},
+			"nonempty-string-default-set": {
+				// Parser errors on empty string and falls back to default
+				Object: &struct {
+					Value string `env:"VALUE,parser=nonempty-string,default=str"`
+				}{},
+				EnvVar:   "",
+				Expected: `&{str}`,
+				Warnings: 1,
+			},
+			"nonempty-string-default-unset": {
+				// UNSET_VALUE is not present so parser called with default
+				Object: &struct {
+					Value string `env:"UNSET_VALUE,parser=nonempty-string,default=str"`
+				}{},
+				Expected: `&{str}`,
+			},
+			"possibly-empty-string": {
+				Object: &struct {
+					Value string `env:"VALUE,parser=possibly-empty-string"`
+				}{},
+				EnvVar:   "",
+				Expected: `&{}`,
+			},
+			"possibly-empty-string-unset": {
+				Object: &struct {
+					Value string `env:"UNSET_VALUE,parser=possibly-empty-string"`
+				}{},
+				Expected: `&{}`,
+				Errors:   1,
+			},
+			"possibly-empty-string-default-set": {
+				Object: &struct {
+					Value string `env:"VALUE,parser=possibly-empty-string,default=str"`
+				}{},
+				EnvVar:   "",
+				Expected: `&{}`,
+			},
+			"possibly-empty-string-default-unset": {
+				Object: &struct {
+					// Use UNSET_VALUE to reference a non-existent env variable.
+					Value string `env:"UNSET_VALUE,parser=possibly-empty-string,default=str"`
+				}{},
+				Expected: `&{str}`,
+			},
+			"logrus.ParseLevel": {
+				Object: &struct {
+					Value string `env:"VALUE,parser=logrus.ParseLevel"`
+				}{},
+				EnvVar:   "info",
+				Expected: `&{info}`,
+			},
+			"resolvable-host": {
+				Object: &struct {
+					Value string `env:"VALUE,parser=resolvable-host"`
+				}{},
+				EnvVar:   "localhost",
+				Expected: `&{localhost}`,
+			},
+			"valid-utf8": {
+				Object: &struct {
+					Value string `env:"VALUE,parser=valid-utf8"`
+				}{},
+				EnvVar:   "caf\u00e9",
+				Expected: "&{caf\u00e9}",
+			},
+			"unquote": {
+				Object: &struct {
+					Value string `env:"VALUE,parser=unquote"`
+				}{},
+				EnvVar:   `"a\x41"`,
+				Expected: `&{aA}`,
+			},
+			"gzip-base64": {
+				Object: &struct {
+					Value string `env:"VALUE,parser=gzip-base64"`
+				}{},
+				EnvVar:   "H4sIAAAAAAAA/8pIzcnJBwQAAP//hqYQNgUAAAA=",
+				Expected: `&{hello}`,
+			},
+		},
+		"bool": {
+			"empty/nonempty": {
+				Object: &struct {
+					Value bool `env:"VALUE,parser=empty/nonempty"`
+				}{},
+				EnvVar:   "false",
+				Expected: `&{true}`,
+			},
+			"strconv.ParseBool": {
+				Object: &struct {
+					Value bool `env:"VALUE,parser=strconv.ParseBool"`
+				}{},
+				EnvVar:   "false",
+				Expected: `&{false}`,
+			},
+			"empty-or-bool": {
+				Object: &struct {
+					Value bool `env:"VALUE,parser=empty-or-bool"`
+				}{},
+				EnvVar:   "",
+				Expected: `&{false}`,
+			},
+		},
+		"int": {
+			"strconv.ParseInt": {
+				Object: &struct {
+					Value int `env:"VALUE,parser=strconv.ParseInt"`
+				}{},
+				EnvVar:   "123",
+				Expected: `&{123}`,
+			},
+			"basis-points": {
+				Object: &struct {
+					Value int `env:"VALUE,parser=basis-points"`
+				}{},
+				EnvVar:   "1.5%",
+				Expected: `&{150}`,
+			},
+		},
+		"int64": {
+			"strconv.ParseInt": {
+				Object: &struct {
+					Value int64 `env:"VALUE,parser=strconv.ParseInt"`
+				}{},
+				EnvVar:   "123",
+				Expected: `&{123}`,
+			},
+		},
+		"int32": {
+			"strconv.ParseInt": {
+				Object: &struct {
+					Value int32 `env:"VALUE,parser=strconv.ParseInt"`
+				}{},
+				EnvVar:   "-123",
+				Expected: `&{-123}`,
+			},
+		},
+		"int16": {
+			"strconv.ParseInt": {
+				Object: &struct {
+					Value int16 `env:"VALUE,parser=strconv.ParseInt"`
+				}{},
+				EnvVar:   "-123",
+				Expected: `&{-123}`,
+			},
+		},
+		"int8": {
+			"strconv.ParseInt": {
+				Object: &struct {
+					Value int8 `env:"VALUE,parser=strconv.ParseInt"`
+				}{},
+				EnvVar:   "-123",
+				Expected: `&{-123}`,
+			},
+		},
+		"uint": {
+			"strconv.ParseUint": {
+				Object: &struct {
+					Value uint `env:"VALUE,parser=strconv.ParseUint"`
+				}{},
+				EnvVar:   "123",
+				Expected: `&{123}`,
+			},
+		},
+		"uint64": {
+			"strconv.ParseUint": {
+				Object: &struct {
+					Value uint64 `env:"VALUE,parser=strconv.ParseUint"`
+				}{},
+				EnvVar:   "123",
+				Expected: `&{123}`,
+			},
+		},
+		"float32": {
+			"strconv.ParseFloat": {
+				Object: &struct {
+					Value float32 `env:"VALUE,parser=strconv.ParseFloat"`
+				}{},
+				EnvVar:   "12.52",
+				Expected: "&{12.52}",
 			},
-			"logrus.ParseLevel": {
+		},
+		"float64": {
+			"strconv.ParseFloat": {
 				Object: &struct {
-					Value string `env:"VALUE,parser=logrus.ParseLevel"`
+					Value float64 `env:"VALUE,parser=strconv.ParseFloat"`
 				}{},
-				EnvVar:   "info",
-				Expected: `&{info}`,
+				EnvVar:   "0.125",
+				Expected: "&{0.125}",
 			},
 		},
-		"bool": {
-			"empty/nonempty": {
+		"fs.FileMode": {
+			"octal-filemode": {
 				Object: &struct {
-					Value bool `env:"VALUE,parser=empty/nonempty"`
+					Value os.FileMode `env:"VALUE,parser=octal-filemode"`
 				}{},
-				EnvVar:   "false",
-				Expected: `&{true}`,
+				EnvVar:   "0640",
+				Expected: `&{-rw-r-----}`,
 			},
-			"strconv.ParseBool": {
+		},
+		"*regexp.Regexp": {
+			"regexp.Compile": {
 				Object: &struct {
-					Value bool `env:"VALUE,parser=strconv.ParseBool"`
+					Value *regexp.Regexp `env:"VALUE,parser=regexp.Compile"`
 				}{},
-				EnvVar:   "false",
-				Expected: `&{false}`,
+				EnvVar:   "^a+$",
+				Expected: `&{^a+$}`,
 			},
 		},
-		"int": {
-			"strconv.ParseInt": {
+		"*template.Template": {
+			"text-template": {
 				Object: &struct {
-					Value int `env:"VALUE,parser=strconv.ParseInt"`
+					Value *template.Template `env:"VALUE,parser=text-template"`
 				}{},
-				EnvVar:   "123",
-				Expected: `&{123}`,
+				EnvVar:   `{{"hello"}}`,
+				Value:    executeTemplate,
+				Expected: `&{hello}`,
 			},
 		},
-		"int64": {
-			"strconv.ParseInt": {
+		"time.Weekday": {
+			"weekday": {
 				Object: &struct {
-					Value int64 `env:"VALUE,parser=strconv.ParseInt"`
+					Value time.Weekday `env:"VALUE,parser=weekday"`
 				}{},
-				EnvVar:   "123",
-				Expected: `&{123}`,
+				EnvVar:   "monday",
+				Expected: `&{Monday}`,
 			},
 		},
-		"float32": {
-			"strconv.ParseFloat": {
+		"time.Month": {
+			"month": {
 				Object: &struct {
-					Value float32 `env:"VALUE,parser=strconv.ParseFloat"`
+					Value time.Month `env:"VALUE,parser=month"`
 				}{},
-				EnvVar:   "12.52",
-				Expected: "&{12.52}",
+				EnvVar:   "12",
+				Expected: `&{December}`,
 			},
 		},
 		"*url.URL": {
@@ -273,6 +5966,27 @@ func TestSmokeTestAllParsers(t *testing.T) {
 				EnvVar:   "https://example.com/",
 				Expected: `&{https://example.com/}`,
 			},
+			"base-URL": {
+				Object: &struct {
+					Value *url.URL `env:"VALUE,parser=base-URL"`
+				}{},
+				EnvVar:   "https://example.com/api",
+				Expected: `&{https://example.com/api/}`,
+			},
+			"any-URI": {
+				Object: &struct {
+					Value *url.URL `env:"VALUE,parser=any-URI"`
+				}{},
+				EnvVar:   "urn:ietf:rfc:2648",
+				Expected: `&{urn:ietf:rfc:2648}`,
+			},
+			"relative-URL": {
+				Object: &struct {
+					Value *url.URL `env:"VALUE,parser=relative-URL"`
+				}{},
+				EnvVar:   "/path?x=1",
+				Expected: `&{/path?x=1}`,
+			},
 			"possibly-empty-absolute-URL": {
 				Object: &struct {
 					Value *url.URL `env:"VALUE,parser=possibly-empty-absolute-URL"`
@@ -288,6 +6002,65 @@ func TestSmokeTestAllParsers(t *testing.T) {
 				Expected: `&{<nil>}`,
 			},
 		},
+		"time.Time": {
+			"time.RFC3339": {
+				Object: &struct {
+					Value time.Time `env:"VALUE,parser=time.RFC3339"`
+				}{},
+				EnvVar:   "2024-01-02T03:04:05Z",
+				Expected: `&{2024-01-02 03:04:05 +0000 UTC}`,
+			},
+			"unix-seconds": {
+				Object: &struct {
+					Value time.Time `env:"VALUE,parser=unix-seconds"`
+				}{},
+				EnvVar:   "1704164645",
+				Expected: `&{2024-01-02 03:04:05 +0000 UTC}`,
+			},
+		},
+		"envconfig.Money": {
+			"currency-amount": {
+				Object: &struct {
+					Value envconfig.Money `env:"VALUE,parser=currency-amount"`
+				}{},
+				EnvVar:   "EUR 0.5",
+				Expected: `&{EUR 0.50}`,
+			},
+		},
+		"envconfig.DurationRange": {
+			"duration-range": {
+				Object: &struct {
+					Value envconfig.DurationRange `env:"VALUE,parser=duration-range"`
+				}{},
+				EnvVar:   "1s..5s",
+				Expected: `&{{1s 5s}}`,
+			},
+		},
+		"[]int": {
+			"comma-split-int": {
+				Object: &struct {
+					Value []int `env:"VALUE,parser=comma-split-int"`
+				}{},
+				EnvVar:   "1, 2,4 ,-8",
+				Expected: `&{[1 2 4 -8]}`,
+			},
+			"comma-split-int-empty-override-default": {
+				Object: &struct {
+					Value []int `env:"VALUE,parser=comma-split-int,default=1,2"`
+				}{},
+				EnvVar:   "",
+				Expected: `&{[]}`,
+			},
+		},
+		"[]*url.URL": {
+			"comma-split-absolute-URL": {
+				Object: &struct {
+					Value []*url.URL `env:"VALUE,parser=comma-split-absolute-URL"`
+				}{},
+				EnvVar:   "https://a.example.com/, http://b.example.com:8080/x",
+				Expected: `&{[https://a.example.com/ http://b.example.com:8080/x]}`,
+			},
+		},
 		"time.Duration": {
 			"integer-seconds": {
 				Object: &struct {
@@ -303,6 +6076,13 @@ func TestSmokeTestAllParsers(t *testing.T) {
 				EnvVar:   "3m2s",
 				Expected: `&{3m2s}`,
 			},
+			"duration-or-never": {
+				Object: &struct {
+					Value time.Duration `env:"VALUE,parser=duration-or-never"`
+				}{},
+				EnvVar:   "never",
+				Expected: `&{2562047h47m16.854775807s}`,
+			},
 		},
 		"[]string": {
 			"comma-split-trim": {
@@ -342,9 +6122,348 @@ func TestSmokeTestAllParsers(t *testing.T) {
 				Format:   "%q",
 				Expected: `&{[]}`,
 			},
+			"shell-split": {
+				Object: &struct {
+					Value []string `env:"VALUE,parser=shell-split"`
+				}{},
+				EnvVar:   `first,"second,third" 'fourth fifth'`,
+				Format:   "%q",
+				Expected: `&{["first" "second,third" "fourth fifth"]}`,
+			},
+			"newline-split-trim": {
+				Object: &struct {
+					Value []string `env:"VALUE,parser=newline-split-trim"`
+				}{},
+				EnvVar:   "first\n second\r\nthird\n",
+				Format:   "%q",
+				Expected: `&{["first" "second" "third"]}`,
+			},
+			"filepath.SplitList": {
+				Object: &struct {
+					Value []string `env:"VALUE,parser=filepath.SplitList"`
+				}{},
+				EnvVar:   "/bin" + string(os.PathListSeparator) + "/usr/bin",
+				Format:   "%q",
+				Expected: `&{["/bin" "/usr/bin"]}`,
+			},
+		},
+		"[]int32": {
+			"runes": {
+				Object: &struct {
+					Value []rune `env:"VALUE,parser=runes"`
+				}{},
+				EnvVar:   "añb",
+				Format:   "%q",
+				Expected: `&{['a' 'ñ' 'b']}`,
+			},
+		},
+		"*os.File": {
+			"os.OpenFile": {
+				Object: &struct {
+					Value *os.File `env:"VALUE,parser=os.OpenFile"`
+				}{},
+				EnvVar:   "-",
+				Expected: `&{/dev/stdin}`,
+				Value: func(obj interface{}) interface{} {
+					return &struct{ Value string }{Value: reflect.ValueOf(obj).Elem().Field(0).Interface().(*os.File).Name()}
+				},
+			},
+		},
+		"*net.IPNet": {
+			"net.ParseCIDR": {
+				Object: &struct {
+					Value *net.IPNet `env:"VALUE,parser=net.ParseCIDR"`
+				}{},
+				EnvVar:   "10.1.2.3/8",
+				Expected: `&{10.0.0.0/8}`,
+			},
+			"net.ParseCIDR-invalid": {
+				Object: &struct {
+					Value *net.IPNet `env:"VALUE,parser=net.ParseCIDR"`
+				}{},
+				EnvVar:   "10.0.0.0/33",
+				Expected: `&{<nil>}`,
+				Errors:   1,
+			},
+			"net.ParseCIDR-empty": {
+				Object: &struct {
+					Value *net.IPNet `env:"VALUE,parser=net.ParseCIDR"`
+				}{},
+				EnvVar:   "",
+				Expected: `&{<nil>}`,
+				Errors:   1,
+			},
+			"possibly-empty-CIDR": {
+				Object: &struct {
+					Value *net.IPNet `env:"VALUE,parser=possibly-empty-CIDR"`
+				}{},
+				EnvVar:   "fd00::/8",
+				Expected: `&{fd00::/8}`,
+			},
+			"possibly-empty-CIDR-empty": {
+				Object: &struct {
+					Value *net.IPNet `env:"VALUE,parser=possibly-empty-CIDR"`
+				}{},
+				EnvVar:   "",
+				Expected: `&{<nil>}`,
+			},
+			"possibly-empty-CIDR-invalid": {
+				Object: &struct {
+					Value *net.IPNet `env:"VALUE,parser=possibly-empty-CIDR"`
+				}{},
+				EnvVar:   "10.0.0.0",
+				Expected: `&{<nil>}`,
+				Errors:   1,
+			},
+		},
+		"[]*net.IPNet": {
+			"comma-split-CIDR": {
+				Object: &struct {
+					Value []*net.IPNet `env:"VALUE,parser=comma-split-CIDR"`
+				}{},
+				EnvVar:   "10.0.0.0/8,::1/128",
+				Expected: `&{[10.0.0.0/8 ::1/128]}`,
+			},
+		},
+		"net.IPMask": {
+			"mask-or-prefix-length": {
+				Object: &struct {
+					Value net.IPMask `env:"VALUE,parser=mask-or-prefix-length"`
+				}{},
+				EnvVar:   "/24",
+				Expected: `&{ffffff00}`,
+			},
+		},
+		"net.Addr": {
+			"network-addr": {
+				Object: &struct {
+					Value net.Addr `env:"VALUE,parser=network-addr"`
+				}{},
+				EnvVar:   "tcp://127.0.0.1:8080",
+				Expected: `&{127.0.0.1:8080}`,
+			},
+		},
+		"[]uint16": {
+			"comma-split-ports": {
+				Object: &struct {
+					Value []uint16 `env:"VALUE,parser=comma-split-ports"`
+				}{},
+				EnvVar:   "80, 443",
+				Expected: `&{[80 443]}`,
+			},
+		},
+		"map[string]string": {
+			"json": {
+				Object: &struct {
+					Value map[string]string `env:"VALUE,parser=json"`
+				}{},
+				EnvVar:   `{"a": "1", "b": "2"}`,
+				Expected: `&{map[a:1 b:2]}`,
+			},
+			"comma-split-kv": {
+				Object: &struct {
+					Value map[string]string `env:"VALUE,parser=comma-split-kv"`
+				}{},
+				EnvVar:   "team=infra, tier=backend",
+				Expected: `&{map[team:infra tier:backend]}`,
+			},
+			"comma-split-ip-kv": {
+				Object: &struct {
+					Value map[string]string `env:"VALUE,parser=comma-split-ip-kv"`
+				}{},
+				EnvVar:   "10.0.0.1=gw, ::1=lo",
+				Expected: `&{map[10.0.0.1:gw ::1:lo]}`,
+			},
+			"dotenv": {
+				Object: &struct {
+					Value map[string]string `env:"VALUE,parser=dotenv"`
+				}{},
+				EnvVar:   "a=1\nexport b='2'",
+				Expected: `&{map[a:1 b:2]}`,
+			},
+		},
+		"*atomic.Value": {
+			"nonempty-string": {
+				Object: &struct {
+					Value *atomic.Value `env:"VALUE,parser=nonempty-string"`
+				}{},
+				EnvVar:   "str",
+				Value:    loadAtomicValue,
+				Expected: `&{str}`,
+			},
+			"possibly-empty-string": {
+				Object: &struct {
+					Value *atomic.Value `env:"VALUE,parser=possibly-empty-string"`
+				}{},
+				EnvVar:   "",
+				Value:    loadAtomicValue,
+				Expected: `&{}`,
+			},
+			"strconv.ParseBool": {
+				Object: &struct {
+					Value *atomic.Value `env:"VALUE,parser=strconv.ParseBool"`
+				}{},
+				EnvVar:   "true",
+				Value:    loadAtomicValue,
+				Expected: `&{true}`,
+			},
+			"strconv.ParseInt": {
+				Object: &struct {
+					Value *atomic.Value `env:"VALUE,parser=strconv.ParseInt"`
+				}{},
+				EnvVar:   "123",
+				Value:    loadAtomicValue,
+				Expected: `&{123}`,
+			},
+			"time.ParseDuration": {
+				Object: &struct {
+					Value *atomic.Value `env:"VALUE,parser=time.ParseDuration"`
+				}{},
+				EnvVar:   "3m2s",
+				Value:    loadAtomicValue,
+				Expected: `&{3m2s}`,
+			},
+			"absolute-URL": {
+				Object: &struct {
+					Value *atomic.Value `env:"VALUE,parser=absolute-URL"`
+				}{},
+				EnvVar:   "https://example.com/",
+				Value:    loadAtomicValue,
+				Expected: `&{https://example.com/}`,
+			},
+		},
+		"map[string]time.Duration": {
+			"comma-split-kv": {
+				Object: &struct {
+					Value map[string]time.Duration `env:"VALUE,parser=comma-split-kv"`
+				}{},
+				EnvVar:   "default=1s,slow=30s",
+				Expected: `&{map[default:1s slow:30s]}`,
+			},
+		},
+		"json.Number": {
+			"json-number": {
+				Object: &struct {
+					Value json.Number `env:"VALUE,parser=json-number"`
+				}{},
+				EnvVar:   "12345678901234567890.5",
+				Expected: `&{12345678901234567890.5}`,
+			},
+		},
+		"map[string]bool": {
+			"comma-split-kv": {
+				Object: &struct {
+					Value map[string]bool `env:"VALUE,parser=comma-split-kv"`
+				}{},
+				EnvVar:   "a,b=false",
+				Expected: `&{map[a:true b:false]}`,
+			},
+		},
+		"[]envconfig.KeyValue": {
+			"comma-split-kv": {
+				Object: &struct {
+					Value []envconfig.KeyValue `env:"VALUE,parser=comma-split-kv"`
+				}{},
+				EnvVar:   "b=1,a=2",
+				Expected: `&{[{b 1} {a 2}]}`,
+			},
+		},
+		"[]envconfig.WeightedEntry": {
+			"comma-split-weighted": {
+				Object: &struct {
+					Value []envconfig.WeightedEntry `env:"VALUE,parser=comma-split-weighted"`
+				}{},
+				EnvVar:   "a:3,b",
+				Expected: `&{[{a 3} {b 1}]}`,
+			},
+		},
+		"io.Reader": {
+			"inline-or-@file": {
+				Object: &struct {
+					Value io.Reader `env:"VALUE,parser=inline-or-@file"`
+				}{},
+				EnvVar:   "inline",
+				Expected: `&{inline}`,
+				Value:    readReader,
+			},
+		},
+		"[]uint8": {
+			"bytes-auto": {
+				Object: &struct {
+					Value []byte `env:"VALUE,parser=bytes-auto"`
+				}{},
+				EnvVar:   "aGVsbG8=",
+				Format:   "%s",
+				Expected: `&{hello}`,
+			},
+			"unquote": {
+				Object: &struct {
+					Value []byte `env:"VALUE,parser=unquote"`
+				}{},
+				EnvVar:   `"a\x41"`,
+				Format:   "%s",
+				Expected: `&{aA}`,
+			},
+			"gzip-base64": {
+				Object: &struct {
+					Value []byte `env:"VALUE,parser=gzip-base64"`
+				}{},
+				EnvVar:   "H4sIAAAAAAAA/8pIzcnJBwQAAP//hqYQNgUAAAA=",
+				Format:   "%s",
+				Expected: `&{hello}`,
+			},
+		},
+		"logrus.Level": {
+			"logrus-level": {
+				Object: &struct {
+					Value logrus.Level `env:"VALUE,parser=logrus-level"`
+				}{},
+				EnvVar:   "Info",
+				Expected: `&{info}`,
+			},
+		},
+		"sql.NullString": {
+			"possibly-empty-string": {
+				Object: &struct {
+					Value sql.NullString `env:"VALUE,parser=possibly-empty-string"`
+				}{},
+				EnvVar:   "str",
+				Expected: `&{{str true}}`,
+			},
+		},
+		"sql.NullInt64": {
+			"strconv.ParseInt": {
+				Object: &struct {
+					Value sql.NullInt64 `env:"VALUE,parser=strconv.ParseInt"`
+				}{},
+				EnvVar:   "123",
+				Expected: `&{{123 true}}`,
+			},
+		},
+		"sql.NullBool": {
+			"strconv.ParseBool": {
+				Object: &struct {
+					Value sql.NullBool `env:"VALUE,parser=strconv.ParseBool"`
+				}{},
+				EnvVar:   "false",
+				Expected: `&{{false true}}`,
+			},
+		},
+		"sql.NullFloat64": {
+			"strconv.ParseFloat": {
+				Object: &struct {
+					Value sql.NullFloat64 `env:"VALUE,parser=strconv.ParseFloat"`
+				}{},
+				EnvVar:   "12.5",
+				Expected: `&{{12.5 true}}`,
+			},
 		},
 	}
 
+	for typeName, typetests := range versionedSmokeTests {
+		tests[typeName] = typetests
+	}
+
 	for typeName, typetests := range tests {
 		typetests := typetests
 		t.Run(typeName, func(t *testing.T) {
@@ -363,7 +6482,11 @@ func TestSmokeTestAllParsers(t *testing.T) {
 					if format == "" {
 						format = "%v"
 					}
-					assert.Equal(t, testinfo.Expected, fmt.Sprintf(format, testinfo.Object))
+					obj := testinfo.Object
+					if testinfo.Value != nil {
+						obj = testinfo.Value(obj)
+					}
+					assert.Equal(t, testinfo.Expected, fmt.Sprintf(format, obj))
 				})
 			}
 		})
@@ -381,5 +6504,10 @@ func TestSmokeTestAllParsers(t *testing.T) {
 				t.Errorf("no test for type %q parser %q", typeName, parserName)
 			}
 		}
+		for parserName := range typeHandler.ContextParsers {
+			if _, ok := tests[typeName][parserName]; !ok {
+				t.Errorf("no test for type %q parser %q", typeName, parserName)
+			}
+		}
 	}
 }
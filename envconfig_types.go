@@ -1,16 +1,39 @@
 package envconfig
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
 	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"text/template"
 	"time"
+	"unicode/utf8"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
+// DurationNever is the time.Duration that the "duration-or-never" parser returns for "never".  It is the
+// largest representable time.Duration (about 292 years), so that it compares as longer than any other
+// duration; but beware that adding it to a time.Time will overflow.
+const DurationNever = time.Duration(math.MaxInt64)
+
 func parseURL(str string) (interface{}, error) {
 	u, err := url.Parse(str)
 	if err != nil {
@@ -30,6 +53,756 @@ func parseURL(str string) (interface{}, error) {
 	return u, nil
 }
 
+// parseBaseURL is like parseURL, but normalizes the path to end with a "/" (so "https://x/api" becomes
+// "https://x/api/"), so that resolving a relative reference against it appends to the path rather than
+// replacing the last path segment.
+func parseBaseURL(str string) (interface{}, error) {
+	val, err := parseURL(str)
+	if err != nil {
+		return nil, err
+	}
+	u := val.(*url.URL)
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+		if u.RawPath != "" {
+			u.RawPath += "/"
+		}
+	}
+	return u, nil
+}
+
+// parseAnyURI is like parseURL, but accepts URNs (such as "urn:ietf:rfc:2648") as well as URLs.
+func parseAnyURI(str string) (interface{}, error) {
+	u, err := url.Parse(str)
+	if err != nil {
+		return nil, errors.Errorf("unable to parse URI %q: %v", str, err)
+	}
+	if !u.IsAbs() {
+		return nil, errors.New("not an absolute URI")
+	}
+	return u, nil
+}
+
+// parseRelativeURL is the inverse of parseURL; it accepts only relative references (such as
+// "/path?x=1"), and rejects absolute URLs.
+func parseRelativeURL(str string) (interface{}, error) {
+	if str == "" {
+		return nil, ErrNotSet
+	}
+	u, err := url.Parse(str)
+	if err != nil {
+		return nil, errors.Errorf("unable to parse URL %q: %v", str, err)
+	}
+	if u.IsAbs() || u.Host != "" {
+		// Also reject network-path references ("//host/path"), which are relative
+		// references in name only.
+		return nil, errors.New("not a relative URL")
+	}
+	return u, nil
+}
+
+// commaSplitTrim splits str on commas, trimming whitespace around each element.
+func commaSplitTrim(str string) []string {
+	// We don't want strings.Split to create a one element slice for an empty string so a special
+	// check is needed for that here.
+	if str == "" {
+		return []string{}
+	}
+	ss := strings.Split(str, ",")
+	for i, s := range ss {
+		ss[i] = strings.TrimSpace(s)
+	}
+	return ss
+}
+
+// shellSplit splits str in to words, similarly to a POSIX shell, except that unquoted commas delimit words
+// as well as unquoted whitespace, so that both `A,"B,C",D` and `A "B C" D` split in to three words.
+//
+//   - Inside of single quotes, every character is literal.
+//   - Inside of double quotes, a backslash escapes a following `"` or `\`; other characters are literal.
+//   - Outside of quotes, a backslash escapes any following character (including a comma).
+//   - Runs of delimiters do not produce empty words; an empty word may be given as a pair of quotes.
+func shellSplit(str string) ([]string, error) {
+	ret := []string{}
+	var word strings.Builder
+	inWord := false
+	for i := 0; i < len(str); i++ {
+		switch char := str[i]; char {
+		case ' ', '\t', '\n', '\r', ',':
+			if inWord {
+				ret = append(ret, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case '\\':
+			i++
+			if i == len(str) {
+				return nil, errors.New("trailing backslash")
+			}
+			word.WriteByte(str[i])
+			inWord = true
+		case '\'':
+			end := strings.IndexByte(str[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+			word.WriteString(str[i+1 : i+1+end])
+			i += 1 + end
+			inWord = true
+		case '"':
+			i++
+			for ; i < len(str) && str[i] != '"'; i++ {
+				if str[i] == '\\' && i+1 < len(str) && (str[i+1] == '"' || str[i+1] == '\\') {
+					i++
+				}
+				word.WriteByte(str[i])
+			}
+			if i == len(str) {
+				return nil, errors.New("unterminated double quote")
+			}
+			inWord = true
+		default:
+			word.WriteByte(char)
+			inWord = true
+		}
+	}
+	if inWord {
+		ret = append(ret, word.String())
+	}
+	return ret, nil
+}
+
+// splitKeyValues splits a "k1=v1,k2=v2" string in to its key/value pairs, trimming whitespace around
+// each key and value.  Each pair is split on its first "=", so values may contain "=".
+func splitKeyValues(str string) ([][2]string, error) {
+	// We don't want strings.Split to create a one element slice for an empty string so a special
+	// check is needed for that here.
+	if str == "" {
+		return nil, nil
+	}
+	pairs := strings.Split(str, ",")
+	ret := make([][2]string, 0, len(pairs))
+	for _, pair := range pairs {
+		keyval := strings.SplitN(pair, "=", 2)
+		if len(keyval) != 2 {
+			return nil, errors.Errorf("not a key=value pair: %q", strings.TrimSpace(pair))
+		}
+		ret = append(ret, [2]string{strings.TrimSpace(keyval[0]), strings.TrimSpace(keyval[1])})
+	}
+	return ret, nil
+}
+
+// A KeyValue is a single key/value pair, for []KeyValue fields; which are like map[string]string fields,
+// but preserve the order of the pairs (and allow duplicate keys).
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
+// A DurationRange is a range of durations, such as for picking a jittered retry backoff.
+type DurationRange struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+// parseDurationRange parses a "MIN..MAX" range of durations (such as "1s..5s"), where each end is
+// parsed by time.ParseDuration.
+func parseDurationRange(str string) (interface{}, error) {
+	parts := strings.Split(str, "..")
+	if len(parts) != 2 {
+		return nil, errors.Errorf("not a MIN..MAX range: %q", str)
+	}
+	var ret DurationRange
+	for i, dst := range []*time.Duration{&ret.Min, &ret.Max} {
+		d, err := time.ParseDuration(strings.TrimSpace(parts[i]))
+		if err != nil {
+			return nil, err
+		}
+		*dst = d
+	}
+	if ret.Min > ret.Max {
+		return nil, errors.Errorf("invalid range %q: minimum %s is greater than maximum %s", str, ret.Min, ret.Max)
+	}
+	return ret, nil
+}
+
+// A WeightedEntry is a name with a relative weight, for []WeightedEntry fields, such as for weighted load
+// balancing across backends.
+type WeightedEntry struct {
+	Name   string
+	Weight int
+}
+
+// parseWeightedEntries parses a "NAME:WEIGHT,NAME:WEIGHT" list (such as "a:3,b:1"), where WEIGHT is a
+// non-negative integer.  A NAME without a ":WEIGHT" has a weight of 1; but "NAME:" is an error.
+func parseWeightedEntries(str string) (interface{}, error) {
+	items := commaSplitTrim(str)
+	ret := make([]WeightedEntry, 0, len(items))
+	for _, item := range items {
+		entry := WeightedEntry{Name: item, Weight: 1}
+		if colon := strings.LastIndexByte(item, ':'); colon >= 0 {
+			entry.Name = strings.TrimSpace(item[:colon])
+			weightStr := strings.TrimSpace(item[colon+1:])
+			weight, err := strconv.Atoi(weightStr)
+			if err != nil || weight < 0 {
+				return nil, errors.Errorf("entry %q: invalid weight %q: must be a non-negative integer", item, weightStr)
+			}
+			entry.Weight = weight
+		}
+		if entry.Name == "" {
+			return nil, errors.Errorf("entry %q: empty name", item)
+		}
+		ret = append(ret, entry)
+	}
+	return ret, nil
+}
+
+// Money is an amount of a currency, in the currency's minor units (such as cents); so "USD 12.34" is
+// Money{Currency: "USD", Amount: 1234}, and "JPY 500" is Money{Currency: "JPY", Amount: 500}.
+type Money struct {
+	// Currency is the ISO 4217 currency code, such as "USD".
+	Currency string
+	// Amount is in the currency's minor units.
+	Amount int64
+}
+
+// currencyExponents are the currencies known to the "currency-amount" parser, and the number of digits
+// after the decimal point in each one's minor unit.
+var currencyExponents = map[string]int{
+	"AUD": 2, "BHD": 3, "BRL": 2, "CAD": 2, "CHF": 2, "CNY": 2, "DKK": 2, "EUR": 2, "GBP": 2, "HKD": 2,
+	"INR": 2, "JPY": 0, "KRW": 0, "KWD": 3, "MXN": 2, "NOK": 2, "NZD": 2, "SEK": 2, "SGD": 2, "USD": 2,
+	"ZAR": 2,
+}
+
+// String formats m like the "currency-amount" parser's input, such as "USD 12.34".
+func (m Money) String() string {
+	exp := currencyExponents[m.Currency]
+	sign, amount := "", m.Amount
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	if exp == 0 {
+		return fmt.Sprintf("%s %s%d", m.Currency, sign, amount)
+	}
+	unit := int64(math.Pow10(exp))
+	return fmt.Sprintf("%s %s%d.%0*d", m.Currency, sign, amount/unit, exp, amount%unit)
+}
+
+// parseMoney parses a "CURRENCY AMOUNT" string (such as "USD 12.34") in to a Money.  The AMOUNT may
+// have at most as many digits after the decimal point as the currency's minor unit (so none for "JPY").
+func parseMoney(str string) (interface{}, error) {
+	fields := strings.Fields(str)
+	if len(fields) != 2 {
+		return nil, errors.Errorf("not a \"CURRENCY AMOUNT\" string: %q", str)
+	}
+	currency, amountStr := fields[0], fields[1]
+	exp, ok := currencyExponents[currency]
+	if !ok {
+		known := make([]string, 0, len(currencyExponents))
+		for code := range currencyExponents {
+			known = append(known, code)
+		}
+		sort.Strings(known)
+		return nil, errors.Errorf("unknown currency %q (must be one of %v)", currency, known)
+	}
+	whole, frac := amountStr, ""
+	if dot := strings.IndexByte(amountStr, '.'); dot >= 0 {
+		whole, frac = amountStr[:dot], amountStr[dot+1:]
+		if frac == "" || strings.Trim(frac, "0123456789") != "" {
+			return nil, errors.Errorf("invalid amount %q", amountStr)
+		}
+	}
+	if len(frac) > exp {
+		return nil, errors.Errorf("invalid amount %q: %s has %d digits after the decimal point, not %d", amountStr, currency, exp, len(frac))
+	}
+	minor, err := strconv.ParseInt(whole+frac+strings.Repeat("0", exp-len(frac)), 10, 64)
+	if err != nil || whole == "" || whole == "-" || strings.HasPrefix(whole, "+") {
+		return nil, errors.Errorf("invalid amount %q", amountStr)
+	}
+	return Money{Currency: currency, Amount: minor}, nil
+}
+
+// parseReader returns an io.Reader over the contents of the file named by the rest of str if str starts
+// with "@", or else over str itself.  The file is read in to memory rather than being left open, so that
+// there is nothing to close.  It is a context parser, since it may read a file.
+func parseReader(_ context.Context, str string) (interface{}, error) {
+	if filename := strings.TrimPrefix(str, "@"); filename != str {
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(content), nil
+	}
+	return strings.NewReader(str), nil
+}
+
+// parseBytesAuto decodes str as hex if it looks like hex (an even number of hex digits), or else as
+// standard padded base64 if it is valid base64, or else returns the raw bytes of str.  Note that this
+// order matters, as many hex strings (such as "deadbeef") are also valid base64.
+func parseBytesAuto(str string) (interface{}, error) {
+	if bs, err := hex.DecodeString(str); err == nil {
+		return bs, nil
+	}
+	if bs, err := base64.StdEncoding.DecodeString(str); err == nil {
+		return bs, nil
+	}
+	return []byte(str), nil
+}
+
+// parseGzipBase64 decodes str as standard padded base64, and then decompresses the result as gzip; for
+// values that would otherwise be too large for an env-var.
+func parseGzipBase64(str string) (interface{}, error) {
+	compressed, err := base64.StdEncoding.DecodeString(str)
+	if err != nil {
+		return nil, errors.Wrap(err, "base64")
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, errors.Wrap(err, "gzip")
+	}
+	ret, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, errors.Wrap(err, "gzip")
+	}
+	return ret, nil
+}
+
+// parseWeekday parses a weekday name ("Monday", case-insensitively) or number (0 for Sunday through 6 for
+// Saturday).
+func parseWeekday(str string) (interface{}, error) {
+	if n, err := strconv.Atoi(str); err == nil {
+		if n < int(time.Sunday) || n > int(time.Saturday) {
+			return nil, errors.Errorf("weekday %d is out of range (0-6)", n)
+		}
+		return time.Weekday(n), nil
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(str, day.String()) {
+			return day, nil
+		}
+	}
+	return nil, errors.Errorf("unrecognized weekday %q", str)
+}
+
+// parseMonth parses a month name ("January", case-insensitively) or number (1 for January through 12 for
+// December).
+func parseMonth(str string) (interface{}, error) {
+	if n, err := strconv.Atoi(str); err == nil {
+		if n < int(time.January) || n > int(time.December) {
+			return nil, errors.Errorf("month %d is out of range (1-12)", n)
+		}
+		return time.Month(n), nil
+	}
+	for month := time.January; month <= time.December; month++ {
+		if strings.EqualFold(str, month.String()) {
+			return month, nil
+		}
+	}
+	return nil, errors.Errorf("unrecognized month %q", str)
+}
+
+// parseDotenv parses str as the contents of a ".env" file: "KEY=VALUE" lines, optionally prefixed by
+// "export ", with blank lines and "#" comments ignored.  A value may be in double quotes (with backslash
+// escapes such as "\n"), or in single quotes (literal); either may span multiple lines.  An unquoted
+// value ends at a " #" comment, and is whitespace-trimmed.  If a key is repeated, the last value wins.
+func parseDotenv(str string) (interface{}, error) {
+	ret := make(map[string]string)
+	lines := strings.Split(strings.ReplaceAll(str, "\r\n", "\n"), "\n")
+	for lineno := 0; lineno < len(lines); lineno++ {
+		line := strings.TrimSpace(lines[lineno])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		startLineno := lineno + 1
+		line = strings.TrimLeft(strings.TrimPrefix(line, "export "), " \t")
+		eq := strings.IndexByte(line, '=')
+		if eq < 0 {
+			return nil, errors.Errorf("line %d: not a KEY=VALUE pair: %q", startLineno, line)
+		}
+		key := strings.TrimSpace(line[:eq])
+		if key == "" {
+			return nil, errors.Errorf("line %d: empty key", startLineno)
+		}
+		rest := strings.TrimLeft(line[eq+1:], " \t")
+		if rest == "" || (rest[0] != '"' && rest[0] != '\'') {
+			if comment := strings.Index(rest, " #"); comment >= 0 {
+				rest = rest[:comment]
+			} else if strings.HasPrefix(rest, "#") {
+				rest = ""
+			}
+			ret[key] = strings.TrimSpace(rest)
+			continue
+		}
+		// Quoted; keep consuming lines until the closing quote.
+		quote := rest[0]
+		rest = rest[1:]
+		var val strings.Builder
+		for {
+			end := -1
+			for i := 0; i < len(rest); i++ {
+				if quote == '"' && rest[i] == '\\' && i+1 < len(rest) {
+					i++
+					continue
+				}
+				if rest[i] == quote {
+					end = i
+					break
+				}
+			}
+			if end >= 0 {
+				val.WriteString(rest[:end])
+				rest = strings.TrimSpace(rest[end+1:])
+				break
+			}
+			val.WriteString(rest)
+			lineno++
+			if lineno >= len(lines) {
+				return nil, errors.Errorf("line %d: unterminated %c-quoted value for %q", startLineno, quote, key)
+			}
+			val.WriteByte('\n')
+			rest = lines[lineno]
+		}
+		if rest != "" && !strings.HasPrefix(rest, "#") {
+			return nil, errors.Errorf("line %d: unexpected text after quoted value for %q: %q", lineno+1, key, rest)
+		}
+		if quote == '\'' {
+			ret[key] = val.String()
+			continue
+		}
+		unescaped, err := unescapeDotenv(val.String())
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d: %q", startLineno, key)
+		}
+		ret[key] = unescaped
+	}
+	return ret, nil
+}
+
+// unescapeDotenv processes the backslash escapes in a double-quoted .env value.
+func unescapeDotenv(str string) (string, error) {
+	var ret strings.Builder
+	for i := 0; i < len(str); i++ {
+		if str[i] != '\\' {
+			ret.WriteByte(str[i])
+			continue
+		}
+		i++
+		if i == len(str) {
+			return "", errors.New("trailing backslash")
+		}
+		switch str[i] {
+		case 'n':
+			ret.WriteByte('\n')
+		case 'r':
+			ret.WriteByte('\r')
+		case 't':
+			ret.WriteByte('\t')
+		case '"', '\\', '$':
+			ret.WriteByte(str[i])
+		default:
+			return "", errors.Errorf("invalid escape \"\\%c\"", str[i])
+		}
+	}
+	return ret.String(), nil
+}
+
+// parseIntBits is strconv.ParseInt in base 10, but with a clearer error (that includes the limits) if str
+// is out of range for an integer of the given bit size, rather than silently truncating it.
+func parseIntBits(str string, bitSize int) (int64, error) {
+	n, err := strconv.ParseInt(str, 10, bitSize)
+	if errors.Is(err, strconv.ErrRange) {
+		max := int64(1)<<(bitSize-1) - 1
+		article := "a"
+		if bitSize == 8 {
+			article = "an"
+		}
+		return 0, errors.Errorf("%q is out of range for %s %d-bit integer (must be between %d and %d)", str, article, bitSize, -max-1, max)
+	}
+	return n, err
+}
+
+// parseUintBits is like parseIntBits, but for unsigned integers; a negative number is an error, rather
+// than wrapping around.
+func parseUintBits(str string, bitSize int) (uint64, error) {
+	if strings.HasPrefix(strings.TrimSpace(str), "-") {
+		return 0, errors.Errorf("%q is negative, but must be an unsigned integer", str)
+	}
+	n, err := strconv.ParseUint(str, 10, bitSize)
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, errors.Errorf("%q is out of range for a %d-bit unsigned integer (maximum is %d)", str, bitSize, ^uint64(0)>>(64-bitSize))
+	}
+	return n, err
+}
+
+// parseBasisPoints parses a percentage ("1.5%") in to an integer number of basis points (150), or a bare
+// integer as already being in basis points.  A percentage may have at most 2 decimal places, since a
+// basis point is 0.01%.
+func parseBasisPoints(str string) (interface{}, error) {
+	pct := strings.TrimSuffix(str, "%")
+	if pct == str {
+		n, err := parseIntBits(str, strconv.IntSize)
+		return int(n), err
+	}
+	whole, frac := pct, ""
+	if dot := strings.IndexByte(pct, '.'); dot >= 0 {
+		whole, frac = pct[:dot], pct[dot+1:]
+	}
+	if len(frac) > 2 || strings.Trim(frac, "0123456789") != "" || (whole == "" && frac == "") {
+		return nil, errors.Errorf("invalid percentage %q", str)
+	}
+	neg := strings.HasPrefix(whole, "-")
+	if whole == "" || whole == "-" || whole == "+" {
+		whole += "0"
+	}
+	n, err := strconv.Atoi(whole)
+	if err != nil {
+		return nil, errors.Errorf("invalid percentage %q", str)
+	}
+	fracBps, _ := strconv.Atoi((frac + "00")[:2])
+	if neg {
+		fracBps = -fracBps
+	}
+	if n > (math.MaxInt-99)/100 || n < (math.MinInt+99)/100 {
+		return nil, errors.Errorf("percentage %q is out of range", str)
+	}
+	return n*100 + fracBps, nil
+}
+
+// parseNetAddr parses a "NETWORK://ADDRESS" listener address (such as "tcp://:8080" or
+// "unix:///tmp/x.sock") in to a net.Addr, using the resolver for NETWORK: net.ResolveTCPAddr for "tcp",
+// "tcp4", and "tcp6"; net.ResolveUDPAddr for "udp", "udp4", and "udp6"; and net.ResolveUnixAddr for
+// "unix", "unixgram", and "unixpacket".  A TCP or UDP hostname is looked up with the Resolver from ctx
+// (see WithResolver), which is why it is a context parser.
+func parseNetAddr(ctx context.Context, str string) (interface{}, error) {
+	sep := strings.Index(str, "://")
+	if sep < 0 {
+		return nil, errors.Errorf("not a NETWORK://ADDRESS address: %q", str)
+	}
+	network, address := str[:sep], str[sep+len("://"):]
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		address, err := resolveHostPort(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		return net.ResolveTCPAddr(network, address)
+	case "udp", "udp4", "udp6":
+		address, err := resolveHostPort(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		return net.ResolveUDPAddr(network, address)
+	case "unix", "unixgram", "unixpacket":
+		if address == "" {
+			return nil, errors.Errorf("empty socket path in %q", str)
+		}
+		return net.ResolveUnixAddr(network, address)
+	default:
+		return nil, errors.Errorf("unknown network %q in %q (must be one of tcp, tcp4, tcp6, udp, udp4, udp6, unix, unixgram, unixpacket)", network, str)
+	}
+}
+
+// resolveHostPort replaces the hostname in the "HOST:PORT" address with its first address of the family
+// that network ("tcp4", "udp6", etc.) calls for, so that resolving the result doesn't do a DNS lookup of
+// its own.  An address whose host is empty or already an IP address is returned unchanged.
+func resolveHostPort(ctx context.Context, network, address string) (string, error) {
+	if address == "" {
+		return address, nil
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", err
+	}
+	if host == "" || net.ParseIP(strings.SplitN(host, "%", 2)[0]) != nil {
+		return address, nil
+	}
+	addrs, err := lookupHost(ctx, host)
+	if err != nil {
+		return "", err
+	}
+	for _, addr := range addrs {
+		ip := net.ParseIP(addr)
+		switch {
+		case ip == nil:
+			continue
+		case strings.HasSuffix(network, "4") && ip.To4() == nil:
+			continue
+		case strings.HasSuffix(network, "6") && ip.To4() != nil:
+			continue
+		}
+		return net.JoinHostPort(addr, port), nil
+	}
+	return "", errors.Errorf("host %q has no %s address", host, network)
+}
+
+// parseIPMask parses an IPv4 netmask, either in dotted form ("255.255.255.0") or as a prefix length
+// ("/24").  The mask must be canonical; that is, its ones must all be before its zeros.
+func parseIPMask(str string) (interface{}, error) {
+	if lenStr := strings.TrimPrefix(str, "/"); lenStr != str {
+		ones, err := strconv.Atoi(lenStr)
+		if err != nil || ones < 0 || ones > 8*net.IPv4len {
+			return nil, errors.Errorf("invalid prefix length %q", str)
+		}
+		return net.CIDRMask(ones, 8*net.IPv4len), nil
+	}
+	ip := net.ParseIP(str).To4()
+	if ip == nil {
+		return nil, errors.Errorf("invalid netmask %q", str)
+	}
+	mask := net.IPMask(ip)
+	if _, bits := mask.Size(); bits == 0 {
+		return nil, errors.Errorf("non-canonical netmask %q", str)
+	}
+	return mask, nil
+}
+
+// A Resolver looks up the addresses of a hostname, for the "resolvable-host" and "network-addr" parsers
+// and the "resolveHost" option.  *net.Resolver implements Resolver.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) (addrs []string, err error)
+}
+
+type resolverContextKey struct{}
+
+// WithResolver returns a copy of ctx that causes the "resolvable-host" and "network-addr" parsers and the
+// "resolveHost" option to use resolver, instead of net.DefaultResolver, when ctx is passed to ParseFromEnvContext.
+func WithResolver(ctx context.Context, resolver Resolver) context.Context {
+	return context.WithValue(ctx, resolverContextKey{}, resolver)
+}
+
+// resolveTimeout is how long lookupHost waits for a lookup, if ctx doesn't have a sooner deadline.
+const resolveTimeout = 5 * time.Second
+
+// parseResolvableHost checks that the hostname str resolves to at least one address.
+func parseResolvableHost(ctx context.Context, str string) (interface{}, error) {
+	if str == "" {
+		return nil, ErrNotSet
+	}
+	if _, err := lookupHost(ctx, str); err != nil {
+		return nil, err
+	}
+	return str, nil
+}
+
+// lookupHost returns the addresses that host resolves to, or an error if it doesn't resolve to any, using
+// the Resolver from ctx.
+func lookupHost(ctx context.Context, host string) ([]string, error) {
+	resolver, ok := ctx.Value(resolverContextKey{}).(Resolver)
+	if !ok {
+		resolver = net.DefaultResolver
+	}
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+	addrs, err := resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, errors.Wrapf(err, "host %q does not resolve", host)
+	}
+	return addrs, nil
+}
+
+type fileModeContextKey struct{}
+
+// dryRunContextKey marks the ctx of a parse that must not have side effects, because the struct it
+// populates isn't the caller's; see StructParser.Lint.
+type dryRunContextKey struct{}
+
+// parseOpenFile opens the file at path, for reading, writing (truncating it), or appending according to
+// the "fileMode" tag option, which wrapParser passes in ctx.  A file that is opened for writing or
+// appending is created if it doesn't exist.  The path "-" is os.Stdin for reading, or os.Stdout for
+// writing or appending.  It is a context parser, since it opens the file.  In a dry run it only checks
+// that the file could be opened, and returns a nil *os.File.
+func parseOpenFile(ctx context.Context, path string) (interface{}, error) {
+	mode, _ := ctx.Value(fileModeContextKey{}).(string)
+	if path == "" {
+		return nil, ErrNotSet
+	}
+	if path == "-" {
+		if mode == "" || mode == "read" {
+			return os.Stdin, nil
+		}
+		return os.Stdout, nil
+	}
+	var flag int
+	switch mode {
+	case "", "read":
+		flag = os.O_RDONLY
+	case "write":
+		flag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	case "append":
+		flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	default:
+		return nil, errors.Errorf("invalid file mode %q", mode)
+	}
+	if dryRun, _ := ctx.Value(dryRunContextKey{}).(bool); dryRun {
+		if err := checkOpenFile(path, flag); err != nil {
+			return nil, err
+		}
+		return (*os.File)(nil), nil
+	}
+	return os.OpenFile(path, flag, 0o666)
+}
+
+// checkOpenFile checks that os.OpenFile(path, flag) would succeed, without creating or truncating the
+// file, and without leaving it open.
+//
+//nolint:wrapcheck // The caller will wrap errors.
+func checkOpenFile(path string, flag int) error {
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err) && flag&os.O_CREATE != 0:
+		// It would be created, so check that the directory it would be created in exists.
+		dir := filepath.Dir(path)
+		dirInfo, err := os.Stat(dir)
+		if err != nil {
+			return err
+		}
+		if !dirInfo.IsDir() {
+			return errors.Errorf("%s is not a directory", dir)
+		}
+		return nil
+	case err != nil:
+		return err
+	case !info.Mode().IsRegular():
+		// Opening a FIFO or a device may block or have side effects of its own.
+		return nil
+	}
+	file, err := os.OpenFile(path, flag&^(os.O_CREATE|os.O_TRUNC), 0)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// FlagSetParser returns an int64 parser for use in a FieldTypeHandler, that parses a comma-separated list
+// of flag names (such as "read,write") in to the bitwise-OR of their values in names.  An empty string
+// is 0, and an unrecognized name is an error.
+//
+//	handlers := envconfig.MergeHandlers(envconfig.DefaultFieldTypeHandlers(), map[reflect.Type]envconfig.FieldTypeHandler{
+//		reflect.TypeOf(int64(0)): {
+//			Parsers: map[string]func(string) (interface{}, error){
+//				"perms": envconfig.FlagSetParser(map[string]int64{"read": 1, "write": 2, "exec": 4}),
+//			},
+//		},
+//	})
+func FlagSetParser(names map[string]int64) func(string) (interface{}, error) {
+	return func(str string) (interface{}, error) {
+		var ret int64
+		for _, name := range commaSplitTrim(str) {
+			bit, ok := names[name]
+			if !ok {
+				known := make([]string, 0, len(names))
+				for name := range names {
+					known = append(known, name)
+				}
+				sort.Strings(known)
+				return nil, errors.Errorf("unknown flag %q: must be one of %v", name, known)
+			}
+			ret |= bit
+		}
+		return ret, nil
+	}
+}
+
 // DefaultFieldTypeHandlers returns a map of the struct field type handlers that are used if a nil
 // map is passed to GenerateParser.  A new map is allocated on each call; mutating the map will not
 // change the defaults.
@@ -38,7 +811,7 @@ func DefaultFieldTypeHandlers() map[reflect.Type]FieldTypeHandler {
 
 	//nolint:unparam,wrapcheck // These are all implemnting the same interface; can't remove any
 	// params.  The caller parser will wrap errors.
-	return map[reflect.Type]FieldTypeHandler{
+	ret := map[reflect.Type]FieldTypeHandler{
 		// string
 		reflect.TypeOf(""): {
 			Parsers: map[string]func(string) (interface{}, error){
@@ -49,6 +822,21 @@ func DefaultFieldTypeHandlers() map[reflect.Type]FieldTypeHandler {
 					return str, nil
 				},
 				"possibly-empty-string": func(str string) (interface{}, error) { return str, nil },
+				"valid-utf8": func(str string) (interface{}, error) {
+					if !utf8.ValidString(str) {
+						return nil, errors.Errorf("invalid UTF-8: %q", str)
+					}
+					return str, nil
+				},
+				// A Go double-quoted (or backquoted) string literal, such as "a\tb\x41".
+				"unquote": func(str string) (interface{}, error) { return strconv.Unquote(str) },
+				"gzip-base64": func(str string) (interface{}, error) {
+					bs, err := parseGzipBase64(str)
+					if err != nil {
+						return nil, err
+					}
+					return string(bs.([]byte)), nil
+				},
 				"logrus.ParseLevel": func(str string) (interface{}, error) {
 					if _, err := logrus.ParseLevel(str); err != nil {
 						return nil, err
@@ -56,6 +844,9 @@ func DefaultFieldTypeHandlers() map[reflect.Type]FieldTypeHandler {
 					return str, nil
 				},
 			},
+			ContextParsers: map[string]func(context.Context, string) (interface{}, error){
+				"resolvable-host": parseResolvableHost,
+			},
 			Setter: func(dst reflect.Value, src interface{}) { dst.SetString(src.(string)) },
 		},
 
@@ -64,6 +855,12 @@ func DefaultFieldTypeHandlers() map[reflect.Type]FieldTypeHandler {
 			Parsers: map[string]func(string) (interface{}, error){
 				"empty/nonempty":    func(str string) (interface{}, error) { return str != "", nil },
 				"strconv.ParseBool": func(str string) (interface{}, error) { return strconv.ParseBool(str) },
+				"empty-or-bool": func(str string) (interface{}, error) {
+					if str == "" {
+						return false, nil
+					}
+					return strconv.ParseBool(str)
+				},
 			},
 			Setter: func(dst reflect.Value, src interface{}) { dst.SetBool(src.(bool)) },
 		},
@@ -72,9 +869,11 @@ func DefaultFieldTypeHandlers() map[reflect.Type]FieldTypeHandler {
 		reflect.TypeOf(int(0)): {
 			Parsers: map[string]func(string) (interface{}, error){
 				"strconv.ParseInt": func(str string) (interface{}, error) {
-					i64, err := strconv.ParseInt(str, 10, 0)
+					i64, err := parseIntBits(str, strconv.IntSize)
 					return int(i64), err
 				},
+				// A percentage ("1.5%") in basis points (150), or a bare number of basis points.
+				"basis-points": parseBasisPoints,
 			},
 			Setter: func(dst reflect.Value, src interface{}) { dst.SetInt(int64(src.(int))) },
 		},
@@ -82,26 +881,148 @@ func DefaultFieldTypeHandlers() map[reflect.Type]FieldTypeHandler {
 		// int64
 		reflect.TypeOf(int64(0)): {
 			Parsers: map[string]func(string) (interface{}, error){
-				"strconv.ParseInt": func(str string) (interface{}, error) { return strconv.ParseInt(str, 10, 64) },
+				"strconv.ParseInt": func(str string) (interface{}, error) { return parseIntBits(str, 64) },
 			},
 			Setter: func(dst reflect.Value, src interface{}) { dst.SetInt(src.(int64)) },
 		},
 
+		// int32
+		reflect.TypeOf(int32(0)): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"strconv.ParseInt": func(str string) (interface{}, error) {
+					i64, err := parseIntBits(str, 32)
+					return int32(i64), err
+				},
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.SetInt(int64(src.(int32))) },
+		},
+
+		// int16
+		reflect.TypeOf(int16(0)): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"strconv.ParseInt": func(str string) (interface{}, error) {
+					i64, err := parseIntBits(str, 16)
+					return int16(i64), err
+				},
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.SetInt(int64(src.(int16))) },
+		},
+
+		// int8
+		reflect.TypeOf(int8(0)): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"strconv.ParseInt": func(str string) (interface{}, error) {
+					i64, err := parseIntBits(str, 8)
+					return int8(i64), err
+				},
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.SetInt(int64(src.(int8))) },
+		},
+
+		// uint
+		reflect.TypeOf(uint(0)): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"strconv.ParseUint": func(str string) (interface{}, error) {
+					u64, err := parseUintBits(str, strconv.IntSize)
+					return uint(u64), err
+				},
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.SetUint(uint64(src.(uint))) },
+		},
+
+		// uint64
+		reflect.TypeOf(uint64(0)): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"strconv.ParseUint": func(str string) (interface{}, error) { return parseUintBits(str, 64) },
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.SetUint(src.(uint64)) },
+		},
+
 		// float32
 		reflect.TypeOf(float32(0)): {
 			Parsers: map[string]func(string) (interface{}, error){
 				"strconv.ParseFloat": func(str string) (interface{}, error) {
 					f, err := strconv.ParseFloat(str, 32)
+					if errors.Is(err, strconv.ErrRange) && (f > math.MaxFloat32 || f < -math.MaxFloat32) {
+						return nil, errors.Errorf("%q is out of range for float32 (maximum magnitude is %g)", str, math.MaxFloat32)
+					}
 					return float32(f), err
 				},
 			},
 			Setter: func(dst reflect.Value, src interface{}) { dst.SetFloat(float64(src.(float32))) },
 		},
 
+		// float64
+		reflect.TypeOf(float64(0)): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"strconv.ParseFloat": func(str string) (interface{}, error) {
+					f, err := strconv.ParseFloat(str, 64)
+					if errors.Is(err, strconv.ErrRange) && (f > math.MaxFloat64 || f < -math.MaxFloat64) {
+						return nil, errors.Errorf("%q is out of range for float64 (maximum magnitude is %g)", str, math.MaxFloat64)
+					}
+					return f, err
+				},
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.SetFloat(src.(float64)) },
+		},
+
+		// os.FileMode
+		reflect.TypeOf(os.FileMode(0)): {
+			Parsers: map[string]func(string) (interface{}, error){
+				// Permission bits in octal, with or without a leading "0" ("0644" or "644").
+				"octal-filemode": func(str string) (interface{}, error) {
+					mode, err := strconv.ParseUint(str, 8, 32)
+					if err != nil {
+						return nil, err
+					}
+					if mode > uint64(os.ModePerm) {
+						return nil, errors.Errorf("file mode %q is out of range (maximum is %#o)", str, os.ModePerm)
+					}
+					return os.FileMode(mode), nil
+				},
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.SetUint(uint64(src.(os.FileMode))) },
+		},
+
+		// *regexp.Regexp
+		reflect.TypeOf((*regexp.Regexp)(nil)): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"regexp.Compile": func(str string) (interface{}, error) { return regexp.Compile(str) },
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src.(*regexp.Regexp))) },
+		},
+
+		// *template.Template
+		reflect.TypeOf((*template.Template)(nil)): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"text-template": func(str string) (interface{}, error) { return template.New("").Parse(str) },
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src.(*template.Template))) },
+		},
+
+		// time.Weekday
+		reflect.TypeOf(time.Weekday(0)): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"weekday": parseWeekday,
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.SetInt(int64(src.(time.Weekday))) },
+		},
+
+		// time.Month
+		reflect.TypeOf(time.Month(0)): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"month": parseMonth,
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.SetInt(int64(src.(time.Month))) },
+		},
+
 		// *url.URL
 		reflect.TypeOf((*url.URL)(nil)): {
 			Parsers: map[string]func(string) (interface{}, error){
 				"absolute-URL": parseURL,
+				"base-URL":     parseBaseURL,
+				"any-URI":      parseAnyURI,
+				"relative-URL": parseRelativeURL,
 				"possibly-empty-absolute-URL": func(str string) (interface{}, error) {
 					if str == "" {
 						return nil, nil
@@ -112,37 +1033,505 @@ func DefaultFieldTypeHandlers() map[reflect.Type]FieldTypeHandler {
 			Setter: func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src.(*url.URL))) },
 		},
 
+		// []*url.URL
+		reflect.TypeOf([]*url.URL{}): {
+			Parsers: map[string]func(string) (interface{}, error){
+				// Each item is parsed like "absolute-URL".
+				"comma-split-absolute-URL": func(str string) (interface{}, error) {
+					items := commaSplitTrim(str)
+					ret := make([]*url.URL, 0, len(items))
+					for i, item := range items {
+						u, err := parseURL(item)
+						if err != nil {
+							return nil, errors.Wrapf(err, "item %d (%q)", i, item)
+						}
+						ret = append(ret, u.(*url.URL))
+					}
+					return ret, nil
+				},
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src)) },
+		},
+
 		// time.Duration
 		reflect.TypeOf(time.Duration(0)): {
 			Parsers: map[string]func(string) (interface{}, error){
 				"integer-seconds": func(str string) (interface{}, error) {
-					secs, err := strconv.Atoi(str)
+					secs, err := parseIntBits(str, 64)
 					if err != nil {
 						return nil, err
 					}
+					if maxSecs := int64(math.MaxInt64 / time.Second); secs > maxSecs || secs < -maxSecs {
+						return nil, errors.Errorf("%q seconds is out of range for time.Duration (must be between %d and %d)", str, -maxSecs, maxSecs)
+					}
 					return time.Duration(secs) * time.Second, nil
 				},
 				"time.ParseDuration": func(str string) (interface{}, error) { return time.ParseDuration(str) },
+				"duration-or-never": func(str string) (interface{}, error) {
+					switch strings.ToLower(strings.TrimSpace(str)) {
+					case "never", "infinite":
+						return DurationNever, nil
+					}
+					d, err := time.ParseDuration(str)
+					if err != nil {
+						return nil, err
+					}
+					if d == 0 {
+						// "0", "0s", etc.
+						return DurationNever, nil
+					}
+					return d, nil
+				},
 			},
 			Setter: func(dst reflect.Value, src interface{}) { dst.SetInt(int64(src.(time.Duration))) },
 		},
+
+		// time.Time
+		reflect.TypeOf(time.Time{}): {
+			Parsers: map[string]func(string) (interface{}, error){
+				// The time keeps the offset given in the string.
+				"time.RFC3339": func(str string) (interface{}, error) { return time.Parse(time.RFC3339, str) },
+				// Seconds since the Unix epoch, as a time in UTC (rather than in time.Local), so that the
+				// result doesn't depend on the machine's time zone.
+				"unix-seconds": func(str string) (interface{}, error) {
+					n, err := parseIntBits(str, 64)
+					if err != nil {
+						return nil, err
+					}
+					return time.Unix(n, 0).UTC(), nil
+				},
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src.(time.Time))) },
+		},
+
+		// Money
+		reflect.TypeOf(Money{}): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"currency-amount": parseMoney,
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src)) },
+		},
+
+		// DurationRange
+		reflect.TypeOf(DurationRange{}): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"duration-range": parseDurationRange,
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src)) },
+		},
 		// []string
 		reflect.TypeOf([]string{}): {
 			Parsers: map[string]func(string) (interface{}, error){
-				"comma-split-trim": func(str string) (interface{}, error) {
-					// We don't want strings.Split to create a one element slice for an empty string so
-					// a special check is needed for that here.
+				"comma-split-trim": func(str string) (interface{}, error) { return commaSplitTrim(str), nil },
+				"shell-split":      func(str string) (interface{}, error) { return shellSplit(str) },
+				"newline-split-trim": func(str string) (interface{}, error) {
+					// A trailing newline terminates the last line, rather than starting a new empty
+					// one.
+					str = strings.TrimSuffix(strings.TrimSuffix(str, "\n"), "\r")
 					if str == "" {
 						return []string{}, nil
 					}
-					ss := strings.Split(str, ",")
+					ss := strings.Split(str, "\n")
 					for i, s := range ss {
+						// This also trims the "\r" of "\r\n" line endings.
 						ss[i] = strings.TrimSpace(s)
 					}
 					return ss, nil
 				},
+				// os.PathListSeparator-separated, such as $PATH.
+				"filepath.SplitList": func(str string) (interface{}, error) { return filepath.SplitList(str), nil },
 			},
 			Setter: func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src)) },
 		},
+
+		// []rune
+		reflect.TypeOf([]rune{}): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"runes": func(str string) (interface{}, error) { return []rune(str), nil },
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src)) },
+		},
+
+		// *net.IPNet
+		reflect.TypeOf((*net.IPNet)(nil)): {
+			Parsers: map[string]func(string) (interface{}, error){
+				// The network, without the host part of the address; so "10.1.2.3/8" is 10.0.0.0/8.
+				"net.ParseCIDR": func(str string) (interface{}, error) {
+					_, ipnet, err := net.ParseCIDR(str)
+					if err != nil {
+						return nil, err
+					}
+					return ipnet, nil
+				},
+				"possibly-empty-CIDR": func(str string) (interface{}, error) {
+					if str == "" {
+						return nil, nil
+					}
+					_, ipnet, err := net.ParseCIDR(str)
+					if err != nil {
+						return nil, err
+					}
+					return ipnet, nil
+				},
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src.(*net.IPNet))) },
+		},
+
+		// []*net.IPNet
+		reflect.TypeOf([]*net.IPNet{}): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"comma-split-CIDR": func(str string) (interface{}, error) {
+					elems := commaSplitTrim(str)
+					ret := make([]*net.IPNet, 0, len(elems))
+					for _, elem := range elems {
+						_, ipnet, err := net.ParseCIDR(elem)
+						if err != nil {
+							return nil, err
+						}
+						ret = append(ret, ipnet)
+					}
+					return ret, nil
+				},
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src)) },
+		},
+
+		// *os.File
+		reflect.TypeOf((*os.File)(nil)): {
+			ContextParsers: map[string]func(context.Context, string) (interface{}, error){
+				"os.OpenFile": parseOpenFile,
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src.(*os.File))) },
+		},
+
+		// net.IPMask
+		reflect.TypeOf(net.IPMask(nil)): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"mask-or-prefix-length": parseIPMask,
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src)) },
+		},
+
+		// net.Addr
+		reflect.TypeOf((*net.Addr)(nil)).Elem(): {
+			ContextParsers: map[string]func(context.Context, string) (interface{}, error){
+				"network-addr": parseNetAddr,
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src)) },
+		},
+
+		// []int
+		reflect.TypeOf([]int(nil)): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"comma-split-int": func(str string) (interface{}, error) {
+					elems := commaSplitTrim(str)
+					ret := make([]int, 0, len(elems))
+					for i, elem := range elems {
+						n, err := parseIntBits(elem, strconv.IntSize)
+						if err != nil {
+							return nil, errors.Wrapf(err, "item %d (%q)", i, elem)
+						}
+						ret = append(ret, int(n))
+					}
+					return ret, nil
+				},
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src)) },
+		},
+
+		// []uint16
+		reflect.TypeOf([]uint16{}): {
+			Parsers: map[string]func(string) (interface{}, error){
+				// TCP/UDP port numbers (0-65535).
+				"comma-split-ports": func(str string) (interface{}, error) {
+					elems := commaSplitTrim(str)
+					ret := make([]uint16, 0, len(elems))
+					for _, elem := range elems {
+						port, err := strconv.ParseUint(elem, 10, 16)
+						if err != nil {
+							return nil, errors.Errorf("invalid port %q", elem)
+						}
+						ret = append(ret, uint16(port))
+					}
+					return ret, nil
+				},
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src)) },
+		},
+
+		// map[string]string
+		reflect.TypeOf(map[string]string{}): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"dotenv": parseDotenv,
+				// If a key appears more than once, the last value wins.
+				"comma-split-kv": func(str string) (interface{}, error) {
+					pairs, err := splitKeyValues(str)
+					if err != nil {
+						return nil, err
+					}
+					ret := make(map[string]string, len(pairs))
+					for _, pair := range pairs {
+						ret[pair[0]] = pair[1]
+					}
+					return ret, nil
+				},
+				// Like "comma-split-kv", but each key must be an IPv4 or IPv6 address, which is
+				// normalized with net.IP.String (so "::FFFF:10.0.0.1" becomes "10.0.0.1").  If two keys
+				// are the same address once normalized, the last value wins.
+				"comma-split-ip-kv": func(str string) (interface{}, error) {
+					pairs, err := splitKeyValues(str)
+					if err != nil {
+						return nil, err
+					}
+					ret := make(map[string]string, len(pairs))
+					for _, pair := range pairs {
+						ip := net.ParseIP(pair[0])
+						if ip == nil {
+							return nil, errors.Errorf("invalid IP address %q", pair[0])
+						}
+						ret[ip.String()] = pair[1]
+					}
+					return ret, nil
+				},
+				"json": func(str string) (interface{}, error) {
+					var ret map[string]string
+					if err := json.Unmarshal([]byte(str), &ret); err != nil {
+						return nil, err
+					}
+					if ret == nil {
+						// JSON "null"
+						ret = map[string]string{}
+					}
+					return ret, nil
+				},
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src)) },
+		},
+
+		// map[string]time.Duration
+		reflect.TypeOf(map[string]time.Duration{}): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"comma-split-kv": func(str string) (interface{}, error) {
+					pairs, err := splitKeyValues(str)
+					if err != nil {
+						return nil, err
+					}
+					ret := make(map[string]time.Duration, len(pairs))
+					for _, pair := range pairs {
+						d, err := time.ParseDuration(pair[1])
+						if err != nil {
+							return nil, errors.Wrapf(err, "key %q", pair[0])
+						}
+						ret[pair[0]] = d
+					}
+					return ret, nil
+				},
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src)) },
+		},
+
+		// json.Number
+		reflect.TypeOf(json.Number("")): {
+			Parsers: map[string]func(string) (interface{}, error){
+				// Kept as a string, so that large integers and decimals don't lose precision.
+				"json-number": func(str string) (interface{}, error) {
+					// Decoding in to an interface{} (rather than in to a json.Number) rejects quoted
+					// strings, and json.Valid rejects trailing garbage.
+					var val interface{}
+					dec := json.NewDecoder(strings.NewReader(str))
+					dec.UseNumber()
+					err := dec.Decode(&val)
+					num, isNum := val.(json.Number)
+					if err != nil || !isNum || !json.Valid([]byte(str)) {
+						return nil, errors.Errorf("invalid JSON number %q", str)
+					}
+					return num, nil
+				},
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.SetString(string(src.(json.Number))) },
+		},
+
+		// map[string]bool
+		reflect.TypeOf(map[string]bool{}): {
+			Parsers: map[string]func(string) (interface{}, error){
+				// Feature toggles; a bare "key" is the same as "key=true".
+				"comma-split-kv": func(str string) (interface{}, error) {
+					elems := commaSplitTrim(str)
+					ret := make(map[string]bool, len(elems))
+					for _, elem := range elems {
+						keyval := strings.SplitN(elem, "=", 2)
+						key := strings.TrimSpace(keyval[0])
+						if key == "" {
+							return nil, errors.Errorf("empty key in %q", elem)
+						}
+						if len(keyval) == 1 {
+							ret[key] = true
+							continue
+						}
+						b, err := strconv.ParseBool(strings.TrimSpace(keyval[1]))
+						if err != nil {
+							return nil, errors.Wrapf(err, "key %q", key)
+						}
+						ret[key] = b
+					}
+					return ret, nil
+				},
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src)) },
+		},
+
+		// []KeyValue
+		reflect.TypeOf([]KeyValue{}): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"comma-split-kv": func(str string) (interface{}, error) {
+					pairs, err := splitKeyValues(str)
+					if err != nil {
+						return nil, err
+					}
+					ret := make([]KeyValue, 0, len(pairs))
+					for _, pair := range pairs {
+						ret = append(ret, KeyValue{Key: pair[0], Value: pair[1]})
+					}
+					return ret, nil
+				},
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src)) },
+		},
+
+		// []WeightedEntry
+		reflect.TypeOf([]WeightedEntry{}): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"comma-split-weighted": parseWeightedEntries,
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src)) },
+		},
+
+		// io.Reader
+		reflect.TypeOf((*io.Reader)(nil)).Elem(): {
+			ContextParsers: map[string]func(context.Context, string) (interface{}, error){
+				"inline-or-@file": parseReader,
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src)) },
+		},
+
+		// []byte
+		reflect.TypeOf([]byte{}): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"bytes-auto":  parseBytesAuto,
+				"gzip-base64": parseGzipBase64,
+				"unquote": func(str string) (interface{}, error) {
+					unquoted, err := strconv.Unquote(str)
+					if err != nil {
+						return nil, err
+					}
+					return []byte(unquoted), nil
+				},
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.SetBytes(src.([]byte)) },
+		},
+
+		// logrus.Level
+		reflect.TypeOf(logrus.Level(0)): {
+			Parsers: map[string]func(string) (interface{}, error){
+				// logrus.ParseLevel is already case-insensitive, and accepts both "warn" and "warning".
+				"logrus-level": func(str string) (interface{}, error) { return logrus.ParseLevel(strings.TrimSpace(str)) },
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.SetUint(uint64(src.(logrus.Level))) },
+		},
+
+		// sql.NullString
+		reflect.TypeOf(sql.NullString{}): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"possibly-empty-string": func(str string) (interface{}, error) {
+					return sql.NullString{String: str, Valid: true}, nil
+				},
+			},
+			Setter:   func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src)) },
+			Optional: true,
+		},
+
+		// sql.NullInt64
+		reflect.TypeOf(sql.NullInt64{}): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"strconv.ParseInt": func(str string) (interface{}, error) {
+					i64, err := parseIntBits(str, 64)
+					if err != nil {
+						return nil, err
+					}
+					return sql.NullInt64{Int64: i64, Valid: true}, nil
+				},
+			},
+			Setter:   func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src)) },
+			Optional: true,
+		},
+
+		// sql.NullBool
+		reflect.TypeOf(sql.NullBool{}): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"strconv.ParseBool": func(str string) (interface{}, error) {
+					b, err := strconv.ParseBool(str)
+					if err != nil {
+						return nil, err
+					}
+					return sql.NullBool{Bool: b, Valid: true}, nil
+				},
+			},
+			Setter:   func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src)) },
+			Optional: true,
+		},
+
+		// sql.NullFloat64
+		reflect.TypeOf(sql.NullFloat64{}): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"strconv.ParseFloat": func(str string) (interface{}, error) {
+					f, err := strconv.ParseFloat(str, 64)
+					if err != nil {
+						return nil, err
+					}
+					return sql.NullFloat64{Float64: f, Valid: true}, nil
+				},
+			},
+			Setter:   func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src)) },
+			Optional: true,
+		},
+	}
+
+	// *atomic.Value
+	//
+	// The value stored in the atomic.Value is of the type that the same-named parser returns for
+	// the type in the comment.
+	ret[reflect.TypeOf((*atomic.Value)(nil))] = FieldTypeHandler{
+		Parsers: map[string]func(string) (interface{}, error){
+			"nonempty-string":       atomicValueParser(ret[reflect.TypeOf("")].Parsers["nonempty-string"]),       // string
+			"possibly-empty-string": atomicValueParser(ret[reflect.TypeOf("")].Parsers["possibly-empty-string"]), // string
+			"strconv.ParseBool":     atomicValueParser(ret[reflect.TypeOf(false)].Parsers["strconv.ParseBool"]),  // bool
+			"strconv.ParseInt":      atomicValueParser(ret[reflect.TypeOf(int(0))].Parsers["strconv.ParseInt"]),  // int
+			"time.ParseDuration":    atomicValueParser(ret[reflect.TypeOf(time.Duration(0))].Parsers["time.ParseDuration"]),
+			"absolute-URL":          atomicValueParser(ret[reflect.TypeOf((*url.URL)(nil))].Parsers["absolute-URL"]),
+		},
+		// Store in to the existing atomic.Value (if there is one) rather than replacing the pointer, so
+		// that anything holding on to the pointer sees the new value when the struct is re-parsed.
+		Setter: func(dst reflect.Value, src interface{}) {
+			if dst.IsNil() {
+				dst.Set(reflect.ValueOf(new(atomic.Value)))
+			}
+			dst.Interface().(*atomic.Value).Store(src.(*atomic.Value).Load())
+		},
+	}
+	addAtomicTypeHandlers(ret)
+
+	return ret
+}
+
+// atomicValueParser wraps a parser so that it returns its result in a new *atomic.Value.
+func atomicValueParser(parser func(string) (interface{}, error)) func(string) (interface{}, error) {
+	return func(str string) (interface{}, error) {
+		val, err := parser(str)
+		if err != nil {
+			return nil, err
+		}
+		ret := new(atomic.Value)
+		ret.Store(val)
+		return ret, nil
 	}
 }
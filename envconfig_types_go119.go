@@ -0,0 +1,58 @@
+//go:build go1.19
+// +build go1.19
+
+package envconfig
+
+import (
+	"reflect"
+	"strconv"
+	"sync/atomic"
+)
+
+// addAtomicTypeHandlers adds handlers for the sync/atomic types that were added in Go 1.19.  Like
+// *atomic.Value, the Setters store in to the existing value (if there is one) rather than replacing the
+// pointer, so that a re-parse updates the value seen by anything holding on to the pointer; such as a
+// runtime-tunable knob that is read concurrently.
+func addAtomicTypeHandlers(ret map[reflect.Type]FieldTypeHandler) {
+	// *atomic.Int64
+	ret[reflect.TypeOf((*atomic.Int64)(nil))] = FieldTypeHandler{
+		Parsers: map[string]func(string) (interface{}, error){
+			"strconv.ParseInt": func(str string) (interface{}, error) {
+				i, err := parseIntBits(str, 64)
+				if err != nil {
+					return nil, err
+				}
+				ret := new(atomic.Int64)
+				ret.Store(i)
+				return ret, nil
+			},
+		},
+		Setter: func(dst reflect.Value, src interface{}) {
+			if dst.IsNil() {
+				dst.Set(reflect.ValueOf(new(atomic.Int64)))
+			}
+			dst.Interface().(*atomic.Int64).Store(src.(*atomic.Int64).Load())
+		},
+	}
+
+	// *atomic.Bool
+	ret[reflect.TypeOf((*atomic.Bool)(nil))] = FieldTypeHandler{
+		Parsers: map[string]func(string) (interface{}, error){
+			"strconv.ParseBool": func(str string) (interface{}, error) {
+				b, err := strconv.ParseBool(str)
+				if err != nil {
+					return nil, err
+				}
+				ret := new(atomic.Bool)
+				ret.Store(b)
+				return ret, nil
+			},
+		},
+		Setter: func(dst reflect.Value, src interface{}) {
+			if dst.IsNil() {
+				dst.Set(reflect.ValueOf(new(atomic.Bool)))
+			}
+			dst.Interface().(*atomic.Bool).Store(src.(*atomic.Bool).Load())
+		},
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !go1.19
+// +build !go1.19
+
+package envconfig
+
+import (
+	"reflect"
+)
+
+// addAtomicTypeHandlers adds handlers for the sync/atomic types that were added in Go 1.19; which is
+// nothing, before Go 1.19.
+func addAtomicTypeHandlers(map[reflect.Type]FieldTypeHandler) {}
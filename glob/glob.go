@@ -0,0 +1,35 @@
+// Package glob provides envconfig parsers for shell-style patterns compiled with github.com/gobwas/glob:
+// "glob", where "*" also matches "/", and "path-glob", where it doesn't.  gobwas/glob is not a
+// dependency of envconfig itself, so merge these handlers in where they're wanted:
+//
+//	handlers := envconfig.MergeHandlers(envconfig.DefaultFieldTypeHandlers(), glob.FieldTypeHandlers())
+//	parser, err := envconfig.GenerateParser(reflect.TypeOf(Config{}), handlers)
+package glob
+
+import (
+	"reflect"
+
+	"github.com/gobwas/glob"
+
+	"github.com/datawire/envconfig"
+)
+
+// FieldTypeHandlers returns a map of struct field type handlers for glob.Glob fields.  A new map is
+// allocated on each call.
+func FieldTypeHandlers() map[reflect.Type]envconfig.FieldTypeHandler {
+	// If you add something to this, please add a test case for it to TestGlob.
+
+	//nolint:wrapcheck // A pattern syntax error is wrapped with the field name by the caller.
+	return map[reflect.Type]envconfig.FieldTypeHandler{
+		// glob.Glob
+		reflect.TypeOf((*glob.Glob)(nil)).Elem(): {
+			Parsers: map[string]func(string) (interface{}, error){
+				// "*" matches any sequence of characters, including "/".
+				"glob": func(str string) (interface{}, error) { return glob.Compile(str) },
+				// "*" matches any sequence of characters except "/"; use "**" to also match "/".
+				"path-glob": func(str string) (interface{}, error) { return glob.Compile(str, '/') },
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src.(glob.Glob))) },
+		},
+	}
+}
@@ -0,0 +1,96 @@
+package glob_test
+
+import (
+	"reflect"
+	"testing"
+
+	gobwasglob "github.com/gobwas/glob"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/datawire/envconfig"
+	"github.com/datawire/envconfig/glob"
+)
+
+type testEnv map[string]string
+
+func (e testEnv) lookup(key string) (string, bool) {
+	v, ok := e[key]
+	return v, ok
+}
+
+func TestGlob(t *testing.T) {
+	var config struct {
+		Any  gobwasglob.Glob `env:"ANY  ,parser=glob      "`
+		Path gobwasglob.Glob `env:"PATH ,parser=path-glob "`
+	}
+	handlers := envconfig.MergeHandlers(envconfig.DefaultFieldTypeHandlers(), glob.FieldTypeHandlers())
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), handlers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		Matches     []string
+		NonMatches  []string
+		ExpectError bool
+	}{
+		"star": {
+			Input:      "/var/log/*.log",
+			Matches:    []string{"/var/log/app.log"},
+			NonMatches: []string{"/var/log/app.txt"},
+		},
+		"star-slash": {
+			Input:      "/var/*.log",
+			Matches:    []string{"/var/app.log"},
+			NonMatches: []string{"/var/log/app.txt"},
+		},
+		"alternatives": {
+			Input:      "*.{json,yaml}",
+			Matches:    []string{"config.json", "config.yaml"},
+			NonMatches: []string{"config.toml"},
+		},
+		"unclosed-class": {
+			Input:       "[a-",
+			ExpectError: true,
+		},
+		"unclosed-range": {
+			Input:       "log[0-",
+			ExpectError: true,
+		},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Any, config.Path = nil, nil
+			warn, fatal := parser.ParseFromEnv(&config, testEnv{"ANY": tc.Input, "PATH": tc.Input}.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError {
+				assert.Equal(t, len(fatal), 2, "There should be 1 fatal error per field")
+				assert.Nil(t, config.Any)
+				assert.Nil(t, config.Path)
+				return
+			}
+			assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+			if assert.NotNil(t, config.Any) && assert.NotNil(t, config.Path) {
+				for _, str := range tc.Matches {
+					assert.True(t, config.Any.Match(str), "glob %q should match %q", tc.Input, str)
+					assert.True(t, config.Path.Match(str), "path-glob %q should match %q", tc.Input, str)
+				}
+				for _, str := range tc.NonMatches {
+					assert.False(t, config.Any.Match(str), "glob %q should not match %q", tc.Input, str)
+					assert.False(t, config.Path.Match(str), "path-glob %q should not match %q", tc.Input, str)
+				}
+			}
+		})
+	}
+
+	t.Run("separator", func(t *testing.T) {
+		config.Any, config.Path = nil, nil
+		warn, fatal := parser.ParseFromEnv(&config, testEnv{"ANY": "/var/*.log", "PATH": "/var/*.log"}.lookup)
+		assert.Equal(t, len(warn), 0, "There should be no warnings")
+		assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+		assert.True(t, config.Any.Match("/var/log/app.log"))
+		assert.False(t, config.Path.Match("/var/log/app.log"))
+	})
+}
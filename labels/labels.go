@@ -0,0 +1,35 @@
+// Package labels provides an envconfig parser for Kubernetes label selectors (labels.Selector from
+// k8s.io/apimachinery), so that a selector such as "app=web,env in (prod,staging)" can be configured
+// with an env-var.  apimachinery is large, so this lives in its own module instead of in envconfig's
+// DefaultFieldTypeHandlers; merge its handlers in:
+//
+//	handlers := envconfig.MergeHandlers(envconfig.DefaultFieldTypeHandlers(), labels.FieldTypeHandlers())
+//	parser, err := envconfig.GenerateParser(reflect.TypeOf(Config{}), handlers)
+package labels
+
+import (
+	"reflect"
+
+	k8slabels "k8s.io/apimachinery/pkg/labels"
+
+	"github.com/datawire/envconfig"
+)
+
+// FieldTypeHandlers returns a map of struct field type handlers for labels.Selector fields.  A new map
+// is allocated on each call.
+func FieldTypeHandlers() map[reflect.Type]envconfig.FieldTypeHandler {
+	// If you add something to this, please add a test case for it to TestSelector.
+
+	//nolint:wrapcheck // A selector syntax error is wrapped with the field name by the caller.
+	return map[reflect.Type]envconfig.FieldTypeHandler{
+		// labels.Selector
+		reflect.TypeOf((*k8slabels.Selector)(nil)).Elem(): {
+			Parsers: map[string]func(string) (interface{}, error){
+				// labels.Parse accepts comma-separated requirements such as
+				// "app=web,tier!=db,env in (prod,staging)"; an empty string selects everything.
+				"labels.Parse": func(str string) (interface{}, error) { return k8slabels.Parse(str) },
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src.(k8slabels.Selector))) },
+		},
+	}
+}
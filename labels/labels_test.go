@@ -0,0 +1,78 @@
+package labels_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
+
+	"github.com/datawire/envconfig"
+	"github.com/datawire/envconfig/labels"
+)
+
+type testEnv map[string]string
+
+func (e testEnv) lookup(key string) (string, bool) {
+	v, ok := e[key]
+	return v, ok
+}
+
+func TestSelector(t *testing.T) {
+	var config struct {
+		Selector k8slabels.Selector `env:"SELECTOR ,parser=labels.Parse "`
+	}
+	handlers := envconfig.MergeHandlers(envconfig.DefaultFieldTypeHandlers(), labels.FieldTypeHandlers())
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), handlers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input       string
+		Matches     map[string]string
+		NotMatches  map[string]string
+		ExpectError bool
+	}{
+		"equality": {
+			Input:      "app=web,tier!=db",
+			Matches:    map[string]string{"app": "web", "tier": "frontend"},
+			NotMatches: map[string]string{"app": "web", "tier": "db"},
+		},
+		"set": {
+			Input:      "env in (prod, staging),!legacy",
+			Matches:    map[string]string{"env": "staging"},
+			NotMatches: map[string]string{"env": "prod", "legacy": "true"},
+		},
+		"empty": {
+			Input:   "",
+			Matches: map[string]string{"anything": "goes"},
+		},
+		"malformed": {
+			Input:       "=web",
+			ExpectError: true,
+		},
+		"unclosed-set": {
+			Input:       "env in (prod",
+			ExpectError: true,
+		},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Selector = nil
+			warn, fatal := parser.ParseFromEnv(&config, testEnv{"SELECTOR": tc.Input}.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError {
+				assert.Equal(t, len(fatal), 1, "There should be 1 fatal error")
+				assert.Nil(t, config.Selector)
+				return
+			}
+			assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+			assert.True(t, config.Selector.Matches(k8slabels.Set(tc.Matches)))
+			if tc.NotMatches != nil {
+				assert.False(t, config.Selector.Matches(k8slabels.Set(tc.NotMatches)))
+			}
+		})
+	}
+}
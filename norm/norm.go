@@ -0,0 +1,30 @@
+// Package norm provides the Unicode normalization forms of golang.org/x/text/unicode/norm for envconfig's
+// "normalize" tag option.  golang.org/x/text is not a dependency of envconfig itself, so pass these in
+// where they're wanted:
+//
+//	parser, err := envconfig.GenerateParserWithOptions(reflect.TypeOf(Config{}), envconfig.GenerateOptions{
+//		Normalizers: norm.Normalizers(),
+//	})
+//
+// and then tag a string field with, for example, `env:"NAME,parser=nonempty-string,normalize=NFC"`.
+package norm
+
+import (
+	xnorm "golang.org/x/text/unicode/norm"
+)
+
+// Normalizers returns a map of the Unicode normalization forms, by name ("NFC", "NFD", "NFKC" and
+// "NFKD"), for GenerateOptions.Normalizers.  A new map is allocated on each call.
+func Normalizers() map[string]func(string) string {
+	// If you add something to this, please add a test case for it to TestNormalizers.
+	return map[string]func(string) string{
+		// Canonical composition, so "e" followed by a combining acute accent becomes a single "é".
+		"NFC": xnorm.NFC.String,
+		// Canonical decomposition, so "é" becomes "e" followed by a combining acute accent.
+		"NFD": xnorm.NFD.String,
+		// Compatibility composition, so the "ﬁ" ligature also becomes "fi".
+		"NFKC": xnorm.NFKC.String,
+		// Compatibility decomposition.
+		"NFKD": xnorm.NFKD.String,
+	}
+}
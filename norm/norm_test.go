@@ -0,0 +1,68 @@
+package norm_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/datawire/envconfig"
+	"github.com/datawire/envconfig/norm"
+)
+
+type testEnv map[string]string
+
+func (e testEnv) lookup(key string) (string, bool) {
+	v, ok := e[key]
+	return v, ok
+}
+
+func TestNormalizers(t *testing.T) {
+	var config struct {
+		NFC  string `env:"NFC  ,parser=nonempty-string ,normalize=NFC  "`
+		NFD  string `env:"NFD  ,parser=nonempty-string ,normalize=NFD  "`
+		NFKC string `env:"NFKC ,parser=nonempty-string ,normalize=NFKC "`
+		NFKD string `env:"NFKD ,parser=nonempty-string ,normalize=NFKD "`
+	}
+	parser, err := envconfig.GenerateParserWithOptions(reflect.TypeOf(config), envconfig.GenerateOptions{
+		Normalizers: norm.Normalizers(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input    string
+		Expected [4]string // NFC, NFD, NFKC, NFKD
+	}{
+		"decomposed": {Input: "cafe\u0301", Expected: [4]string{"caf\u00e9", "cafe\u0301", "caf\u00e9", "cafe\u0301"}},
+		"composed":   {Input: "caf\u00e9", Expected: [4]string{"caf\u00e9", "cafe\u0301", "caf\u00e9", "cafe\u0301"}},
+		"ascii":      {Input: "cafe", Expected: [4]string{"cafe", "cafe", "cafe", "cafe"}},
+		"ligature":   {Input: "\ufb01le", Expected: [4]string{"\ufb01le", "\ufb01le", "file", "file"}},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			env := testEnv{"NFC": tc.Input, "NFD": tc.Input, "NFKC": tc.Input, "NFKD": tc.Input}
+			warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			assert.Equal(t, len(fatal), 0, "There should be no errors")
+			assert.Equal(t, tc.Expected, [4]string{config.NFC, config.NFD, config.NFKC, config.NFKD})
+		})
+	}
+
+	t.Run("invalid-utf8", func(t *testing.T) {
+		var config struct {
+			Name string `env:"NAME ,parser=nonempty-string ,normalize=NFC "`
+		}
+		parser, err := envconfig.GenerateParserWithOptions(reflect.TypeOf(config), envconfig.GenerateOptions{
+			Normalizers: norm.Normalizers(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, fatal := parser.ParseFromEnv(&config, testEnv{"NAME": "caf\xe9"}.lookup)
+		assert.Equal(t, len(fatal), 1, "There should be 1 fatal error")
+		assert.Equal(t, "", config.Name)
+	})
+}
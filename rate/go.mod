@@ -0,0 +1,22 @@
+module github.com/datawire/envconfig/rate
+
+go 1.17
+
+require (
+	github.com/datawire/envconfig v0.0.0-20261017203906-4946f9903a4f
+	github.com/pkg/errors v0.9.1
+	github.com/stretchr/testify v1.7.0
+	golang.org/x/time v0.3.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/sirupsen/logrus v1.8.1 // indirect
+	golang.org/x/sys v0.0.0-20191026070338-33540a1f6037 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
+)
+
+// The version required above is the baseline envconfig.  Build against the envconfig in this checkout
+// instead, for developing the two together; this only applies when building this module itself.
+replace github.com/datawire/envconfig => ../
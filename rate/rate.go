@@ -0,0 +1,72 @@
+// Package rate provides an envconfig parser that builds a *rate.Limiter (from golang.org/x/time/rate)
+// from a "COUNT/PER[;burst=N]" spec such as "100/s" or "5/10m;burst=20".  It is kept out of envconfig's
+// DefaultFieldTypeHandlers so that golang.org/x/time is only a dependency of programs that use it;
+// merge its handlers in:
+//
+//	handlers := envconfig.MergeHandlers(envconfig.DefaultFieldTypeHandlers(), rate.FieldTypeHandlers())
+//	parser, err := envconfig.GenerateParser(reflect.TypeOf(Config{}), handlers)
+package rate
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	xrate "golang.org/x/time/rate"
+
+	"github.com/datawire/envconfig"
+)
+
+// FieldTypeHandlers returns a map of struct field type handlers for *rate.Limiter fields.  A new map is
+// allocated on each call.
+func FieldTypeHandlers() map[reflect.Type]envconfig.FieldTypeHandler {
+	// If you add something to this, please add a test case for it to TestLimiter.
+	return map[reflect.Type]envconfig.FieldTypeHandler{
+		// *rate.Limiter
+		reflect.TypeOf((*xrate.Limiter)(nil)): {
+			Parsers: map[string]func(string) (interface{}, error){
+				"rate-limit": parseLimiter,
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src.(*xrate.Limiter))) },
+		},
+	}
+}
+
+// parseLimiter parses a "COUNT/PER[;burst=N]" spec, such as "100/s" or "5/10m;burst=20"; PER is a
+// duration, which may omit a leading "1".  The burst defaults to COUNT.
+func parseLimiter(str string) (interface{}, error) {
+	spec, burstStr := str, ""
+	if semi := strings.IndexByte(str, ';'); semi >= 0 {
+		spec = str[:semi]
+		opt := strings.TrimSpace(str[semi+1:])
+		if !strings.HasPrefix(opt, "burst=") {
+			return nil, errors.Errorf("invalid rate limit %q: unrecognized option %q", str, opt)
+		}
+		burstStr = strings.TrimPrefix(opt, "burst=")
+	}
+	parts := strings.Split(spec, "/")
+	if len(parts) != 2 {
+		return nil, errors.Errorf("invalid rate limit %q: not a COUNT/PER rate", str)
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || count < 0 {
+		return nil, errors.Errorf("invalid rate limit %q: invalid count %q", str, parts[0])
+	}
+	perStr := strings.TrimSpace(parts[1])
+	if perStr != "" && (perStr[0] < '0' || perStr[0] > '9') {
+		perStr = "1" + perStr
+	}
+	per, err := time.ParseDuration(perStr)
+	if err != nil || per <= 0 {
+		return nil, errors.Errorf("invalid rate limit %q: invalid period %q", str, parts[1])
+	}
+	burst := count
+	if burstStr != "" {
+		if burst, err = strconv.Atoi(burstStr); err != nil || burst < 0 {
+			return nil, errors.Errorf("invalid rate limit %q: invalid burst %q", str, burstStr)
+		}
+	}
+	return xrate.NewLimiter(xrate.Limit(float64(count)/per.Seconds()), burst), nil
+}
@@ -0,0 +1,66 @@
+package rate_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	xrate "golang.org/x/time/rate"
+
+	"github.com/datawire/envconfig"
+	"github.com/datawire/envconfig/rate"
+)
+
+type testEnv map[string]string
+
+func (e testEnv) lookup(key string) (string, bool) {
+	v, ok := e[key]
+	return v, ok
+}
+
+func TestLimiter(t *testing.T) {
+	var config struct {
+		Limiter *xrate.Limiter `env:"LIMIT ,parser=rate-limit "`
+	}
+	handlers := envconfig.MergeHandlers(envconfig.DefaultFieldTypeHandlers(), rate.FieldTypeHandlers())
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), handlers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input         string
+		ExpectedLimit xrate.Limit
+		ExpectedBurst int
+		ExpectError   bool
+	}{
+		"rate-only":       {Input: "100/s", ExpectedLimit: 100, ExpectedBurst: 100},
+		"rate-with-burst": {Input: "100/s;burst=10", ExpectedLimit: 100, ExpectedBurst: 10},
+		"per-minute":      {Input: "30/m", ExpectedLimit: 0.5, ExpectedBurst: 30},
+		"per-duration":    {Input: "5/500ms; burst=1", ExpectedLimit: 10, ExpectedBurst: 1},
+		"no-period":       {Input: "100", ExpectError: true},
+		"bad-count":       {Input: "lots/s", ExpectError: true},
+		"bad-period":      {Input: "100/fortnight", ExpectError: true},
+		"zero-period":     {Input: "100/0s", ExpectError: true},
+		"bad-burst":       {Input: "100/s;burst=many", ExpectError: true},
+		"bad-option":      {Input: "100/s;jitter=1", ExpectError: true},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Limiter = nil
+			warn, fatal := parser.ParseFromEnv(&config, testEnv{"LIMIT": tc.Input}.lookup)
+			assert.Equal(t, len(warn), 0, "There should be no warnings")
+			if tc.ExpectError {
+				assert.Equal(t, len(fatal), 1, "There should be 1 fatal error")
+				assert.Nil(t, config.Limiter)
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				if assert.NotNil(t, config.Limiter) {
+					assert.InDelta(t, float64(tc.ExpectedLimit), float64(config.Limiter.Limit()), 1e-9)
+					assert.Equal(t, tc.ExpectedBurst, config.Limiter.Burst())
+				}
+			}
+		})
+	}
+}
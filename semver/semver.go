@@ -0,0 +1,37 @@
+// Package semver provides envconfig parsers for *semver.Version fields from
+// github.com/Masterminds/semver/v3, with a choice of lenient ("v1.2") or strict ("1.2.0") SemVer
+// parsing.  It is its own module, rather than part of envconfig's DefaultFieldTypeHandlers, so that
+// Masterminds/semver is only a dependency of programs that use it; merge its handlers in:
+//
+//	handlers := envconfig.MergeHandlers(envconfig.DefaultFieldTypeHandlers(), semver.FieldTypeHandlers())
+//	parser, err := envconfig.GenerateParser(reflect.TypeOf(Config{}), handlers)
+package semver
+
+import (
+	"reflect"
+
+	mmsemver "github.com/Masterminds/semver/v3"
+
+	"github.com/datawire/envconfig"
+)
+
+// FieldTypeHandlers returns a map of struct field type handlers for *semver.Version fields.  A new map
+// is allocated on each call.
+func FieldTypeHandlers() map[reflect.Type]envconfig.FieldTypeHandler {
+	// If you add something to this, please add a test case for it to TestVersion.
+
+	//nolint:wrapcheck // The errors from Masterminds/semver are wrapped with the field name by the
+	// caller.
+	return map[reflect.Type]envconfig.FieldTypeHandler{
+		// *semver.Version
+		reflect.TypeOf((*mmsemver.Version)(nil)): {
+			Parsers: map[string]func(string) (interface{}, error){
+				// semver.NewVersion is lenient; it accepts a "v" prefix and missing minor/patch parts.
+				"semver.NewVersion": func(str string) (interface{}, error) { return mmsemver.NewVersion(str) },
+				// semver.StrictNewVersion only accepts strictly-formatted SemVer 2 versions.
+				"semver.StrictNewVersion": func(str string) (interface{}, error) { return mmsemver.StrictNewVersion(str) },
+			},
+			Setter: func(dst reflect.Value, src interface{}) { dst.Set(reflect.ValueOf(src.(*mmsemver.Version))) },
+		},
+	}
+}
@@ -0,0 +1,69 @@
+package semver_test
+
+import (
+	"reflect"
+	"testing"
+
+	mmsemver "github.com/Masterminds/semver/v3"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/datawire/envconfig"
+	"github.com/datawire/envconfig/semver"
+)
+
+type testEnv map[string]string
+
+func (e testEnv) lookup(key string) (string, bool) {
+	v, ok := e[key]
+	return v, ok
+}
+
+func TestVersion(t *testing.T) {
+	var config struct {
+		Lenient *mmsemver.Version `env:"LENIENT ,parser=semver.NewVersion       "`
+		Strict  *mmsemver.Version `env:"STRICT  ,parser=semver.StrictNewVersion ,default=0.0.0 "`
+	}
+	handlers := envconfig.MergeHandlers(envconfig.DefaultFieldTypeHandlers(), semver.FieldTypeHandlers())
+	parser, err := envconfig.GenerateParser(reflect.TypeOf(config), handlers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]struct {
+		Input          string
+		Expected       string
+		ExpectError    bool
+		ExpectStrictOK bool
+	}{
+		"1.2.3":     {Input: "1.2.3", Expected: "1.2.3", ExpectStrictOK: true},
+		"v1.2.3":    {Input: "v1.2.3", Expected: "1.2.3"},
+		"v1.2":      {Input: "v1.2", Expected: "1.2.0"},
+		"rc":        {Input: "1.2.3-rc.1", Expected: "1.2.3-rc.1", ExpectStrictOK: true},
+		"malformed": {Input: "1.2.x.y", ExpectError: true},
+	}
+	for name, tc := range testcases {
+		tc := tc // capture loop variable
+		t.Run(name, func(t *testing.T) {
+			config.Lenient = nil
+			config.Strict = nil
+			env := testEnv{"LENIENT": tc.Input, "STRICT": tc.Input}
+			warn, fatal := parser.ParseFromEnv(&config, env.lookup)
+			if tc.ExpectError {
+				assert.Equal(t, len(fatal), 1, "There should be 1 fatal error")
+				assert.Nil(t, config.Lenient)
+			} else {
+				assert.Equal(t, len(fatal), 0, "There should be no fatal errors")
+				if assert.NotNil(t, config.Lenient) {
+					assert.Equal(t, tc.Expected, config.Lenient.String())
+				}
+			}
+			if tc.ExpectStrictOK {
+				assert.Equal(t, len(warn), 0, "There should be no warnings")
+				assert.Equal(t, tc.Expected, config.Strict.String())
+			} else {
+				assert.Equal(t, len(warn), 1, "There should be 1 warning")
+				assert.Equal(t, "0.0.0", config.Strict.String(), "STRICT should fall back to its default")
+			}
+		})
+	}
+}